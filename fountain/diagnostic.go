@@ -0,0 +1,122 @@
+/*
+	Meander
+	A portable Fountain utility for production writing
+	Copyright (C) 2022-2023 Harley Denham
+*/
+
+package fountain
+
+import "fmt"
+import "bytes"
+import "strconv"
+import "strings"
+
+type Severity uint8
+const (
+	WARNING Severity = iota
+	ERROR
+)
+
+func (x Severity) String() string {
+	if x == ERROR {
+		return "error"
+	}
+	return "warning"
+}
+
+func (x Severity) MarshalJSON() ([]byte, error) {
+	buffer := new(bytes.Buffer)
+	buffer.Grow(16)
+
+	buffer.WriteRune('"')
+	buffer.WriteString(x.String())
+	buffer.WriteRune('"')
+
+	return buffer.Bytes(), nil
+}
+
+// a problem found while merging or parsing; none of
+// these stop the parse, they only record a decision
+// the parser made that the writer might not expect
+type Diagnostic struct {
+	Severity Severity `json:"severity"`
+	Code     string   `json:"code"`
+	Message  string   `json:"message"`
+
+	Position
+}
+
+const (
+	CODE_MISSING_INCLUDE   = "missing-include"
+	CODE_INCLUDE_CYCLE     = "include-cycle"
+	CODE_MISSING_LABEL     = "missing-label"
+	CODE_UNKNOWN_TITLE_KEY = "unknown-title-key"
+	CODE_DEMOTED_CHARACTER = "demoted-character"
+	CODE_DUPLICATE_SCENE   = "duplicate-scene-number"
+	CODE_SCENE_ORDER       = "scene-number-order"
+)
+
+func diagnose(list *[]Diagnostic, severity Severity, code string, pos Position, format string, guff ...any) {
+	*list = append(*list, Diagnostic{
+		Severity: severity,
+		Code:     code,
+		Message:  fmt.Sprintf(format, guff...),
+		Position: pos,
+	})
+}
+
+// forced scene numbers should be unique and climb through
+// the script; "12A" sits between "12" and "13", and numbers
+// that don't start with a digit are only checked for
+// duplicates
+func check_scene_numbers(data *Document) {
+	seen := make(map[string]Position, 64)
+
+	last_number := -1
+	last_suffix := ""
+	last_text   := ""
+
+	for _, section := range data.Content {
+		if section.Type != SCENE || section.SceneNumber == "" {
+			continue
+		}
+
+		key := strings.ToLower(strings.TrimSpace(section.SceneNumber))
+
+		if first, exists := seen[key]; exists {
+			diagnose(&data.Diagnostics, WARNING, CODE_DUPLICATE_SCENE, section.Position, "scene number %q is already used at %s", section.SceneNumber, data.Location(first))
+			continue
+		}
+		seen[key] = section.Position
+
+		number, suffix, success := split_scene_number(key)
+		if !success {
+			continue
+		}
+
+		if last_number >= 0 && (number < last_number || (number == last_number && suffix <= last_suffix)) {
+			diagnose(&data.Diagnostics, WARNING, CODE_SCENE_ORDER, section.Position, "scene number %q comes after %q", section.SceneNumber, last_text)
+		}
+
+		last_number = number
+		last_suffix = suffix
+		last_text   = section.SceneNumber
+	}
+}
+
+func split_scene_number(text string) (int, string, bool) {
+	n := 0
+	for n < len(text) && text[n] >= '0' && text[n] <= '9' {
+		n += 1
+	}
+	if n == 0 {
+		return 0, "", false
+	}
+
+	number, err := strconv.Atoi(text[:n])
+	if err != nil {
+		return 0, "", false
+	}
+
+	return number, text[n:], true
+}
@@ -0,0 +1,293 @@
+/*
+	Meander
+	A portable Fountain utility for production writing
+	Copyright (C) 2022-2023 Harley Denham
+*/
+
+// Package fountain is the Fountain parser used by Meander, made
+// available to other Go programs.  It produces a plain document
+// model with no knowledge of pages, templates or rendering.
+package fountain
+
+import "io"
+import "fmt"
+import "bytes"
+import "strings"
+import "encoding/json"
+
+// DATA_VERSION is the version of the JSON written by Meander's
+// data command.  version 2 moved inline styling out of the text
+// and into spans.
+const DATA_VERSION = 2
+
+type Document struct {
+	Meta Meta `json:"meta"`
+
+	Title TitlePage `json:"title"`
+
+	Files      []SourceFile `json:"files,omitempty"`
+	Characters []Character   `json:"characters,omitempty"`
+	Content    []Section     `json:"content,omitempty"`
+
+	// renderer options found in the document
+	Settings Settings `json:"-"`
+
+	// [location.x] boneyard entries, in order
+	Locations []Location `json:"-"`
+
+	// lowercased character names and aliases
+	// mapped to their index in Characters
+	lookup map[string]int
+
+	// words in the text after boneyards are removed
+	WordCount int `json:"-"`
+
+	// problems found while merging and parsing
+	Diagnostics []Diagnostic `json:"-"`
+}
+
+// Settings are the values found in the title page and the
+// [template] tables that only mean something to a renderer.
+// they're kept as plain text for the caller to interpret.
+type Settings struct {
+	Header  string
+	Footer  string
+	MoreTag string
+	ContTag string
+	Format  string
+	Paper   string
+
+	DialogueWPM string
+	ActionWPM   string
+
+	// [template] boneyard entries, in order
+	Templates []TemplateRule
+
+	// where each title page key was found, by its
+	// name in lower case without spaces, such as
+	// "dialoguewpm", for reporting bad values
+	Positions map[string]Position
+}
+
+type Meta struct {
+	Source    string `json:"source"`
+	Version   uint8  `json:"version"`
+	Paginated bool   `json:"paginated,omitempty"`
+}
+
+type TitlePage struct {
+	HasAny    bool   `json:"-"`
+	Title     string `json:"title,omitempty"`
+	Credit    string `json:"credit,omitempty"`
+	Author    string `json:"author,omitempty"`
+	Source    string `json:"source,omitempty"`
+	Notes     string `json:"notes,omitempty"`
+	DraftDate string `json:"draft_date,omitempty"`
+	Copyright string `json:"copyright,omitempty"`
+	Revision  string `json:"revision,omitempty"`
+	Contact   string `json:"contact,omitempty"`
+	Info      string `json:"info,omitempty"`
+}
+
+type Character struct {
+	Name       string   `json:"name"`
+	Gender     string   `json:"gender"`
+	OtherNames []string `json:"other_names,omitempty"`
+	Lines      int      `json:"lines_spoken,omitempty"`
+	Words      int      `json:"words_spoken,omitempty"`
+}
+
+type Section struct {
+	Type        SectionType `json:"type"`
+	Text        string       `json:"text,omitempty"`
+	SceneNumber string       `json:"scene_number,omitempty"`
+	Revision    string       `json:"revision,omitempty"`
+	Level       int          `json:"level,omitempty"`
+
+	// only filled in by the data command, which gives
+	// plain text alongside the styled runs that make
+	// it up; the parser leaves markup in Text
+	Spans []Span `json:"spans,omitempty"`
+
+	Position
+}
+
+// a run of text in a single inline style.  Style is a
+// list of "bold", "italic", "underline", "strikeout",
+// "highlight" and "note", or empty for plain text.
+type Span struct {
+	Text  string   `json:"text"`
+	Style []string `json:"style,omitempty"`
+}
+
+// a single line from a [location.x] table: the name a
+// location should be known by and the other ways it's
+// written in scene headings.  Group is the x, which the
+// location report keeps alongside the name.
+type Location struct {
+	Name       string
+	Group      string
+	OtherNames []string
+}
+
+// a single line from a [template] table; Type is
+// TYPE_NONE for the global [template] heading
+type TemplateRule struct {
+	Type SectionType
+	Text string
+
+	Position
+}
+
+type SectionType uint8
+const (
+	WHITESPACE SectionType = iota
+
+	PAGE_BREAK
+
+	HEADER
+	FOOTER
+
+	IS_PRINTABLE
+
+	ACTION
+	SCENE
+
+	BEGIN_CHARACTER
+	CHARACTER
+	DUAL_CHARACTER
+	PARENTHETICAL
+	DUAL_PARENTHETICAL
+	DIALOGUE
+	DUAL_DIALOGUE
+	LYRIC
+	DUAL_LYRIC
+	END_CHARACTER
+
+	TRANSITION
+	SYNOPSIS
+	CENTERED
+
+	IS_SECTION
+
+	SECTION
+	SECTION2
+	SECTION3
+
+	TYPE_COUNT
+	TYPE_NONE
+)
+
+func (x SectionType) MarshalJSON() ([]byte, error) {
+	buffer := new(bytes.Buffer)
+	buffer.Grow(32)
+
+	buffer.WriteRune('"')
+	buffer.WriteString(x.String())
+	buffer.WriteRune('"')
+
+	return buffer.Bytes(), nil
+}
+
+func (x *SectionType) UnmarshalJSON(blob []byte) error {
+	var name string
+	if err := json.Unmarshal(blob, &name); err != nil {
+		return err
+	}
+
+	for i := WHITESPACE; i < TYPE_COUNT; i++ {
+		if i.String() == name {
+			*x = i
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown section type %q", name)
+}
+
+// ReadJSON loads a Document from the JSON that Meander's
+// data command writes, so it can be edited by other tools
+// and brought back.  any data version up to this package's
+// DATA_VERSION is accepted, but paginated data is a record
+// of a layout, not a script, so it's turned away.
+func ReadJSON(reader io.Reader) (*Document, error) {
+	data := new(Document)
+
+	if err := json.NewDecoder(reader).Decode(data); err != nil {
+		return nil, err
+	}
+
+	if data.Meta.Version < 1 || data.Meta.Version > DATA_VERSION {
+		return nil, fmt.Errorf("data version %d is not supported, expected %d or earlier", data.Meta.Version, DATA_VERSION)
+	}
+
+	if data.Meta.Paginated {
+		return nil, fmt.Errorf("paginated data can't be read back")
+	}
+
+	// from version 2 the text is plain, so
+	// the markup has to be put back in
+	if data.Meta.Version >= 2 {
+		for i := range data.Content {
+			section := &data.Content[i]
+			if len(section.Spans) > 0 {
+				section.Text = spans_markup(section.Spans)
+			} else {
+				section.Text = escape_markup(section.Text)
+			}
+		}
+	}
+
+	title := &data.Title
+	for _, x := range [...]string{title.Title, title.Credit, title.Author, title.Source, title.Notes, title.DraftDate, title.Copyright, title.Revision, title.Contact, title.Info} {
+		if x != "" {
+			title.HasAny = true
+			break
+		}
+	}
+
+	// rebuild everything the parser would have
+	// worked out for itself along the way
+	data.lookup = make(map[string]int, len(data.Characters))
+
+	for i, c := range data.Characters {
+		data.lookup[strings.ToLower(c.Name)] = i
+		for _, name := range c.OtherNames {
+			data.lookup[strings.ToLower(name)] = i
+		}
+	}
+
+	for _, section := range data.Content {
+		if section.Type > IS_PRINTABLE {
+			data.WordCount += word_count(section.Text)
+		}
+	}
+
+	return data, nil
+}
+
+// FindCharacter looks a character up by their name or any
+// of their other names, ignoring case, and returns their
+// index in Characters
+func (data *Document) FindCharacter(name string) (int, bool) {
+	i, ok := data.lookup[strings.ToLower(name)]
+	return i, ok
+}
+
+// CountWords counts the words in a piece of text the
+// same way as the document's own word count
+func CountWords(text string) int {
+	return word_count(text)
+}
+
+func IsCharacterTrain(node_type SectionType) bool {
+	return node_type > BEGIN_CHARACTER && node_type < END_CHARACTER
+}
+
+const section_type_names = "whitespacepage_breakheaderfooteris_printableactionscenebegin_charactercharacterdual_characterparentheticaldual_parentheticaldialoguedual_dialoguelyricdual_lyricend_charactertransitionsynopsiscenteredis_sectionsectionsection2section3type_count"
+
+var section_type_indices = [...]uint8{0, 10, 20, 26, 32, 44, 50, 55, 70, 79, 93, 106, 124, 132, 145, 150, 160, 173, 183, 191, 199, 209, 216, 224, 232, 242}
+
+func (i SectionType) String() string {
+	return section_type_names[section_type_indices[i]:section_type_indices[i+1]]
+}
@@ -0,0 +1,74 @@
+/*
+	Meander
+	A portable Fountain utility for production writing
+	Copyright (C) 2022-2023 Harley Denham
+*/
+
+package fountain
+
+// language.go is an extension of parse.go that reveals
+// the underlying syntax that's matched for; basically we can
+// easily extend language support in this file
+
+// text is lowercased
+func lang_scene(text string) bool {
+	switch text {
+	case "int":     return true
+	case "ext":     return true
+	case "int/ext": return true
+	case "ext/int": return true
+	case "i/e":     return true
+	case "e/i":     return true
+	case "est":     return true
+	case "scene":   return true
+	}
+	return false
+}
+
+// the setting a scene heading prefix stands for,
+// as it's written in a breakdown; text is lowercased
+func lang_scene_setting(text string) string {
+	switch text {
+	case "int":     return "INT"
+	case "ext":     return "EXT"
+	case "int/ext": return "INT/EXT"
+	case "i/e":     return "INT/EXT"
+	case "ext/int": return "EXT/INT"
+	case "e/i":     return "EXT/INT"
+	case "est":     return "EXT"
+	}
+	return ""
+}
+
+// text is lowercased
+func lang_transition(text string) bool {
+	return text == "to:"
+}
+
+// text is homogenised
+func lang_title_page(text string) bool {
+	switch text {
+	// fountain
+	case "title":     return true
+	case "credit":    return true
+	case "author":    return true
+	case "source":    return true
+	case "contact":   return true
+	case "revision":  return true
+	case "copyright": return true
+	case "draftdate": return true
+	case "notes":     return true
+
+	// meander
+	case "paper":   return true
+	case "format":  return true
+	case "conttag": return true
+	case "moretag": return true
+	case "header":  return true
+	case "footer":  return true
+
+	case "dialoguewpm": return true
+	case "actionwpm":   return true
+	}
+	return false
+}
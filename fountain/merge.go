@@ -0,0 +1,430 @@
+/*
+	Meander
+	A portable Fountain utility for production writing
+	Copyright (C) 2022-2023 Harley Denham
+*/
+
+package fountain
+
+import "fmt"
+import "sort"
+import "strings"
+import "unicode"
+import "path/filepath"
+
+// Merge loads a Fountain file and collapses all of its
+// include directives into a single document.  the returned
+// Document only has its Files and Diagnostics filled in.
+func Merge(source_file string) (string, *Document, error) {
+	raw, success := load_file(source_file)
+	if !success {
+		return "", nil, fmt.Errorf("%q not found", source_file)
+	}
+
+	state := new_merge_state(len(raw))
+	merge_text(state, source_file, nil, "", raw)
+
+	data := new(Document)
+	data.Files       = state.files
+	data.Diagnostics = state.diagnostics
+
+	return state.content.String(), data, nil
+}
+
+// the merged text and where each of its lines came from
+type merge_state struct {
+	content *strings.Builder
+	lines   []Position
+	files   []SourceFile
+
+	// indices into files of those currently
+	// being merged, outermost first
+	active []int
+
+	diagnostics []Diagnostic
+}
+
+func new_merge_state(size int) *merge_state {
+	state := new(merge_state)
+
+	state.content = new(strings.Builder)
+	state.content.Grow(size)
+
+	state.lines  = make([]Position, 0, 256)
+	state.files  = make([]SourceFile, 0, 8)
+	state.active = make([]int, 0, 8)
+
+	return state
+}
+
+// merge loads and merges an included file, reporting
+// problems against the include directive at from
+func merge(state *merge_state, source_file string, label string, from *Position) bool {
+	at := *from
+	at.Column = 1
+
+	raw, success := load_file(source_file)
+	if !success {
+		diagnose(&state.diagnostics, ERROR, CODE_MISSING_INCLUDE, at, "included file %q not found", source_file)
+		return false
+	}
+
+	if !merge_text(state, source_file, from, label, raw) {
+		diagnose(&state.diagnostics, ERROR, CODE_MISSING_LABEL, at, "no section or scene labelled %q in %q", label, source_file)
+		return false
+	}
+	return true
+}
+
+// merge_text appends the normalised raw text to the merged
+// content, recursing into any includes.  included files
+// have their title pages removed.  if a label is given,
+// only the matching section or scene is merged, and
+// false is returned if it can't be found.
+func merge_text(state *merge_state, source_file string, from *Position, label string, raw string) bool {
+	line := leading_lines(raw) + 1
+	text := normalise_text(raw)
+
+	if from != nil {
+		skipped := 0
+		text, skipped = consume_title_page(text)
+		line += skipped
+	}
+
+	if label != "" {
+		skipped := 0
+		success := false
+
+		text, skipped, success = select_label(text, label)
+		if !success {
+			return false
+		}
+		line += skipped
+	}
+
+	file_index := len(state.files)
+	state.files = append(state.files, SourceFile{
+		Path: source_file,
+		From: from,
+	})
+
+	state.active = append(state.active, file_index)
+	defer func() { state.active = state.active[:len(state.active) - 1] }()
+
+	state.lines = append(state.lines, Position{File: file_index, Line: line})
+
+	content := state.content
+
+	// includes inside boneyards and notes are
+	// commented out, so we leave them alone
+	in_boneyard := false
+	in_note     := false
+
+	for {
+		if len(text) == 0 {
+			break
+		}
+
+		if text[0] == '\n' && len(text) > 9 && text[1] == 'i' && !in_boneyard && !in_note {
+			content.WriteRune('\n')
+			text = text[1:] // newline
+
+			line += 1
+			state.lines = append(state.lines, Position{File: file_index, Line: line})
+
+			if !is_include(text) {
+				continue
+			}
+
+			test_text := extract_to_newline(text)
+
+			// the child's first line replaces the include
+			// directive, so it takes over that position
+			include := state.lines[len(state.lines) - 1]
+			state.lines = state.lines[:len(state.lines) - 1]
+
+			if !merge_include(state, source_file, strings.TrimSpace(test_text[8:]), include) {
+				state.lines = append(state.lines, include)
+				content.WriteString(text[:len(test_text)])
+			}
+
+			text = text[len(test_text):]
+			continue
+		}
+
+		// escaped markers don't open or close anything
+		if text[0] == '\\' && len(text) > 1 && text[1] != '\n' {
+			_, rune_width := get_rune(text[1:])
+			content.WriteString(text[:rune_width + 1])
+			text = text[rune_width + 1:]
+			continue
+		}
+
+		if len(text) > 1 {
+			pair := text[:2]
+
+			switch {
+			case pair == "/*" && !in_note:
+				in_boneyard = true
+			case pair == "*/" && in_boneyard:
+				in_boneyard = false
+			case pair == "[[" && !in_boneyard:
+				in_note = true
+			case pair == "]]" && in_note:
+				in_note = false
+			default:
+				pair = ""
+			}
+
+			if pair != "" {
+				content.WriteString(pair)
+				text = text[2:]
+				continue
+			}
+		}
+
+		the_rune, rune_width := get_rune(text)
+		text = text[rune_width:]
+		content.WriteRune(the_rune)
+
+		if the_rune == '\n' {
+			line += 1
+			state.lines = append(state.lines, Position{File: file_index, Line: line})
+
+			// notes can't run over a blank line
+			if len(text) > 0 && text[0] == '\n' {
+				in_note = false
+			}
+		}
+	}
+
+	return true
+}
+
+// merge_include merges the file or files named by an include
+// directive, returning false if nothing was written in its
+// place.  patterns are expanded in sorted order, with the
+// files separated by a blank line.  "file#Label" merges
+// only that part of the file.
+func merge_include(state *merge_state, parent, name string, include Position) bool {
+	label := ""
+	if n := strings.IndexRune(name, '#'); n > -1 {
+		label = strings.TrimSpace(name[n + 1:])
+		name  = strings.TrimSpace(name[:n])
+	}
+
+	file_name := include_path(parent, name)
+
+	at := include
+	at.Column = 1
+
+	if !is_glob(name) {
+		if chain, is_cycle := include_cycle(state, file_name); is_cycle {
+			diagnose(&state.diagnostics, ERROR, CODE_INCLUDE_CYCLE, at, "include cycle: %s", chain)
+			return false
+		}
+
+		return merge(state, file_name, label, &include)
+	}
+
+	matches, err := filepath.Glob(file_name)
+	if err != nil {
+		diagnose(&state.diagnostics, ERROR, CODE_MISSING_INCLUDE, at, "bad include pattern %q", name)
+		return false
+	}
+
+	sort.Strings(matches)
+
+	written := false
+	skipped := false
+
+	for _, match := range matches {
+		// a pattern matching its own parent (or anything
+		// else still being merged) is almost certainly
+		// an accident, so it only gets a warning
+		if chain, is_cycle := include_cycle(state, match); is_cycle {
+			diagnose(&state.diagnostics, WARNING, CODE_INCLUDE_CYCLE, at, "skipped %q to avoid an include cycle: %s", match, chain)
+			skipped = true
+			continue
+		}
+
+		if written {
+			state.content.WriteString("\n\n")
+			state.lines = append(state.lines, include)
+		}
+
+		if merge(state, match, label, &include) {
+			written = true
+		}
+	}
+
+	if !written && !skipped {
+		diagnose(&state.diagnostics, ERROR, CODE_MISSING_INCLUDE, at, "no files match include pattern %q", name)
+	}
+
+	return written
+}
+
+// include_cycle checks whether a file is already being merged
+// and if so, returns the chain of includes leading back to it
+func include_cycle(state *merge_state, file_name string) (string, bool) {
+	target := absolute_path(file_name)
+
+	for i, index := range state.active {
+		if absolute_path(state.files[index].Path) != target {
+			continue
+		}
+
+		chain := make([]string, 0, len(state.active) - i + 1)
+		for _, index := range state.active[i:] {
+			chain = append(chain, state.files[index].Path)
+		}
+		chain = append(chain, file_name)
+
+		return strings.Join(chain, " -> "), true
+	}
+
+	return "", false
+}
+
+// select_label finds the section heading or scene labelled
+// by label and returns the text from it up to the next
+// heading that closes it, as well as the number of lines
+// skipped to get there.
+//
+// a section runs until the next section of the same or a
+// higher level; a scene runs until the next scene or section.
+// scenes can be labelled by their #number# or by the text
+// of the heading itself.  anything in a boneyard is left
+// alone, the same as includes are in merge_text.
+func select_label(text, label string) (string, int, bool) {
+	start := -1
+	level := 0
+
+	offset      := 0
+	line_count  := 0
+	skipped     := 0
+	was_blank   := true
+	in_boneyard := false
+
+	for offset < len(text) {
+		end := strings.IndexRune(text[offset:], '\n')
+		if end < 0 {
+			end = len(text)
+		} else {
+			end += offset
+		}
+
+		line := strings.TrimSpace(text[offset:end])
+
+		this_level := 0
+		if !in_boneyard {
+			this_level = label_level(line, was_blank)
+		}
+		in_boneyard = boneyard_after(line, in_boneyard)
+
+		if start < 0 {
+			if this_level > 0 && is_label(line, this_level, label) {
+				start   = offset
+				level   = this_level
+				skipped = line_count
+			}
+		} else if this_level > 0 && this_level <= level {
+			return strings.TrimSpace(text[start:offset]), skipped, true
+		}
+
+		was_blank = line == ""
+
+		line_count += 1
+		offset = end + 1
+	}
+
+	if start < 0 {
+		return "", 0, false
+	}
+
+	return strings.TrimSpace(text[start:]), skipped, true
+}
+
+// boneyard_after reports whether a boneyard is still open
+// at the end of line, given whether one was at its start
+func boneyard_after(line string, in_boneyard bool) bool {
+	for len(line) > 1 {
+		width := 1
+
+		switch {
+		case line[0] == '\\':
+			_, rune_width := get_rune(line[1:])
+			width = rune_width + 1
+		case line[:2] == "/*":
+			in_boneyard = true
+			width = 2
+		case line[:2] == "*/" && in_boneyard:
+			in_boneyard = false
+			width = 2
+		}
+
+		line = line[width:]
+	}
+	return in_boneyard
+}
+
+// sections are levels 1 to 6 by their number of
+// hashes; scenes sit beneath all of them
+const label_scene_level = 7
+
+func label_level(line string, was_blank bool) int {
+	if len(line) == 0 {
+		return 0
+	}
+
+	if line[0] == '#' {
+		n := count_rune(line, '#')
+		if r, _ := get_rune(line[n:]); n == len(line) || unicode.IsSpace(r) {
+			if n >= label_scene_level {
+				n = label_scene_level - 1
+			}
+			return n
+		}
+		return 0
+	}
+
+	if !was_blank {
+		return 0
+	}
+
+	if line[0] == '.' && len(line) > 1 && line[1] != '.' {
+		return label_scene_level
+	}
+	if IsValidScene(line) {
+		return label_scene_level
+	}
+
+	return 0
+}
+
+func is_label(line string, level int, label string) bool {
+	if level < label_scene_level {
+		return strings.EqualFold(strings.TrimSpace(strings.TrimLeft(line, "#")), label)
+	}
+
+	if line[0] == '.' {
+		line = left_trim(line[1:])
+	}
+
+	name, number, success := get_scene_number(line)
+	if success && strings.EqualFold(strings.TrimSpace(number), label) {
+		return true
+	}
+	if !success {
+		name = line
+	}
+
+	return strings.EqualFold(name, label)
+}
+
+// is_include reports whether a line is an include directive,
+// such as "include: act_one.fountain"
+func is_include(line string) bool {
+	return len(line) > 8 && line[0] == 'i' && rune_on_line(line, ':') == 8 && homogenise(line[:7]) == "include"
+}
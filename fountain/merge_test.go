@@ -0,0 +1,241 @@
+/*
+	Meander
+	A portable Fountain utility for production writing
+	Copyright (C) 2022-2023 Harley Denham
+*/
+
+package fountain
+
+import "os"
+import "strings"
+import "testing"
+import "path/filepath"
+
+// write_files fills a temporary folder with files named
+// by their path inside it, and returns the folder
+func write_files(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	for name, text := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func diagnostic_codes(list []Diagnostic) []string {
+	codes := make([]string, 0, len(list))
+	for _, d := range list {
+		codes = append(codes, d.Code)
+	}
+	return codes
+}
+
+func TestIncludeCycle(t *testing.T) {
+	tests := []struct {
+		name   string
+		active []string
+		file   string
+		chain  string
+		cycle  bool
+	}{
+		{"nothing open",     nil,                                                "a.fountain",   "",                                       false},
+		{"not open",         []string{"a.fountain", "b.fountain"},               "c.fountain",   "",                                       false},
+		{"back to the root", []string{"a.fountain", "b.fountain"},               "a.fountain",   "a.fountain -> b.fountain -> a.fountain", true},
+		{"itself",           []string{"a.fountain", "b.fountain"},               "b.fountain",   "b.fountain -> b.fountain",               true},
+		{"another spelling", []string{"a.fountain"},                             "./a.fountain", "a.fountain -> ./a.fountain",             true},
+		{"middle of chain",  []string{"a.fountain", "b.fountain", "c.fountain"}, "b.fountain",   "b.fountain -> c.fountain -> b.fountain", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			state := new_merge_state(0)
+
+			for i, path := range test.active {
+				state.files  = append(state.files, SourceFile{Path: path})
+				state.active = append(state.active, i)
+			}
+
+			chain, cycle := include_cycle(state, test.file)
+			if chain != test.chain || cycle != test.cycle {
+				t.Errorf("include_cycle(%q) = %q, %v; want %q, %v", test.file, chain, cycle, test.chain, test.cycle)
+			}
+		})
+	}
+}
+
+func TestMergeIncludes(t *testing.T) {
+	tests := []struct {
+		name   string
+		files  map[string]string // the root is main.fountain
+		output string
+		codes  []string
+	}{
+		{
+			name: "plain include",
+			files: map[string]string{
+				"main.fountain": "FADE IN:\n\ninclude: one.fountain\n\nFADE OUT.\n",
+				"one.fountain":  "Title: One\n\nINT. ONE - DAY\n",
+			},
+			output: "FADE IN:\n\nINT. ONE - DAY\n\nFADE OUT.",
+		},
+		{
+			name: "cycle",
+			files: map[string]string{
+				"main.fountain": "Main.\n\ninclude: a.fountain\n",
+				"a.fountain":    "A.\n\ninclude: main.fountain\n",
+			},
+			output: "Main.\n\nA.\n\ninclude: main.fountain",
+			codes:  []string{CODE_INCLUDE_CYCLE},
+		},
+		{
+			name: "pattern in sorted order",
+			files: map[string]string{
+				"main.fountain":       "Episodes.\n\ninclude: episodes/*.fountain\n",
+				"episodes/2.fountain": "Two.\n",
+				"episodes/1.fountain": "One.\n",
+			},
+			output: "Episodes.\n\nOne.\n\nTwo.",
+		},
+		{
+			name: "pattern matching its parent",
+			files: map[string]string{
+				"main.fountain":  "Main.\n\ninclude: *.fountain\n",
+				"other.fountain": "Other.\n",
+			},
+			output: "Main.\n\nOther.",
+			codes:  []string{CODE_INCLUDE_CYCLE},
+		},
+		{
+			name: "in a boneyard",
+			files: map[string]string{
+				"main.fountain": "Main.\n/*\ninclude: missing.fountain\n*/\n",
+			},
+			output: "Main.\n/*\ninclude: missing.fountain\n*/",
+		},
+		{
+			name: "in a note",
+			files: map[string]string{
+				"main.fountain": "Main. [[a note\ninclude: missing.fountain]]\n",
+			},
+			output: "Main. [[a note\ninclude: missing.fountain]]",
+		},
+		{
+			name: "missing file",
+			files: map[string]string{
+				"main.fountain": "Main.\n\ninclude: missing.fountain\n",
+			},
+			output: "Main.\n\ninclude: missing.fountain",
+			codes:  []string{CODE_MISSING_INCLUDE},
+		},
+		{
+			name: "pattern with no matches",
+			files: map[string]string{
+				"main.fountain": "Main.\n\ninclude: nothing/*.fountain\n",
+			},
+			output: "Main.\n\ninclude: nothing/*.fountain",
+			codes:  []string{CODE_MISSING_INCLUDE},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := write_files(t, test.files)
+
+			output, data, err := Merge(filepath.Join(dir, "main.fountain"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if output != test.output {
+				t.Errorf("merged text is\n%q\nwant\n%q", output, test.output)
+			}
+
+			codes := diagnostic_codes(data.Diagnostics)
+			if strings.Join(codes, ",") != strings.Join(test.codes, ",") {
+				t.Errorf("diagnostics are %v, want %v", codes, test.codes)
+			}
+		})
+	}
+}
+
+func TestSelectLabel(t *testing.T) {
+	const text = "# Episode 1\n\nINT. ONE - DAY\n\nAction one.\n\n# Episode 2\n\n## Part A\n\nINT. TWO - NIGHT #12#\n\nAction two.\n\n.FLASHBACK\n\nAction three.\n\n# Episode 3\n\nLead in.\nINT. HIDDEN - DAY\n\nThe end.\n\n/*\n\n# Cut Episode\n\nINT. CUT - DAY\n\n*/"
+
+	tests := []struct {
+		label   string
+		output  string
+		skipped int
+		found   bool
+	}{
+		{"Episode 1",         "# Episode 1\n\nINT. ONE - DAY\n\nAction one.",                                                          0,  true},
+		{"episode 2",         "# Episode 2\n\n## Part A\n\nINT. TWO - NIGHT #12#\n\nAction two.\n\n.FLASHBACK\n\nAction three.",       6,  true},
+		{"Part A",            "## Part A\n\nINT. TWO - NIGHT #12#\n\nAction two.\n\n.FLASHBACK\n\nAction three.",                      8,  true},
+		{"12",                "INT. TWO - NIGHT #12#\n\nAction two.",                                                                  10, true},
+		{"flashback",         ".FLASHBACK\n\nAction three.",                                                                           14, true},
+		{"int. one - day",    "INT. ONE - DAY\n\nAction one.",                                                                         2,  true},
+		{"Episode 3",         "# Episode 3\n\nLead in.\nINT. HIDDEN - DAY\n\nThe end.\n\n/*\n\n# Cut Episode\n\nINT. CUT - DAY\n\n*/", 18, true},
+		{"INT. HIDDEN - DAY", "",                                                                                                      0,  false},
+		{"Episode 4",         "",                                                                                                      0,  false},
+		{"Cut Episode",       "",                                                                                                      0,  false},
+		{"int. cut - day",    "",                                                                                                      0,  false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.label, func(t *testing.T) {
+			output, skipped, found := select_label(text, test.label)
+			if output != test.output || skipped != test.skipped || found != test.found {
+				t.Errorf("select_label(%q) = %q, %d, %v; want %q, %d, %v", test.label, output, skipped, found, test.output, test.skipped, test.found)
+			}
+		})
+	}
+}
+
+func TestMergeLabels(t *testing.T) {
+	const opens = "Title: Cold Opens\n\n# Episode 1\n\nINT. ROOF - NIGHT\n\nRain.\n\n# Episode 2\n\nINT. CELLAR - DAY #7#\n\nDust.\n\nINT. STAIRS - DAY\n\nFootsteps."
+
+	tests := []struct {
+		name    string
+		include string
+		output  string
+		codes   []string
+	}{
+		{"section",       "opens.fountain#Episode 1",           "Open.\n\n# Episode 1\n\nINT. ROOF - NIGHT\n\nRain.\n\nClose.", nil},
+		{"scene number",  "opens.fountain#7",                   "Open.\n\nINT. CELLAR - DAY #7#\n\nDust.\n\nClose.",            nil},
+		{"scene heading", "opens.fountain # int. stairs - day", "Open.\n\nINT. STAIRS - DAY\n\nFootsteps.\n\nClose.",           nil},
+		{"missing label", "opens.fountain#Episode 9",           "Open.\n\ninclude: opens.fountain#Episode 9\n\nClose.",         []string{CODE_MISSING_LABEL}},
+		{"on a pattern",  "open*.fountain#7",                   "Open.\n\nINT. CELLAR - DAY #7#\n\nDust.\n\nClose.",            nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := write_files(t, map[string]string{
+				"main.fountain":  "Open.\n\ninclude: " + test.include + "\n\nClose.\n",
+				"opens.fountain": opens,
+			})
+
+			output, data, err := Merge(filepath.Join(dir, "main.fountain"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if output != test.output {
+				t.Errorf("merged text is\n%q\nwant\n%q", output, test.output)
+			}
+
+			codes := diagnostic_codes(data.Diagnostics)
+			if strings.Join(codes, ",") != strings.Join(test.codes, ",") {
+				t.Errorf("diagnostics are %v, want %v", codes, test.codes)
+			}
+		})
+	}
+}
@@ -0,0 +1,1048 @@
+/*
+	Meander
+	A portable Fountain utility for production writing
+	Copyright (C) 2022-2023 Harley Denham
+*/
+
+package fountain
+
+import "io"
+import "strings"
+import "unicode"
+import "unicode/utf8"
+import "path/filepath"
+
+type Options struct {
+	// path of the source file; includes are resolved
+	// relative to it and its name is the fallback title
+	Path string
+
+	// keep [[notes]] in the text instead of removing them
+	IncludeNotes bool
+}
+
+// Parse reads a Fountain document, expands any includes
+// and returns the parsed document model
+func Parse(reader io.Reader, options Options) (*Document, error) {
+	blob, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	state := new_merge_state(len(blob))
+	merge_text(state, options.Path, nil, "", string(blob))
+
+	data := new(Document)
+	data.Files       = state.files
+	data.Diagnostics = state.diagnostics
+
+	syntax_parser(options, data, state.content.String(), state.lines)
+
+	return data, nil
+}
+
+// lines holds the source position of each line of the
+// merged text, as built by merge_text
+func syntax_parser(options Options, data *Document, text string, lines []Position) {
+	data.Meta.Version = DATA_VERSION
+
+	source := &source_map{new_line_index(text), lines, len(text)}
+
+	data.lookup     = make(map[string]int, 32)
+	data.Characters = make([]Character, 0, 32) // we pre-empt needing these
+
+	// only remove newlines in case the first
+	// element is indented action
+	text = consume_newlines(text)
+
+	// title page mini-parser
+	for {
+		n, success := find_title_colon(text)
+		if !success {
+			break
+		}
+
+		key     := strings.TrimSpace(text[:n])
+		key_pos := source.at_suffix(text)
+		key_pos.Column = 1
+
+		word := homogenise(text[:n])
+		text = text[n + 1:]
+
+		word = homogenise(strings.TrimSpace(word))
+
+		title_buffer := strings.Builder{}
+		title_buffer.Grow(64)
+
+		break_main_loop := false
+
+		// begin parsing
+		for {
+			// grab the first line manually
+			line := extract_to_newline(text)
+			text = text[len(line):] // consume the line
+
+			title_buffer.WriteString(strings.TrimSpace(line))
+
+			if len(text) == 0 {
+				break
+			}
+
+			if text[0] == '\n' {
+				text = text[1:] // consume the newline
+
+				if len(text) == 0 {
+					break
+				}
+
+				if len(text) > 0 && text[0] == '\n' {
+					break_main_loop = true
+					break
+				}
+				if !unicode.IsSpace(rune(text[0])) {
+					break
+				}
+
+				sub_line := extract_to_newline(text)
+				text = text[len(sub_line):]
+
+				title_buffer.WriteRune('\n')
+				title_buffer.WriteString(strings.TrimSpace(sub_line))
+			}
+		}
+
+		sub_line := left_trim(title_buffer.String())
+
+		if sub_line != "" {
+			if data.Settings.Positions == nil {
+				data.Settings.Positions = make(map[string]Position, 16)
+			}
+			data.Settings.Positions[word] = key_pos
+
+			switch word {
+			case "title":
+				data.Title.Title = sub_line
+				data.Title.HasAny = true
+			case "credit":
+				data.Title.Credit = sub_line
+				data.Title.HasAny = true
+			case "author":
+				data.Title.Author = sub_line
+				data.Title.HasAny = true
+			case "source":
+				data.Title.Source = sub_line
+				data.Title.HasAny = true
+			case "notes":
+				data.Title.Notes = sub_line
+				data.Title.HasAny = true
+			case "draftdate":
+				data.Title.DraftDate = sub_line
+				data.Title.HasAny = true
+			case "copyright":
+				data.Title.Copyright = sub_line
+				data.Title.HasAny = true
+			case "revision":
+				data.Title.Revision = sub_line
+				data.Title.HasAny = true
+			case "contact":
+				data.Title.Contact = sub_line
+				data.Title.HasAny = true
+			case "info":
+				data.Title.Info = sub_line
+				data.Title.HasAny = true
+
+			case "header":
+				data.Settings.Header = sub_line
+			case "footer":
+				data.Settings.Footer = sub_line
+
+			case "conttag":
+				data.Settings.ContTag = sub_line
+			case "moretag":
+				data.Settings.MoreTag = sub_line
+
+			case "format", "template":
+				data.Settings.Format = sub_line
+			case "paper":
+				data.Settings.Paper = sub_line
+
+			case "dialoguewpm":
+				data.Settings.DialogueWPM = sub_line
+			case "actionwpm":
+				data.Settings.ActionWPM = sub_line
+
+			default:
+				diagnose(&data.Diagnostics, WARNING, CODE_UNKNOWN_TITLE_KEY, key_pos, "unknown title page key %q", key)
+			}
+		}
+
+		if break_main_loop {
+			break
+		}
+	}
+
+	{
+		// remove boneyards in a single step:
+		// it's the only syntax that crosses a
+		// line-boundary, so we deal with it now
+
+		copy := new(strings.Builder)
+		copy.Grow(len(text))
+
+		// where each line of the de-boned copy came from
+		// each line of the copy takes the position of the
+		// first thing written to it, because whatever
+		// follows a newline might be about to be eaten
+		body_lines := make([]Position, 0, len(lines))
+		line_start := true
+
+		mark := func(at string) {
+			if line_start {
+				body_lines = append(body_lines, source.at_suffix(at))
+				line_start = false
+			}
+		}
+
+		eat_spaces    := false
+		eat_newlines  := false
+		last_rune     := '_'
+
+		is_escaped := false
+
+		for len(text) > 0 {
+			if !options.IncludeNotes {
+				if text[0] == '[' && len(text) > 1 && text[1] == '[' {
+					if is_escaped {
+						mark(text)
+						text = text[2:]
+						copy.WriteString("[[")
+						is_escaped = false
+						continue
+					}
+
+					n := rune_pair(text[2:], ']', ']')
+
+					if n < 0 {
+						mark(text)
+						copy.WriteString("[[")
+						text = text[2:]
+						continue
+					}
+
+					text = text[n + 2:]
+
+					eat_newlines = (last_rune == '\n')
+					eat_spaces   = (last_rune == ' ')
+					continue
+				}
+			}
+
+			if text[0] == '/' && len(text) > 1 && text[1] == '*' {
+				if is_escaped {
+					mark(text)
+					text = text[2:]
+					copy.WriteString("/*")
+					is_escaped = false
+					continue
+				}
+
+				n := rune_pair(text[2:], '*', '/')
+
+				if n < 0 {
+					mark(text)
+					copy.WriteString("/*")
+					text = text[2:]
+					continue
+				}
+
+				parse_data_table(data, text[2:n], source.offset(text) + 2, source)
+
+				text = text[n + 2:]
+
+				eat_newlines = (last_rune == '\n')
+				eat_spaces   = (last_rune == ' ')
+				continue
+			}
+
+			here := text
+
+			r, width := get_rune(text)
+			text = text[width:]
+
+			if r == '\\' {
+				last_rune = '\\'
+
+				if is_escaped {
+					mark(here)
+					copy.WriteRune('\\')
+					is_escaped = false
+					continue
+				}
+
+				is_escaped = true
+				continue
+			}
+
+			if is_escaped {
+				mark(here)
+				copy.WriteRune('\\')
+			}
+			is_escaped = false
+
+			last_rune = r
+
+			if r == '\n' && eat_newlines {
+				continue
+			} else {
+				eat_newlines = false
+			}
+			if r == ' ' && eat_spaces {
+				continue
+			} else {
+				eat_spaces = false
+			}
+
+			mark(here)
+			copy.WriteRune(r)
+
+			if r == '\n' {
+				line_start = true
+			}
+		}
+
+		mark(text)
+
+		text = copy.String() // return de-boned string
+
+		source = &source_map{new_line_index(text), body_lines, len(text)}
+	}
+
+	if data.Title.Title == "" && options.Path != "" {
+		data.Title.Title = filepath.Base(options.Path)
+	}
+
+	data.WordCount = word_count(text)
+
+	//
+	// line parsing
+	//
+	nodes := make([]Section, 0, 256)
+
+	for {
+		if len(left_trim(text)) == 0 {
+			break
+		}
+
+		count := count_rune(text, '\n')
+
+		if count > 0 {
+			if count == 1 {
+				text = text[1:]
+				continue
+			}
+
+			nodes = append(nodes, Section{
+				Type:     WHITESPACE,
+				Level:    count - 1,
+				Position: source.at_suffix(text[1:]),
+			})
+			text = text[count:]
+			continue
+		}
+
+		pos := source.at_suffix(text)
+
+		dirty_line := extract_to_newline(text)
+		clean_line := strings.TrimSpace(dirty_line)
+		text = text[len(dirty_line):]
+
+		if len(clean_line) == 0 {
+			nodes = append(nodes, Section{
+				Type:     WHITESPACE,
+				Level:    1,
+				Position: pos,
+			})
+			continue
+		}
+
+		pos.Column = rune_count(dirty_line) - rune_count(left_trim(dirty_line)) + 1
+
+		// this is just a simple guard-rail for lines that have
+		// only a single character; they can't be anything but
+		// action by definition and we cut a whole bunch of
+		// additional len() checks out from subsequent code
+		if len(clean_line) == 1 {
+			nodes = append(nodes, Section{
+				Type:     ACTION,
+				Text:     clean_line,
+				Position: pos,
+			})
+			continue
+		}
+
+		the_type := ACTION
+		level    := 0
+
+		switch clean_line[0] {
+		case '!':
+			the_type   = ACTION
+			clean_line = left_trim(clean_line[1:])
+
+			nodes = append(nodes, Section{
+				Type:     the_type,
+				Text:     clean_line,
+				Position: pos,
+			})
+			continue
+
+		case '@':
+			the_type   = CHARACTER
+			clean_line = clean_line[1:]
+
+			if clean_line[len(clean_line) - 1] == '^' {
+				clean_line = clean_line[:len(clean_line) - 1]
+				level += 1
+			}
+
+		case '~':
+			n := count_rune(clean_line, '~')
+			if n == 2 {
+				break
+			}
+
+			the_type   = LYRIC
+			clean_line = left_trim(clean_line[1:])
+
+		case '=':
+			n := count_rune(clean_line, '=')
+
+			if n >= 3 {
+				the_type = PAGE_BREAK
+			} else if n == 1 {
+				the_type   = SYNOPSIS
+				clean_line = left_trim(clean_line[1:])
+			}
+
+		case '#':
+			// if we consider it to be a variable, we action it
+			if r, _ := get_rune(clean_line[1:]); !(r == '#' || unicode.IsSpace(r)) {
+				the_type = ACTION
+
+			// otherwise it's a section and we treat it
+			// accordingly.
+			} else {
+				n := count_rune(clean_line, '#')
+
+				the_type   = SECTION
+				level      = n
+				clean_line = left_trim(clean_line[n:])
+
+				if level > 3 {
+					level = 3
+				}
+			}
+
+		case '(':
+			if clean_line[len(clean_line) - 1] == ')' {
+				if last_node, success := get_last_section(nodes); success {
+					if IsCharacterTrain(last_node.Type) {
+						the_type = PARENTHETICAL
+					}
+				}
+			}
+
+		case '>':
+			clean_line = left_trim(clean_line[1:])
+
+			the_type = TRANSITION
+
+			if clean_line[len(clean_line) - 1] == '<' {
+				the_type   = CENTERED
+				clean_line = right_trim(clean_line[:len(clean_line) - 1])
+			}
+
+		case '.':
+			if left_trim(clean_line[1:])[0] != '.' {
+				name, number, success := get_scene_number(left_trim(clean_line[1:]))
+				if !success {
+					name = left_trim(clean_line[1:])
+				}
+
+				nodes = append(nodes, Section{
+					Type:        SCENE,
+					Text:        name,
+					SceneNumber: number,
+					Position:    pos,
+				})
+				continue
+			}
+		}
+
+		// if we're still marked as action
+		// check general syntaxes
+		if the_type == ACTION {
+			if n := strings.IndexRune(clean_line, ':'); n > 0 {
+				count := 0
+
+				for _, c := range clean_line[:n] {
+					if !unicode.IsLetter(c) {
+						break
+					}
+					count += 1
+				}
+
+				if n == count {
+					switch homogenise(clean_line[:n]) {
+					case "header":
+						the_type   = HEADER
+						clean_line = left_trim(clean_line[n + 1:])
+					case "footer":
+						the_type   = FOOTER
+						clean_line = left_trim(clean_line[n + 1:])
+					}
+
+					if the_type != ACTION {
+						nodes = append(nodes, Section{
+							Type:     the_type,
+							Text:     clean_line,
+							Position: pos,
+						})
+						continue
+					}
+				}
+			}
+
+			if IsValidScene(clean_line) {
+				// can we combine this with the scene in the switch above? ^^
+				name, number, success := get_scene_number(left_trim(clean_line))
+				if !success {
+					name = clean_line
+				}
+
+				nodes = append(nodes, Section{
+					Type:        SCENE,
+					Text:        name,
+					SceneNumber: number,
+					Position:    pos,
+				})
+				continue
+
+			} else if IsValidTransition(clean_line) {
+				the_type = TRANSITION
+
+			} else if last_node, success := get_last_section(nodes); success && IsCharacterTrain(last_node.Type) {
+				the_type = DIALOGUE
+
+			} else if IsValidCharacter(clean_line) {
+				the_type = CHARACTER
+
+				if clean_line[len(clean_line) - 1] == '^' {
+					clean_line = strings.TrimSpace(clean_line[:len(clean_line) - 1])
+					level += 1
+				}
+
+				// @todo this rule should not have precedence over the DIALOGUE force below
+				// exceptionally short lines of dialogue are mistakenly indented: "I—"
+
+			} else {
+				clean_line = dirty_line // plain action uses dirty_line
+			}
+		}
+
+		nodes = append(nodes, Section{
+			Type:     the_type,
+			Level:    level,
+			Text:     clean_line,
+			Position: pos,
+		})
+	}
+
+	var last_char *Section
+	any_visible := false
+
+	speaker := -1
+
+	for i := range nodes {
+		node := &nodes[i]
+
+		handle_rev_tags(node)
+
+		if speaker >= 0 {
+			switch node.Type {
+			case DIALOGUE, DUAL_DIALOGUE, LYRIC, DUAL_LYRIC:
+				data.Characters[speaker].Words += word_count(node.Text)
+			}
+			if !IsCharacterTrain(node.Type) {
+				speaker = -1
+			}
+		}
+
+		if !any_visible && node.Type > IS_PRINTABLE && !IsCharacterTrain(node.Type) {
+			any_visible = true
+		}
+
+		if node.Type == CHARACTER || node.Type == DUAL_CHARACTER {
+			if i == len(nodes) - 1 || !IsCharacterTrain(nodes[i + 1].Type) {
+				node.Type = ACTION
+				diagnose(&data.Diagnostics, WARNING, CODE_DEMOTED_CHARACTER, node.Position, "%q has no dialogue after it, so it's treated as action", node.Text)
+				continue
+			}
+
+			if node.Level == 1 {
+				if last_char == nil || last_char.Level == 2 || any_visible {
+					node.Level = 0
+				} else if last_char.Level == 0 {
+					last_char.Level = 1
+					node.Level = 2
+				}
+			}
+
+			last_char = node
+			any_visible = false
+
+			name := strings.ToLower(node.Text)
+
+			for i, c := range name {
+				if c == '(' {
+					name = strings.TrimSpace(name[:i])
+					break
+				}
+			}
+
+			if x, success := data.lookup[name]; success {
+				c := &data.Characters[x]
+				c.Lines += 1
+				speaker = x
+			} else {
+				speaker = len(data.Characters)
+				data.lookup[name] = speaker
+				data.Characters = append(data.Characters, Character{
+					Name:   title_case(name),
+					Gender: "unknown",
+					Lines:  1,
+				})
+			}
+		}
+	}
+
+	has_dual := false
+	revision := ""
+	level    := 0
+
+	for i := range nodes {
+		node := &nodes[i]
+
+		if node.Type == CHARACTER {
+			has_dual = node.Level > 0
+			revision = node.Revision
+
+			if has_dual {
+				node.Type += 1
+				level = node.Level
+			}
+			continue
+		}
+
+		if IsCharacterTrain(node.Type) {
+			if has_dual {
+				node.Type += 1
+				node.Level = level
+			}
+			node.Revision = revision
+			continue
+		}
+
+		revision = ""
+		has_dual = false
+	}
+
+	data.Content = nodes
+
+	check_scene_numbers(data)
+}
+
+// offset is where the table text begins in the source
+func parse_data_table(data *Document, text string, offset int, source *source_map) {
+	offset += len(text) - len(left_trim(text))
+	text = strings.TrimSpace(text)
+	table_length := len(text)
+
+	if !(len(text) > 8) {
+		return
+	}
+
+	test_string := strings.ToLower(text[:9])
+	if test_string[:8] != "[gender." && test_string != "[template" && test_string != "[location" {
+		return
+	}
+
+	const MODE_GENDER   = 0
+	const MODE_TEMPLATE = 1
+	const MODE_LOCATION = 2
+	current_mode := MODE_GENDER
+
+	current_gender   := ""
+	current_group    := ""
+	current_template := TYPE_NONE
+
+	for len(text) > 0 {
+		pos := source.at(offset + table_length - len(text))
+
+		line := extract_to_newline(text)
+		text = text[len(line):]
+		line = strings.TrimSpace(line)
+		text = left_trim(text)
+
+		if line == "" {
+			continue
+		}
+
+		if line[0] == '[' {
+			if line[len(line) - 1] != ']' {
+				return
+			}
+
+			line = strings.ToLower(strings.TrimSpace(line[1:len(line) - 1]))
+
+			if strings.HasPrefix(line, "gender.") {
+				current_mode   = MODE_GENDER
+				current_gender = line[7:]
+				continue
+			}
+
+			if strings.HasPrefix(line, "location.") {
+				current_mode  = MODE_LOCATION
+				current_group = line[9:]
+				continue
+			}
+
+			current_template = TYPE_NONE
+
+			if strings.HasPrefix(line, "template.") {
+				current_mode = MODE_TEMPLATE
+				t, success := StringToSectionType(line[9:])
+				if success {
+					current_template = t
+				}
+				continue
+			} else if line == "template" {
+				current_mode = MODE_TEMPLATE
+				continue
+			} else {
+				// @error in data table heading
+			}
+			continue
+		}
+
+		if current_mode == MODE_LOCATION {
+			names := strings.Split(line, "|")
+			for i, entry := range names {
+				names[i] = strings.TrimSpace(entry)
+			}
+
+			location := Location{
+				Name:  names[0],
+				Group: current_group,
+			}
+			if len(names) > 1 {
+				location.OtherNames = names[1:]
+			}
+
+			data.Locations = append(data.Locations, location)
+		} else if current_mode == MODE_GENDER {
+			names := strings.Split(line, "|")
+			for i, entry := range names {
+				names[i] = strings.TrimSpace(entry)
+			}
+			name := names[0]
+			names = names[1:]
+
+			n := len(data.Characters)
+
+			if len(names) > 0 {
+				for _, x := range names {
+					data.lookup[strings.ToLower(x)] = n
+				}
+			} else {
+				names = nil
+			}
+
+			data.lookup[strings.ToLower(name)] = n
+			data.Characters = append(data.Characters, Character{
+				Name:       name,
+				Gender:     current_gender,
+				OtherNames: names,
+			})
+		} else {
+			data.Settings.Templates = append(data.Settings.Templates, TemplateRule{
+				Type:     current_template,
+				Text:     line,
+				Position: pos,
+			})
+		}
+	}
+}
+
+func get_last_section(nodes []Section) (*Section, bool) {
+	if len(nodes) > 0 {
+		return &nodes[len(nodes) - 1], true
+	}
+	return nil, false
+}
+
+// returns the text without its title page and the
+// number of lines that were removed from the top
+func consume_title_page(input string) (string, int) {
+	if n := strings.IndexRune(input, '\n'); n > -1 {
+		if is_title_element(input[:n]) {
+			if n := rune_pair(input, '\n', '\n'); n > -1 {
+				text := strings.TrimSpace(input[n:])
+				return text, strings.Count(input[:len(input) - len(text)], "\n")
+			}
+		}
+	}
+
+	return input, 0
+}
+
+func IsValidCharacter(line string) bool {
+	for i, c := range line {
+		if !is_format_char(c) {
+			line = line[i:]
+			break
+		}
+	}
+
+	// characters must start with a letter
+	if !unicode.IsLetter(rune(line[0])) {
+		return false
+	}
+
+	has_letters := false
+	first_char := true
+
+	for len(line) > 0 {
+		c, rune_width := get_rune(line)
+
+		// allow for rev markers
+		if c == '@' {
+			if _, w := extract_ident(line[rune_width:]); w > 0 {
+				line = line[rune_width + w:]
+				continue
+			}
+		}
+
+		if c == '(' && !first_char {
+			for i, c := range line {
+				if c == ')' {
+					line = line[i:]
+					break
+				}
+			}
+		}
+
+		if unicode.IsLetter(c) {
+			has_letters = true
+
+			if !unicode.IsUpper(c) {
+				return false
+			}
+		}
+
+		line = line[rune_width:]
+		first_char = false
+	}
+
+	return has_letters
+}
+
+func find_title_colon(input string) (int, bool) {
+	for i, c := range input {
+		if c == '\n' {
+			return 0, false
+		}
+		if c == ':' {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func is_title_element(line string) bool {
+	found_colon := false
+
+	for i, c := range line {
+		if c == ':' {
+			line = strings.TrimSpace(homogenise(line[:i]))
+			found_colon = true
+			break
+		}
+	}
+
+	if found_colon && lang_title_page(line) {
+		return true
+	}
+
+	return false
+}
+
+func IsValidTransition(line string) bool {
+	for i := len(line) - 1; i >= 0; i-- {
+		c := line[i]
+		if ascii_space[c] == 1 {
+			return lang_transition(strings.ToLower(line[i+1:]))
+		}
+	}
+	return false
+}
+
+func get_scene_number(text string) (string, string, bool) {
+	if text[len(text) - 1] == '#' {
+		n := 0
+		t := text[:len(text) - 1]
+
+		for i := len(t) - 1; i > 0; i-- {
+			the_rune, _ := utf8.DecodeLastRuneInString(t[:i + 1])
+
+			if unicode.IsSpace(the_rune) {
+				break
+			}
+			if the_rune == '#' {
+				n = i
+				break
+			}
+		}
+
+		if n != 0 {
+			return strings.TrimSpace(text[:n]), t[n + 1:], true
+		}
+	}
+
+	return "", "", false
+}
+
+func IsValidScene(line string) bool {
+	word := ""
+
+	for i, c := range line {
+		if c == '.' {
+			word = line[:i]
+			break
+		}
+
+		if c >= utf8.RuneSelf {
+			if unicode.IsSpace(c) {
+				word = line[:i]
+				break
+			}
+			continue
+		}
+
+		if ascii_space[c] == 1 {
+			word = line[:i]
+			break
+		}
+	}
+
+	if len(word) > 0 {
+		return lang_scene(strings.ToLower(clean_string(word)))
+	}
+
+	return false
+}
+
+// SplitScene breaks a scene heading into its setting (INT,
+// EXT, INT/EXT or EXT/INT), location and time of day, such as
+// "INT", "KITCHEN" and "NIGHT".  the time is whatever follows
+// the last dash, so a heading without one has no time, and
+// one without a known prefix has no setting.
+func SplitScene(line string) (string, string, string) {
+	setting := ""
+	rest    := strings.TrimLeft(strings.TrimSpace(line), ".")
+
+	for i, c := range rest {
+		if c == '.' || unicode.IsSpace(c) {
+			setting = lang_scene_setting(strings.ToLower(rest[:i]))
+			if setting != "" || lang_scene(strings.ToLower(rest[:i])) {
+				rest = strings.TrimLeft(rest[i:], ". \t")
+			}
+			break
+		}
+	}
+
+	time := ""
+
+	for _, dash := range [...]string{" - ", " – ", " — "} {
+		if i := strings.LastIndex(rest, dash); i >= 0 {
+			time = strings.TrimSpace(rest[i + len(dash):])
+			rest = rest[:i]
+			break
+		}
+	}
+
+	return setting, strings.TrimSpace(rest), time
+}
+
+func handle_rev_tags(node *Section) {
+	index := strings.IndexRune(node.Text, '@')
+	if index < 0 {
+		return
+	}
+
+	word, width := extract_ident(node.Text[index + 1:])
+	if width > 0 {
+		node.Revision = strings.ToLower(word)
+
+		buffer := new(strings.Builder)
+		buffer.Grow(len(node.Text))
+
+		offset := index + width + 1
+
+		if offset == len(node.Text) {
+			buffer.WriteString(strings.TrimSpace(node.Text[:index]))
+		} else {
+			buffer.WriteString(node.Text[:index])
+			buffer.WriteString(strings.TrimSpace(node.Text[offset:]))
+		}
+
+		node.Text = buffer.String()
+	}
+}
+
+func StringToSectionType(x string) (SectionType, bool) {
+	switch strings.ToLower(x) {
+	case "action":
+		return ACTION, true
+	case "scene":
+		return SCENE, true
+	case "character":
+		return CHARACTER, true
+	case "dual_character":
+		return DUAL_CHARACTER, true
+	case "parenthetical":
+		return PARENTHETICAL, true
+	case "dual_parenthetical":
+		return DUAL_PARENTHETICAL, true
+	case "dialogue":
+		return DIALOGUE, true
+	case "dual_dialogue":
+		return DUAL_DIALOGUE, true
+	case "lyric":
+		return LYRIC, true
+	case "dual_lyric":
+		return DUAL_LYRIC, true
+	case "transition":
+		return TRANSITION, true
+	case "synopsis":
+		return SYNOPSIS, true
+	case "centered":
+		return CENTERED, true
+	case "section":
+		return SECTION, true
+	case "section2":
+		return SECTION2, true
+	case "section3":
+		return SECTION3, true
+	}
+	return WHITESPACE, false
+}
@@ -0,0 +1,103 @@
+/*
+	Meander
+	A portable Fountain utility for production writing
+	Copyright (C) 2022-2023 Harley Denham
+*/
+
+package fountain
+
+import "fmt"
+import "sort"
+
+// a location in one of the Document's source files;
+// File indexes Document.Files
+type Position struct {
+	File   int `json:"file"`
+	Line   int `json:"line,omitempty"`
+	Column int `json:"column,omitempty"`
+}
+
+type SourceFile struct {
+	Path string `json:"path"`
+
+	// the include directive that pulled this file
+	// in, or nil for the root document
+	From *Position `json:"included_from,omitempty"`
+}
+
+// Location formats a position as "path:line:column"
+func (data *Document) Location(pos Position) string {
+	path := "?"
+	if pos.File >= 0 && pos.File < len(data.Files) {
+		path = data.Files[pos.File].Path
+	}
+	if pos.Column > 0 {
+		return fmt.Sprintf("%s:%d:%d", path, pos.Line, pos.Column)
+	}
+	return fmt.Sprintf("%s:%d", path, pos.Line)
+}
+
+// IncludeStack returns the position followed by each
+// include directive that led to it, innermost first
+func (data *Document) IncludeStack(pos Position) []Position {
+	stack := []Position{pos}
+
+	for pos.File >= 0 && pos.File < len(data.Files) {
+		from := data.Files[pos.File].From
+		if from == nil {
+			break
+		}
+		pos = *from
+		stack = append(stack, pos)
+	}
+
+	return stack
+}
+
+// line_index maps byte offsets in a string to
+// zero-based line numbers
+type line_index []int
+
+func new_line_index(text string) line_index {
+	index := make(line_index, 1, 256)
+
+	for i := 0; i < len(text); i += 1 {
+		if text[i] == '\n' {
+			index = append(index, i + 1)
+		}
+	}
+
+	return index
+}
+
+func (index line_index) line(offset int) int {
+	return sort.SearchInts(index, offset + 1) - 1
+}
+
+// source_map resolves byte offsets in a string built by
+// merge_text, or the de-boned copy of it, to positions
+type source_map struct {
+	index  line_index
+	lines  []Position
+	length int
+}
+
+func (source *source_map) at(offset int) Position {
+	line := source.index.line(offset)
+	if line >= len(source.lines) {
+		line = len(source.lines) - 1
+	}
+	if line < 0 {
+		return Position{}
+	}
+	return source.lines[line]
+}
+
+// the offset of a suffix of the mapped string
+func (source *source_map) offset(suffix string) int {
+	return source.length - len(suffix)
+}
+
+func (source *source_map) at_suffix(suffix string) Position {
+	return source.at(source.offset(suffix))
+}
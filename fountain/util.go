@@ -0,0 +1,386 @@
+/*
+	Meander
+	A portable Fountain utility for production writing
+	Copyright (C) 2022-2023 Harley Denham
+*/
+
+package fountain
+
+import "os"
+import "strings"
+import "unicode"
+import "unicode/utf8"
+import "path/filepath"
+
+var ascii_space = [256]uint8{'\t':1,'\n':1,'\v':1,'\f':1,'\r':1,' ':1}
+
+var get_rune = utf8.DecodeRuneInString
+
+func left_trim(input string) string {
+	start := 0
+
+	for ; start < len(input); start += 1 {
+		c := input[start]
+		if c >= utf8.RuneSelf {
+			return strings.TrimFunc(input[start:], unicode.IsSpace)
+		}
+		if ascii_space[c] == 0 {
+			break
+		}
+	}
+
+	return input[start:]
+}
+
+func right_trim(input string) string {
+	start := 0
+	stop := len(input)
+
+	for ; stop > start; stop-- {
+		c := input[stop-1]
+
+		if c >= utf8.RuneSelf {
+			return strings.TrimFunc(input[start:stop], unicode.IsSpace)
+		}
+
+		if ascii_space[c] == 0 {
+			break
+		}
+	}
+
+	return input[start:stop]
+}
+
+func consume_newlines(input string) string {
+	for i, c := range input {
+		if c != '\n' {
+			return input[i:]
+		}
+	}
+	return input
+}
+
+func is_format_char(x rune) bool {
+	switch x {
+	case '*':  return true
+	case '+':  return true
+	case '~':  return true
+	case '_':  return true
+	case ']':  return true
+	case '[':  return true
+	case '$': return true
+	case '#': return true
+	case '\\': return true
+	case '\n': return true
+	}
+	return false
+}
+
+func extract_ident(input string) (string, int) {
+	width := 0
+	for _, c := range input {
+		if !(unicode.IsLetter(c) || c == '_') {
+			return input[:width], width
+		}
+		width += utf8.RuneLen(c)
+	}
+	return input, width
+}
+
+func normalise_text(input string) string {
+	buffer := strings.Builder{}
+	buffer.Grow(len(input))
+
+	input = strings.TrimSpace(input)
+
+	last_rune := 'a'
+
+	for _, c := range input {
+		switch c {
+		case '“', '”':
+			buffer.WriteRune('"')
+			last_rune = c
+			continue
+
+		case '’':
+			buffer.WriteRune('\'')
+			last_rune = c
+			continue
+
+		case '`', '‘':
+			buffer.WriteRune('\'')
+			last_rune = c
+			continue
+
+		case '\n':
+			if last_rune == '\r' {
+				continue
+			}
+			buffer.WriteRune('\n')
+			last_rune = c
+			continue
+
+		case '\r':
+			if last_rune == '\n' {
+				continue
+			}
+			buffer.WriteRune('\n')
+			last_rune = c
+			continue
+
+		case '\t':
+			buffer.WriteString(`    `) // 4 spaces
+			last_rune = c
+			continue
+		}
+
+		last_rune = c
+		buffer.WriteRune(c)
+	}
+
+	return buffer.String()
+}
+
+// homogenise "Draft Date" or "draft_date" into "draftdate"
+// this helps us simplify any multi-matches in the title page
+func homogenise(input string) string {
+	buffer := strings.Builder{}
+	buffer.Grow(len(input))
+
+	for _, c := range input {
+		if c >= utf8.RuneSelf {
+			continue
+		}
+		if ascii_space[c] == 1 {
+			continue
+		}
+		if c == '_' || c == '-' {
+			continue
+		}
+		buffer.WriteRune(unicode.ToLower(c))
+	}
+
+	return buffer.String()
+}
+
+func extract_to_newline(input string) string {
+	for i, c := range input {
+		if c == '\n' {
+			return input[:i]
+		}
+	}
+	return input
+}
+
+// utf8.RuneCountInString
+func rune_count(input string) int {
+	count := 0
+	for range input {
+		count += 1
+	}
+	return count
+}
+
+func count_rune(input string, r rune) int {
+	count := 0
+	for _, c := range input {
+		if c != r {
+			return count
+		}
+		count += 1
+	}
+	return count
+}
+
+func rune_on_line(input string, x rune) int {
+	for i, c := range input {
+		if c == x {
+			return i + 1
+		}
+		if c == '\n' {
+			break
+		}
+	}
+
+	return -1
+}
+
+func rune_pair(text string, x, y rune) int {
+	last := 'a'
+
+	for i, c := range text {
+		if c == y && last == x { // swapped from above
+			return i + 1
+		}
+		last = c
+	}
+
+	return -1
+}
+
+func clean_string(input string) string {
+	if input == "" {
+		return ""
+	}
+
+	buffer := strings.Builder{}
+	buffer.Grow(len(input))
+
+	for _, c := range input {
+		if c == '\n' {
+			buffer.WriteRune(' ')
+			continue
+		}
+		if is_format_char(c) {
+			continue
+		}
+		buffer.WriteRune(c)
+	}
+
+	return buffer.String()
+}
+
+func short_words(t string) bool {
+	switch t {
+	case "a":   return true
+	case "an":  return true
+	case "and": return true
+	case "the": return true
+	case "on":  return true
+	case "to":  return true
+	case "in":  return true
+	case "for": return true
+	case "nor": return true
+	case "or":  return true
+	}
+	return false
+}
+
+func title_case(input string) string {
+	words := strings.Split(input, " ")
+
+	for i, word := range words {
+		if i > 0 && short_words(word) {
+			continue
+		}
+
+		buffer := strings.Builder{}
+		buffer.Grow(len(word))
+
+		for len(word) > 0 {
+			c, width := get_rune(word)
+
+			if buffer.Len() == 0 {
+				buffer.WriteRune(unicode.ToUpper(c))
+				word = word[width:]
+				continue
+			}
+
+			if c == '-' || c == '—' {
+				buffer.WriteRune(unicode.ToLower(c))
+				word = word[width:]
+
+				c, width = get_rune(word)
+
+				buffer.WriteRune(unicode.ToUpper(c))
+				word = word[width:]
+				continue
+			}
+
+			buffer.WriteRune(unicode.ToLower(c))
+			word = word[width:]
+		}
+
+		words[i] = buffer.String()
+	}
+
+	return strings.Join(words, " ")
+}
+
+func word_count(text string) int {
+	total_count := 0
+	each_word   := 0
+
+	for {
+		if len(text) == 0 {
+			break
+		}
+
+		r, w := get_rune(text)
+		text = text[w:]
+
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			each_word += 1
+			continue
+		}
+
+		if each_word > 0 {
+			total_count += 1
+			each_word = 0
+		}
+	}
+
+	if each_word > 0 {
+		total_count += 1
+	}
+
+	return total_count
+}
+
+func load_file(source_file string) (string, bool) {
+	bytes, err := os.ReadFile(source_file)
+	if err != nil {
+		return "", false
+	}
+	return string(bytes), true
+}
+
+// leading_lines counts the lines that normalise_text
+// trims from the top of the input
+func leading_lines(input string) int {
+	count := 0
+	last_rune := 'a'
+
+	for _, c := range input {
+		if !unicode.IsSpace(c) {
+			break
+		}
+
+		switch c {
+		case '\n':
+			if last_rune == '\r' {
+				continue
+			}
+			count += 1
+		case '\r':
+			if last_rune == '\n' {
+				continue
+			}
+			count += 1
+		}
+
+		last_rune = c
+	}
+
+	return count
+}
+
+func include_path(parent, input string) string {
+	if !filepath.IsAbs(input) {
+		return filepath.Join(filepath.Dir(parent), input)
+	}
+	return input
+}
+
+// used to compare paths; if the working directory
+// can't be found, the cleaned path is good enough
+func absolute_path(input string) string {
+	if x, err := filepath.Abs(input); err == nil {
+		return x
+	}
+	return filepath.Clean(input)
+}
+
+func is_glob(input string) bool {
+	return strings.ContainsAny(input, "*?[")
+}
@@ -0,0 +1,399 @@
+/*
+	Meander
+	A portable Fountain utility for production writing
+	Copyright (C) 2022-2023 Harley Denham
+*/
+
+package fountain
+
+import "strings"
+import "unicode"
+import "unicode/utf8"
+
+// Write turns a Document back into Fountain.  the output
+// is canonical rather than a copy of any original source:
+// elements are spaced out and given force-characters
+// wherever the parser wouldn't recognise them otherwise,
+// so a Document built by hand, with or without whitespace
+// sections, reads back in as the same content.
+func Write(data *Document) string {
+	buffer := new(strings.Builder)
+	buffer.Grow(len(data.Content) * 64)
+
+	write_title_page(buffer, &data.Title)
+
+	last     := TYPE_NONE
+	revision := "" // the revision of the current speech
+
+	for i := range data.Content {
+		section := &data.Content[i]
+
+		switch section.Type {
+		case HEADER, FOOTER:
+			continue
+
+		case WHITESPACE:
+			if last == TYPE_NONE {
+				continue
+			}
+			n := section.Level
+			if n < 1 {
+				n = 1
+			}
+			buffer.WriteString(strings.Repeat("\n", n))
+			last = WHITESPACE
+			continue
+		}
+
+		if needs_blank_line(last, section.Type) {
+			buffer.WriteRune('\n')
+		}
+
+		text := section.Text
+
+		switch section.Type {
+		case SCENE:
+			if !IsValidScene(text) {
+				buffer.WriteRune('.')
+			}
+			buffer.WriteString(text)
+			write_revision(buffer, section.Revision)
+			if section.SceneNumber != "" {
+				buffer.WriteString(" #")
+				buffer.WriteString(section.SceneNumber)
+				buffer.WriteRune('#')
+			}
+
+		case CHARACTER, DUAL_CHARACTER:
+			if !IsValidCharacter(text) {
+				buffer.WriteRune('@')
+			}
+			buffer.WriteString(text)
+			if section.Type == DUAL_CHARACTER && section.Level == 2 {
+				buffer.WriteString(" ^")
+			}
+
+			// the parser gives the character's revision
+			// to the whole speech, so it only goes here
+			revision = section.Revision
+			write_revision(buffer, revision)
+
+		case PARENTHETICAL, DUAL_PARENTHETICAL, DIALOGUE, DUAL_DIALOGUE:
+			buffer.WriteString(text)
+			if section.Revision != revision {
+				write_revision(buffer, section.Revision)
+			}
+
+		case LYRIC, DUAL_LYRIC:
+			buffer.WriteRune('~')
+			buffer.WriteString(text)
+			if section.Revision != revision {
+				write_revision(buffer, section.Revision)
+			}
+
+		case TRANSITION:
+			if !IsValidTransition(text) {
+				buffer.WriteString("> ")
+			}
+			buffer.WriteString(text)
+			write_revision(buffer, section.Revision)
+
+		case CENTERED:
+			buffer.WriteString("> ")
+			buffer.WriteString(text)
+			buffer.WriteString(" <")
+			write_revision(buffer, section.Revision)
+
+		case SECTION, SECTION2, SECTION3:
+			level := section.Level
+			if level < 1 {
+				level = int(section.Type - SECTION) + 1
+			}
+			buffer.WriteString(strings.Repeat("#", level))
+			buffer.WriteRune(' ')
+			buffer.WriteString(text)
+
+		case SYNOPSIS:
+			buffer.WriteString("= ")
+			buffer.WriteString(text)
+
+		case PAGE_BREAK:
+			buffer.WriteString("===")
+
+		default:
+			// capitals only look like a character
+			// if more action follows straight on
+			joined := i + 1 < len(data.Content) && data.Content[i + 1].Type == ACTION
+
+			if is_forced_action(text, joined) {
+				buffer.WriteRune('!')
+			}
+			buffer.WriteString(text)
+			write_revision(buffer, section.Revision)
+		}
+
+		buffer.WriteRune('\n')
+		last = section.Type
+	}
+
+	write_gender_tables(buffer, data.Characters)
+
+	return buffer.String()
+}
+
+// needs_blank_line reports whether Fountain needs a blank
+// line between two elements to tell them apart.  only lines
+// of the same action and the lines of a speech run together.
+func needs_blank_line(last, next SectionType) bool {
+	switch {
+	case last == TYPE_NONE || last == WHITESPACE:
+		return false
+	case last == ACTION && next == ACTION:
+		return false
+	case IsCharacterTrain(last) && IsCharacterTrain(next):
+		return next == CHARACTER || next == DUAL_CHARACTER
+	}
+	return true
+}
+
+// is_forced_action reports whether a line of action would
+// be read as something else if it were left as it is,
+// including by merge, which would take a line such as
+// "include: this" as a file to pull in
+func is_forced_action(text string, joined bool) bool {
+	if text == "" {
+		return false
+	}
+
+	c, _ := utf8.DecodeRuneInString(text)
+	switch c {
+	case '.', '!', '@', '#', '~', '>', '=':
+		return true
+	}
+
+	return is_include(text) || IsValidScene(text) || IsValidTransition(text) || (joined && IsValidCharacter(text))
+}
+
+func write_revision(buffer *strings.Builder, revision string) {
+	if revision != "" {
+		buffer.WriteString(" @")
+		buffer.WriteString(revision)
+	}
+}
+
+func write_title_page(buffer *strings.Builder, title *TitlePage) {
+	fields := [...]struct{
+		key   string
+		value string
+	}{
+		{"Title",      title.Title},
+		{"Credit",     title.Credit},
+		{"Author",     title.Author},
+		{"Source",     title.Source},
+		{"Notes",      title.Notes},
+		{"Draft date", title.DraftDate},
+		{"Copyright",  title.Copyright},
+		{"Revision",   title.Revision},
+		{"Contact",    title.Contact},
+		{"Info",       title.Info},
+	}
+
+	any_written := false
+
+	for _, field := range fields {
+		if field.value == "" {
+			continue
+		}
+
+		buffer.WriteString(field.key)
+		buffer.WriteRune(':')
+
+		// multi-line values go on their own
+		// indented lines beneath the key
+		if strings.ContainsRune(field.value, '\n') {
+			for _, line := range strings.Split(field.value, "\n") {
+				buffer.WriteString("\n\t")
+				buffer.WriteString(line)
+			}
+		} else {
+			buffer.WriteRune(' ')
+			buffer.WriteString(field.value)
+		}
+
+		buffer.WriteRune('\n')
+		any_written = true
+	}
+
+	if any_written {
+		buffer.WriteRune('\n')
+	}
+}
+
+// characters with a known gender or other names are put
+// back into [gender] tables, in the order they first
+// appear, so the character list survives the round trip
+func write_gender_tables(buffer *strings.Builder, characters []Character) {
+	order   := make([]string, 0, 8)
+	genders := make(map[string][]*Character, 8)
+
+	for i := range characters {
+		c := &characters[i]
+
+		gender := strings.ToLower(c.Gender)
+		if gender == "" || (gender == "unknown" && len(c.OtherNames) == 0) {
+			continue
+		}
+
+		if _, exists := genders[gender]; !exists {
+			order = append(order, gender)
+		}
+		genders[gender] = append(genders[gender], c)
+	}
+
+	if len(order) == 0 {
+		return
+	}
+
+	buffer.WriteString("\n/*\n")
+
+	for i, gender := range order {
+		if i > 0 {
+			buffer.WriteRune('\n')
+		}
+
+		buffer.WriteString("\t[gender.")
+		buffer.WriteString(gender)
+		buffer.WriteString("]\n")
+
+		for _, c := range genders[gender] {
+			buffer.WriteRune('\t')
+			buffer.WriteString(c.Name)
+			for _, name := range c.OtherNames {
+				buffer.WriteString(" | ")
+				buffer.WriteString(name)
+			}
+			buffer.WriteRune('\n')
+		}
+	}
+
+	buffer.WriteString("*/\n")
+}
+
+// the markup for each span style, outermost first
+var span_markers = [...]struct{
+	style string
+	open  string
+	close string
+}{
+	{"note",      "[[", "]]"},
+	{"highlight", "+",  "+"},
+	{"strikeout", "~~", "~~"},
+	{"underline", "_",  "_"},
+	{"bold",      "**", "**"},
+	{"italic",    "*",  "*"},
+}
+
+// spans_markup turns a list of spans from the data command
+// back into Fountain markup.  styles are opened and closed
+// only where they change, and always against the words
+// they apply to, so the spaces either side stay outside.
+func spans_markup(spans []Span) string {
+	buffer := new(strings.Builder)
+
+	open := make([]int, 0, len(span_markers)) // indices into span_markers
+
+	close_to := func(n int) {
+		text   := buffer.String()
+		spaces := text[len(strings.TrimRight(text, " \n")):]
+
+		buffer.Reset()
+		buffer.WriteString(text[:len(text) - len(spaces)])
+
+		for len(open) > n {
+			buffer.WriteString(span_markers[open[len(open) - 1]].close)
+			open = open[:len(open) - 1]
+		}
+
+		buffer.WriteString(spaces)
+	}
+
+	for _, span := range spans {
+		wanted := make([]bool, len(span_markers))
+		for _, style := range span.Style {
+			for i, m := range span_markers {
+				if m.style == style {
+					wanted[i] = true
+				}
+			}
+		}
+
+		// keep everything up to the first open
+		// style this span doesn't have
+		keep := 0
+		for keep < len(open) && wanted[open[keep]] {
+			keep += 1
+		}
+		close_to(keep)
+
+		text    := escape_markup(span.Text)
+		trimmed := strings.TrimLeft(text, " \n")
+		buffer.WriteString(text[:len(text) - len(trimmed)])
+
+		for i := range span_markers {
+			if !wanted[i] || is_open(open, i) {
+				continue
+			}
+			buffer.WriteString(span_markers[i].open)
+			open = append(open, i)
+		}
+
+		buffer.WriteString(trimmed)
+	}
+
+	close_to(0)
+
+	return buffer.String()
+}
+
+func is_open(open []int, i int) bool {
+	for _, x := range open {
+		if x == i {
+			return true
+		}
+	}
+	return false
+}
+
+// escape_markup puts a backslash in front of anything in
+// plain text that would otherwise be read as markup,
+// variables or counters
+func escape_markup(text string) string {
+	buffer := new(strings.Builder)
+	buffer.Grow(len(text))
+
+	runes := []rune(text)
+
+	for i, c := range runes {
+		next := rune(0)
+		if i + 1 < len(runes) {
+			next = runes[i + 1]
+		}
+
+		switch c {
+		case '\\', '*', '_', '+', '~':
+			buffer.WriteRune('\\')
+		case '[', ']':
+			if next == c {
+				buffer.WriteRune('\\')
+			}
+		case '$', '#':
+			if unicode.IsLetter(next) || next == '_' {
+				buffer.WriteRune('\\')
+			}
+		}
+
+		buffer.WriteRune(c)
+	}
+
+	return buffer.String()
+}
@@ -0,0 +1,211 @@
+/*
+	Meander
+	A portable Fountain utility for production writing
+	Copyright (C) 2022-2023 Harley Denham
+*/
+
+package fountain
+
+import "strings"
+import "testing"
+import "path/filepath"
+
+func TestEscapeMarkup(t *testing.T) {
+	tests := []struct {
+		input  string
+		output string
+	}{
+		{"plain text",      "plain text"},
+		{"*emphasis*",      `\*emphasis\*`},
+		{"**strong**",      `\*\*strong\*\*`},
+		{"snake_case",      `snake\_case`},
+		{"+highlight+",     `\+highlight\+`},
+		{"~~struck~~",      `\~\~struck\~\~`},
+		{`back\slash`,      `back\\slash`},
+		{"[[not a note]]",  `\[[not a note\]]`},
+		{"[in brackets]",   "[in brackets]"},
+		{"$variable",       `\$variable`},
+		{"$_variable",      `\$\_variable`},
+		{"costs $5",        "costs $5"},
+		{"#page",           `\#page`},
+		{"number #1",       "number #1"},
+		{"# not a section", "# not a section"},
+		{"café",            "café"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			output := escape_markup(test.input)
+			if output != test.output {
+				t.Errorf("escape_markup(%q) = %q, want %q", test.input, output, test.output)
+			}
+		})
+	}
+}
+
+func TestSpansMarkup(t *testing.T) {
+	tests := []struct {
+		name   string
+		spans  []Span
+		output string
+	}{
+		{
+			name:   "plain",
+			spans:  []Span{{Text: "just text"}},
+			output: "just text",
+		},
+		{
+			name:   "spaces stay outside",
+			spans:  []Span{{Text: "a "}, {Text: " bold ", Style: []string{"bold"}}, {Text: " b"}},
+			output: "a  **bold**  b",
+		},
+		{
+			name:   "nested",
+			spans:  []Span{{Text: "bold ", Style: []string{"bold"}}, {Text: "both", Style: []string{"bold", "italic"}}, {Text: " bold", Style: []string{"bold"}}},
+			output: "**bold *both* bold**",
+		},
+		{
+			name:   "outer style ends first",
+			spans:  []Span{{Text: "both", Style: []string{"italic", "bold"}}, {Text: " italic", Style: []string{"italic"}}},
+			output: "***both*** *italic*",
+		},
+		{
+			name:   "every style",
+			spans:  []Span{{Text: "x", Style: []string{"italic", "bold", "underline", "strikeout", "highlight", "note"}}},
+			output: "[[+~~_***x***_~~+]]",
+		},
+		{
+			name:   "markup in styled text",
+			spans:  []Span{{Text: "a*b", Style: []string{"bold"}}},
+			output: `**a\*b**`,
+		},
+		{
+			name:   "over a line break",
+			spans:  []Span{{Text: "one\ntwo", Style: []string{"underline"}}, {Text: "\nthree"}},
+			output: "_one\ntwo_\nthree",
+		},
+		{
+			name:   "unknown styles are dropped",
+			spans:  []Span{{Text: "odd", Style: []string{"blink"}}},
+			output: "odd",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output := spans_markup(test.spans)
+			if output != test.output {
+				t.Errorf("spans_markup() = %q, want %q", output, test.output)
+			}
+		})
+	}
+}
+
+// describe_content writes out the parts of a document that
+// Write has to keep, one section to a line, leaving out
+// the whitespace and the positions that it doesn't
+func describe_content(data *Document) string {
+	buffer := new(strings.Builder)
+
+	for _, section := range data.Content {
+		if section.Type == WHITESPACE {
+			continue
+		}
+
+		buffer.WriteString(section.Type.String())
+		buffer.WriteString(" ")
+		buffer.WriteString(strings.ReplaceAll(section.Text, "\n", `\n`))
+
+		if section.SceneNumber != "" {
+			buffer.WriteString(" #" + section.SceneNumber)
+		}
+		if section.Revision != "" {
+			buffer.WriteString(" @" + section.Revision)
+		}
+		if section.Type == DUAL_CHARACTER {
+			buffer.WriteString(" ^" + string(rune('0' + section.Level)))
+		}
+		buffer.WriteRune('\n')
+	}
+
+	for _, c := range data.Characters {
+		buffer.WriteString(c.Gender + ": " + c.Name)
+		for _, name := range c.OtherNames {
+			buffer.WriteString(" | " + name)
+		}
+		buffer.WriteRune('\n')
+	}
+
+	return buffer.String()
+}
+
+// parse_string parses text with no path, so there's
+// no file name to fall back on for the title
+func parse_string(t *testing.T, text string) *Document {
+	t.Helper()
+
+	data, err := Parse(strings.NewReader(text), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"scenes",                "INT. HOUSE - DAY #1#\n\nAction.\n\n.FLASHBACK\n\nMore action."},
+		{"speech",                "BOB\n(quietly)\nHello.\n\nALICE @rev1\nHi.\n~A lyric."},
+		{"dual dialogue",         "BOB\nOne.\n\nALICE ^\nTwo."},
+		{"forced elements",       "@McCLANE\nYippee.\n\n> BURN TO: PINK\n\n> THE END <\n\n!SHOUTING IN ACTION\nstill action."},
+		{"sections and synopses", "# Act One\n\n= The setup.\n\n## Part One\n\nAction.\n\n===\n\nNew page."},
+		{"lookalike action",      "!INT. is not a heading\n\n!CUT TO:\n\n!.not a scene either"},
+		{"include directive",     "Before.\n\n!include: secret.fountain\n\nAfter."},
+		{"markup",                "Some **bold** and *italic* and _underline_ text.\n\nA \\*literal\\* star."},
+		{"gender tables",         "BOB\nHi.\n\nALICE\nHello.\n\n/*\n\t[gender.female]\n\tAlice | Ally\n\t[gender.male]\n\tBob\n*/"},
+		{"title page",            "Title: Round Trip\nAuthor: Someone\n\nINT. HOUSE - DAY\n\nAction."},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			first  := parse_string(t, test.input)
+			output := Write(first)
+			second := parse_string(t, output)
+
+			if a, b := describe_content(first), describe_content(second); a != b {
+				t.Errorf("written as\n%s\nwhich reads back as\n%s\nnot\n%s", output, b, a)
+			}
+			if first.Title != second.Title {
+				t.Errorf("title page is %+v, want %+v", second.Title, first.Title)
+			}
+			if len(second.Diagnostics) > 0 {
+				t.Errorf("written as\n%s\nwhich reads back with %v", output, diagnostic_codes(second.Diagnostics))
+			}
+		})
+	}
+}
+
+func TestWriteEscapesInclude(t *testing.T) {
+	data := &Document{
+		Content: []Section{
+			{Type: ACTION, Text: "Before."},
+			{Type: ACTION, Text: "include: secret.fountain"},
+			{Type: ACTION, Text: "After."},
+		},
+	}
+
+	output := Write(data)
+	if !strings.Contains(output, "\n!include: secret.fountain\n") {
+		t.Fatalf("include directive wasn't escaped in\n%s", output)
+	}
+
+	merged, doc, err := Merge(filepath.Join(write_files(t, map[string]string{"main.fountain": output}), "main.fountain"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Diagnostics) > 0 || !strings.Contains(merged, "!include: secret.fountain") {
+		t.Errorf("merge read the action as an include: %q, %v", merged, diagnostic_codes(doc.Diagnostics))
+	}
+}
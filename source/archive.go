@@ -0,0 +1,191 @@
+/*
+	Meander
+	A portable Fountain utility for production writing
+	Copyright (C) 2022-2023 Harley Denham
+*/
+
+package main
+
+import "math"
+import "strings"
+
+const TXT_EXT = ".txt"
+
+// command_archive lays the script out exactly as render
+// does, then prints each page onto a grid of monospaced
+// characters instead of a PDF.  the result has the same
+// line breaks, indents and pages as the printed script,
+// which makes it a plain-text record that diffs cleanly.
+func command_archive(config *Config) {
+	data, success := parse_file(config)
+	if !success {
+		return
+	}
+
+	vet_template(data.template)
+	paginate(config, data)
+
+	if !select_starred(config, data) {
+		return
+	}
+
+	pages := make([]*Text_Page, 0, 64)
+
+	if data.Title.HasAny && !data.config.starred_only {
+		page := new(Text_Page)
+		for _, section := range title_sections(data) {
+			page.draw_section(data, section)
+		}
+		pages = append(pages, page)
+	}
+
+	var page *Text_Page
+	page_number := 0
+
+	for i := range data.Content {
+		section := &data.Content[i]
+
+		if section.skip {
+			continue
+		}
+
+		if section.page > page_number {
+			page_number = section.page
+			page = new(Text_Page)
+			pages = append(pages, page)
+		}
+
+		if section.Type == SCENE && config.scenes != SCENE_REMOVE {
+			text_width := float64(rune_count(section.SceneNumber)) * CHAR_WIDTH
+			right_x    := data.template.margin_right - text_width
+			left_x     := data.template.margin_left - INCH / 2 - text_width
+
+			page.write(left_x,  section.pos_y, section.SceneNumber)
+			page.write(right_x, section.pos_y, section.SceneNumber)
+		}
+
+		page.draw_section(data, section)
+	}
+
+	buffer := strings.Builder{}
+
+	for i, page := range pages {
+		if i > 0 {
+			buffer.WriteString("\f\n")
+		}
+		page.write_to(&buffer)
+	}
+
+	success = write_file(fix_path(config.output_file), []byte(buffer.String()))
+	if !success {
+		eprintln("failed to write", config.output_file)
+	}
+}
+
+// a page of text, one row for every line of the
+// default line height, measured from the top edge
+type Text_Page struct {
+	rows [][]rune
+}
+
+// write places text on the page at a position in points,
+// snapped to the nearest character and row
+func (page *Text_Page) write(pos_x, pos_y float64, text string) {
+	column := int(math.Round(pos_x / CHAR_WIDTH))
+	row    := int(math.Round(pos_y / LINE_HEIGHT))
+
+	if column < 0 {
+		column = 0
+	}
+	if row < 0 {
+		row = 0
+	}
+
+	for len(page.rows) <= row {
+		page.rows = append(page.rows, nil)
+	}
+
+	line := page.rows[row]
+
+	for len(line) < column + rune_count(text) {
+		line = append(line, ' ')
+	}
+	for _, c := range text {
+		line[column] = c
+		column += 1
+	}
+
+	page.rows[row] = line
+}
+
+// draw_section mirrors its PDF counterpart, minus the styling
+func (page *Text_Page) draw_section(data *Fountain, section *Section) {
+	if section == nil {
+		return
+	}
+	if section.Text == "" {
+		return
+	}
+
+	if section.is_raw {
+		pos_x := section.pos_x
+
+		switch section.justify {
+		case CENTER:
+			pos_x -= CHAR_WIDTH * float64(section.longest_line / 2)
+		case RIGHT:
+			pos_x -= CHAR_WIDTH * float64(section.longest_line)
+		}
+
+		page.write(pos_x, section.pos_y, section.Text)
+		page.draw_star(data, section, section.pos_y)
+		return
+	}
+
+	pos_x := section.pos_x + section.para_indent
+	pos_y := section.pos_y
+
+	for i, line := range section.lines {
+		if len(line.leaves) == 0 {
+			pos_y += section.line_height
+			continue
+		}
+
+		if i > 0 {
+			pos_x = section.pos_x
+		}
+
+		switch section.justify {
+		case CENTER:
+			pos_x -= CHAR_WIDTH * float64(line.length / 2)
+		case RIGHT:
+			pos_x -= CHAR_WIDTH * float64(line.length)
+		}
+
+		text := strings.Builder{}
+		for _, leaf := range line.leaves {
+			text.WriteString(leaf.text)
+		}
+
+		page.write(pos_x, pos_y, text.String())
+		page.draw_star(data, section, pos_y)
+		pos_y += section.line_height
+	}
+}
+
+func (page *Text_Page) draw_star(data *Fountain, section *Section, pos_y float64) {
+	if !data.config.starred_show {
+		return
+	}
+
+	if len(section.Revision) > 0 && strings.Contains(data.config.starred_target, section.Revision) {
+		page.write(data.template.starred_margin, pos_y + data.template.starred_nudge, "*")
+	}
+}
+
+func (page *Text_Page) write_to(buffer *strings.Builder) {
+	for _, line := range page.rows {
+		buffer.WriteString(strings.TrimRight(string(line), " "))
+		buffer.WriteRune('\n')
+	}
+}
@@ -0,0 +1,159 @@
+/*
+	Meander
+	A portable Fountain utility for production writing
+	Copyright (C) 2022-2023 Harley Denham
+*/
+
+package main
+
+import "fmt"
+import "strings"
+
+func command_breakdown(config *Config) {
+	if !valid_report_format(config) {
+		return
+	}
+
+	data, success := parse_file(config)
+	if !success {
+		return
+	}
+
+	scenes := collect_scenes(config, data)
+
+	if len(scenes) == 0 {
+		eprintf("breakdown: %q has no scenes", config.source_file)
+		return
+	}
+
+	if wants_csv(config) {
+		rows := make([][]string, 0, len(scenes) + 1)
+		rows = append(rows, []string{
+			"Scene", "I/E", "Location", "Time", "Page", "Eighths",
+			"Speaking", "Non-Speaking", "Synopsis",
+		})
+
+		for _, scene := range scenes {
+			rows = append(rows, []string{
+				scene.number,
+				scene.setting,
+				scene.location,
+				scene.time,
+				fmt.Sprintf("%d", scene.page),
+				fmt.Sprintf("%d", scene.eighths),
+				strings.Join(scene.speaking, ", "),
+				strings.Join(scene.mentioned, ", "),
+				scene.synopsis,
+			})
+		}
+
+		if !write_csv(config.output_file, rows) {
+			eprintln("failed to write", config.output_file)
+		}
+		return
+	}
+
+	render_breakdown(config, data, scenes)
+}
+
+type Breakdown_Detail struct {
+	label   string
+	section *Section
+}
+
+// render_breakdown lays out one block per scene: a row with
+// the heading split into columns, then the cast, the other
+// characters and the synopsis beneath it
+func render_breakdown(config *Config, data *Fountain, scenes []*Scene) {
+	r := new_report(config, data, BREAKDOWN_HEADING, false)
+
+	// column widths, in characters
+	number_width := rune_count(BREAKDOWN_SCENE)
+	time_width   := rune_count(BREAKDOWN_TIME)
+
+	for _, scene := range scenes {
+		if n := rune_count(scene.number); n > number_width {
+			number_width = n
+		}
+		if n := rune_count(scene.time); n > time_width {
+			time_width = n
+		}
+	}
+
+	col_setting  := r.left + float64(number_width + 2) * CHAR_WIDTH
+	col_location := col_setting + 9 * CHAR_WIDTH
+	col_length   := r.right - 6 * CHAR_WIDTH
+	col_page     := col_length - 6 * CHAR_WIDTH
+	col_time     := col_page - float64(time_width + 2) * CHAR_WIDTH
+	col_label    := col_setting
+	col_detail   := col_setting + float64(rune_count(BREAKDOWN_MENTIONED) + 2) * CHAR_WIDTH
+
+	location_width := col_time - col_location - CHAR_WIDTH * 2
+	detail_width   := r.right - col_detail
+
+	header := func() {
+		report_text(r, r.left,       BREAKDOWN_SCENE,    BOLD)
+		report_text(r, col_setting,  BREAKDOWN_SETTING,  BOLD)
+		report_text(r, col_location, BREAKDOWN_LOCATION, BOLD)
+		report_text(r, col_time,     BREAKDOWN_TIME,     BOLD)
+		report_text(r, col_page,     BREAKDOWN_PAGE,     BOLD)
+		report_text(r, col_length,   BREAKDOWN_LENGTH,   BOLD)
+		report_rule(r)
+		r.y += LINE_HEIGHT * 2
+	}
+
+	header()
+
+	for _, scene := range scenes {
+		location := report_wrap(r, scene.location, col_location, location_width)
+
+		details := make([]Breakdown_Detail, 0, 3)
+
+		for _, d := range [...]struct{
+			label string
+			text  string
+		}{
+			{BREAKDOWN_CAST,      strings.Join(scene.speaking, ", ")},
+			{BREAKDOWN_MENTIONED, strings.Join(scene.mentioned, ", ")},
+			{BREAKDOWN_SYNOPSIS,  scene.synopsis},
+		} {
+			if d.text == "" {
+				continue
+			}
+			details = append(details, Breakdown_Detail{d.label, report_wrap(r, d.text, col_detail, detail_width)})
+		}
+
+		height := float64(report_lines(location) + 1) * LINE_HEIGHT
+		for _, d := range details {
+			height += float64(report_lines(d.section)) * LINE_HEIGHT
+		}
+
+		if !report_fits(r, height) {
+			header()
+		}
+
+		report_text(r, r.left,      scene.number,                 BOLD)
+		report_text(r, col_setting, scene.setting,                NORMAL)
+		report_text(r, col_time,    scene.time,                   NORMAL)
+		report_text(r, col_page,    fmt.Sprintf("%d", scene.page), NORMAL)
+		report_text(r, col_length,  format_eighths(scene.eighths), NORMAL)
+
+		location.pos_y = r.y
+		draw_section(r.doc, data, location)
+
+		r.y += float64(report_lines(location)) * LINE_HEIGHT
+
+		for _, d := range details {
+			report_text(r, col_label, d.label, ITALIC)
+
+			d.section.pos_y = r.y
+			draw_section(r.doc, data, d.section)
+
+			r.y += float64(report_lines(d.section)) * LINE_HEIGHT
+		}
+
+		r.y += LINE_HEIGHT
+	}
+
+	save_report(r, config.output_file)
+}
@@ -0,0 +1,238 @@
+/*
+	Meander
+	A portable Fountain utility for production writing
+	Copyright (C) 2022-2023 Harley Denham
+*/
+
+package main
+
+import "fmt"
+import "sort"
+import "strings"
+import "encoding/json"
+
+import "github.com/lichendust/meander/fountain"
+
+// codes for problems only the renderer can see;
+// the rest live in the fountain package
+const (
+	CODE_TEMPLATE_ERROR      = "template-error"
+	CODE_UNBALANCED_EMPHASIS = "unbalanced-emphasis"
+)
+
+// command_check parses and lays out the script without
+// rendering it, then reports everything that went wrong.
+// it returns false if any of the problems were errors.
+func command_check(config *Config) bool {
+	data, success := parse_file(config)
+	if !success {
+		return false
+	}
+
+	lint_sections(data)
+
+	sort.SliceStable(data.diagnostics, func(i, j int) bool {
+		a := data.diagnostics[i].Position
+		b := data.diagnostics[j].Position
+
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+
+	errors   := 0
+	warnings := 0
+
+	for _, d := range data.diagnostics {
+		if d.Severity == fountain.ERROR {
+			errors += 1
+		} else {
+			warnings += 1
+		}
+	}
+
+	if config.json_output {
+		return print_check_json(data, errors, warnings) && errors == 0
+	}
+
+	for _, d := range data.diagnostics {
+		print_diagnostic(data.document, d)
+	}
+
+	if errors + warnings == 0 {
+		println("no problems found in", config.source_file)
+	} else {
+		eprintf("%s, %s", plural(errors, "error"), plural(warnings, "warning"))
+	}
+
+	return errors == 0
+}
+
+// lint_sections runs every printable section through the
+// line breaker, which is where unbalanced emphasis gets
+// quietly turned back into plain text.  we work on copies
+// so the data is left as it came out of the parser.
+func lint_sections(data *Fountain) {
+	data.lint = true
+	defer func() { data.lint = false }()
+
+	data.counter_lookup["page"]  = &Counter{value: 1}
+	data.counter_lookup["scene"] = &Counter{value: 0}
+
+	for _, section := range data.Content {
+		if section.Type < is_printable {
+			continue
+		}
+
+		if section.Type == SECTION {
+			section.Type += Section_Type(section.Level - 1)
+		}
+
+		t := data.template.types[section.Type]
+		if t.skip {
+			continue
+		}
+
+		section.line_height = t.line_height
+		break_section(data, &section, t.width, t.para_indent, t.style != NORMAL)
+	}
+}
+
+// lint_emphasis records a style marker the line
+// breaker gave up on
+func lint_emphasis(data *Fountain, section *Section, entry *Inline_Format, was Leaf_Type) {
+	if !data.lint {
+		return
+	}
+
+	pos := text_position(section.Position, section.Text, entry.offset)
+	diagnose(data, fountain.WARNING, CODE_UNBALANCED_EMPHASIS, pos, "unbalanced %s marker %q is printed as plain text", leaf_name(was), entry.text)
+}
+
+// text_position moves a section's position along to the
+// given byte offset in its text, which may run over more
+// than one line
+func text_position(pos fountain.Position, text string, offset int) fountain.Position {
+	if pos.Line == 0 {
+		return pos
+	}
+
+	before := text[:offset]
+
+	if i := strings.LastIndexByte(before, '\n'); i >= 0 {
+		pos.Line  += strings.Count(before, "\n")
+		pos.Column = rune_count(before[i + 1:]) + 1
+		return pos
+	}
+
+	// the column is already past any indent the
+	// text has kept
+	if pos.Column > 0 {
+		pos.Column += rune_count(strings.TrimLeft(before, " \t"))
+	}
+	return pos
+}
+
+func leaf_name(x Leaf_Type) string {
+	switch x {
+	case ITALIC:
+		return "italic"
+	case BOLD:
+		return "bold"
+	case BOLD | ITALIC:
+		return "bold italic"
+	case UNDERLINE:
+		return "underline"
+	case STRIKEOUT:
+		return "strikeout"
+	case HIGHLIGHT:
+		return "highlight"
+	case NOTE:
+		return "note"
+	}
+	return "style"
+}
+
+func diagnose(data *Fountain, severity fountain.Severity, code string, pos fountain.Position, format string, guff ...any) {
+	data.diagnostics = append(data.diagnostics, fountain.Diagnostic{
+		Severity: severity,
+		Code:     code,
+		Message:  fmt.Sprintf(format, guff...),
+		Position: pos,
+	})
+}
+
+// file:line:column: severity: message [code]
+// followed by the chain of includes, if any
+func print_diagnostic(doc *fountain.Document, d fountain.Diagnostic) {
+	stack := doc.IncludeStack(d.Position)
+
+	eprintf("%s: %s: %s [%s]", doc.Location(d.Position), d.Severity, d.Message, d.Code)
+
+	for _, pos := range stack[1:] {
+		eprintf("    included from %s", doc.Location(pos))
+	}
+}
+
+type Check_Entry struct {
+	Severity fountain.Severity `json:"severity"`
+	Code     string            `json:"code"`
+	Message  string            `json:"message"`
+	File     string            `json:"file"`
+	Line     int               `json:"line,omitempty"`
+	Column   int               `json:"column,omitempty"`
+
+	IncludedFrom []string `json:"included_from,omitempty"`
+}
+
+type Check_Report struct {
+	Errors      int           `json:"errors"`
+	Warnings    int           `json:"warnings"`
+	Diagnostics []Check_Entry `json:"diagnostics"`
+}
+
+func print_check_json(data *Fountain, errors, warnings int) bool {
+	report := Check_Report{
+		Errors:      errors,
+		Warnings:    warnings,
+		Diagnostics: make([]Check_Entry, 0, len(data.diagnostics)),
+	}
+
+	for _, d := range data.diagnostics {
+		entry := Check_Entry{
+			Severity: d.Severity,
+			Code:     d.Code,
+			Message:  d.Message,
+			File:     data.document.Files[d.File].Path,
+			Line:     d.Line,
+			Column:   d.Column,
+		}
+
+		stack := data.document.IncludeStack(d.Position)
+		for _, pos := range stack[1:] {
+			entry.IncludedFrom = append(entry.IncludedFrom, data.document.Location(pos))
+		}
+
+		report.Diagnostics = append(report.Diagnostics, entry)
+	}
+
+	blob, err := json.MarshalIndent(report, "", "\t")
+	if err != nil {
+		eprintln("failed to marshal check report")
+		return false
+	}
+
+	println(string(blob))
+	return true
+}
+
+func plural(n int, word string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, word)
+	}
+	return fmt.Sprintf("%d %ss", n, word)
+}
@@ -6,118 +6,241 @@
 
 package main
 
-import "strings"
+import "os"
+import "math"
 import "encoding/json"
 
+import "github.com/lichendust/meander/fountain"
+
 func command_merge(config *Config) {
-	merged_file, success := merge(config.source_file)
-	if !success {
+	merged_file, doc, err := fountain.Merge(config.source_file)
+	if err != nil {
+		eprintln(err.Error())
 		eprintln("failed to merge file", config.source_file)
 		return
 	}
 
-	success = write_file(fix_path(config.output_file), []byte(merged_file))
+	for _, d := range doc.Diagnostics {
+		print_diagnostic(doc, d)
+	}
+
+	success := write_file(fix_path(config.output_file), []byte(merged_file))
 	if !success {
 		eprintln("failed to write", config.output_file)
 	}
 }
 
-/*func command_archive(config *Config) {
-	text, success := merge(config.source_file)
+func command_data(config *Config) {
+	if config.data_schema {
+		print(DATA_SCHEMA)
+		return
+	}
+
+	data, success := parse_file(config)
 	if !success {
-		eprintln("failed to merge file", config.source_file)
 		return
 	}
 
-	const NL rune = '\n'
+	output := Data_Output{
+		Meta:       data.Meta,
+		Title:      data.Title,
+		Files:      data.Files,
+		Characters: data.Characters,
+	}
 
-	content := new(strings.Builder)
-	content.Grow(len(text))
+	output.Meta.Version = DATA_VERSION
 
-	last_relevant_rune := 'x' // this initial value is never relevant
-	for {
-		c, w := get_rune(content)
-		content = content[w:]
+	// the runtime estimate needs the scenes read
+	// before layout and measured after it, so it's
+	// worked out alongside the content either way
+	var scenes []*Scene
 
-		if c == '#' {
-			if last_relevant_rune != '\n' {
+	if config.data_paginate {
+		vet_template(data.template)
+		scenes = read_scenes(data)
+		paginate(config, data)
 
-			}
-		}
+		output.Meta.Paginated = true
+		output.Content = paginated_data(data)
+	} else {
+		prepare_export(data)
+		scenes = read_scenes(data)
+		output.Content = plain_data(data)
+
+		vet_template(data.template)
+		paginate(config, data)
+	}
+
+	measure_scenes(data, scenes)
+	output.Runtime = runtime_data(data, scenes)
 
-		buffer.WriteRune(c)
-		last_rune = c
+	blob, err := json.MarshalIndent(output, "", "\t")
+	if err != nil {
+		eprintln("failed to marshal", config.output_file)
+		return
 	}
 
-	success = write_file(fix_path(config.output_file), []byte(content.String()))
+	success = write_file(config.output_file, blob)
 	if !success {
 		eprintln("failed to write", config.output_file)
 	}
-}*/
+}
 
-func merge(source_file string) (string, bool) {
-	text, success := load_file_normalise(fix_path(source_file))
-	if !success {
-		eprintf("%q not found", source_file)
-		return "", false
+// Data_Output mirrors Fountain's exported fields, with
+// content that has the markup pulled out into spans
+// and, when paginated, where each piece sits on the page
+type Data_Output struct {
+	Meta       fountain.Meta          `json:"meta"`
+	Title      Title_Page             `json:"title"`
+	Files      []Source_File          `json:"files,omitempty"`
+	Characters []Character            `json:"characters,omitempty"`
+	Content    []Data_Section         `json:"content,omitempty"`
+	Runtime    *Data_Runtime          `json:"runtime,omitempty"`
+}
+
+type Data_Section struct {
+	fountain.Section
+
+	Page    int     `json:"page,omitempty"`
+	Eighths float64 `json:"eighths,omitempty"`
+	Y       float64 `json:"y,omitempty"`
+	Lines   int     `json:"lines,omitempty"`
+}
+
+func plain_data(data *Fountain) []Data_Section {
+	output := make([]Data_Section, 0, len(data.Content))
+
+	for i := range data.Content {
+		section := &data.Content[i]
+
+		entry := Data_Section{Section: section.Section}
+
+		if section.Type == SCENE {
+			entry.SceneNumber = export_scene_number(data, section)
+		}
+
+		if section.Text != "" {
+			entry.Text, entry.Spans = data_spans(section_spans(data, section))
+		}
+
+		output = append(output, entry)
 	}
 
-	content := new(strings.Builder)
-	content.Grow(len(text))
+	return output
+}
+
+// paginated_data reports every piece of text as it was
+// laid out, so sections broken across a page turn up
+// once on each page, and the headers, footers and
+// (more)s added by paginate are included
+func paginated_data(data *Fountain) []Data_Section {
+	template := data.template
 
-	for {
-		if len(text) == 0 {
-			break
+	// the height of an eighth of the page body
+	eighth := (template.paper.H - template.margin_bottom - template.margin_top) / 8
+
+	output := make([]Data_Section, 0, len(data.Content))
+
+	for i := range data.Content {
+		section := &data.Content[i]
+
+		// do_header leaves empty slots for
+		// the missing parts of a header
+		if section.Text == "" {
+			continue
 		}
 
-		if text[0] == '\n' && len(text) > 9 && text[1] == 'i' {
-			content.WriteRune('\n')
-			text = text[1:] // newline
+		entry := Data_Section{
+			Section: section.Section,
+			Page:    section.page,
+			Y:       math.Round(section.pos_y * 100) / 100,
+			Lines:   len(section.lines),
+		}
 
-			if rune_on_line(text, ':') != 8 || homogenise(text[:7]) != "include" {
-				continue
+		if section.lines == nil {
+			entry.Lines = 1
+			entry.Text, entry.Spans = data_spans(section_spans(data, section))
+		} else {
+			lines := make([][]Span, 0, len(section.lines))
+			for i := range section.lines {
+				lines = append(lines, line_spans(&section.lines[i]))
 			}
+			entry.Text, entry.Spans = data_spans(lines)
+		}
 
-			test_text := extract_to_newline(text)
-			file_name := include_path(source_file, strings.TrimSpace(test_text[8:]))
-
-			if child_text, found_file := merge(file_name); found_file {
-				content.Grow(len(child_text))
-				content.WriteString(consume_title_page(child_text))
-			} else {
-				content.WriteString(text[:len(test_text)])
+		if section.Type > is_printable {
+			// the (more) and cont'd that paginate
+			// adds don't carry their own line height
+			line_height := section.line_height
+			if line_height == 0 {
+				line_height = template.line_height
 			}
 
-			text = text[len(test_text):]
-			continue
+			height := line_height * float64(entry.Lines)
+			entry.Eighths = math.Round(height / eighth * 100) / 100
 		}
 
-		the_rune, rune_width := get_rune(text)
-		text = text[rune_width:]
-		content.WriteRune(the_rune)
+		output = append(output, entry)
 	}
 
-	return content.String(), true
+	return output
 }
 
-const DATA_VERSION = 1
+// data_spans joins lines of spans into plain text and
+// the list of spans for the data command.  text without
+// any styling doesn't get spans at all.
+func data_spans(lines [][]Span) (string, []fountain.Span) {
+	joined := make([]Span, 0, 8)
 
-func command_data(config *Config) {
-	text, success := merge(config.source_file)
-	if !success {
-		return
+	for i, line := range lines {
+		if i > 0 {
+			joined = append_span(joined, Span{"\n", NORMAL})
+		}
+		for _, span := range line {
+			joined = append_span(joined, span)
+		}
+	}
+
+	text   := spans_text(joined)
+	styled := false
+
+	output := make([]fountain.Span, 0, len(joined))
+
+	for _, span := range joined {
+		names := span_style_names(span.style)
+		if len(names) > 0 {
+			styled = true
+		}
+		output = append(output, fountain.Span{
+			Text:  span.text,
+			Style: names,
+		})
 	}
 
-	data := init_data(config)
-	syntax_parser(config, data, text)
+	if !styled {
+		return text, nil
+	}
 
-	blob, err := json.MarshalIndent(data, "", "\t")
+	return text, output
+}
+
+// convert_data writes the JSON from command_data
+// back out as Fountain
+func convert_data(config *Config) {
+	file, err := os.Open(fix_path(config.source_file))
 	if err != nil {
-		eprintln("failed to marshal", config.output_file)
+		eprintf("%q not found", config.source_file)
 		return
 	}
+	defer file.Close()
 
-	success = write_file(config.output_file, blob)
+	doc, err := fountain.ReadJSON(file)
+	if err != nil {
+		eprintf("failed to load %q: %v", config.source_file, err)
+		return
+	}
+
+	success := write_file(fix_path(config.output_file), []byte(fountain.Write(doc)))
 	if !success {
 		eprintln("failed to write", config.output_file)
 	}
@@ -0,0 +1,151 @@
+/*
+	Meander
+	A portable Fountain utility for production writing
+	Copyright (C) 2022-2023 Harley Denham
+*/
+
+package main
+
+import "bytes"
+import "strings"
+import "testing"
+import "encoding/json"
+
+import "github.com/lichendust/meander/fountain"
+
+// describe_document writes out what the data command
+// should carry through, one section to a line, leaving
+// out whitespace and positions
+func describe_document(doc *fountain.Document) string {
+	buffer := new(strings.Builder)
+
+	for _, section := range doc.Content {
+		if section.Type == WHITESPACE {
+			continue
+		}
+		buffer.WriteString(section.Type.String() + " " + strings.ReplaceAll(section.Text, "\n", `\n`))
+
+		if section.SceneNumber != "" {
+			buffer.WriteString(" #" + section.SceneNumber)
+		}
+		if section.Revision != "" {
+			buffer.WriteString(" @" + section.Revision)
+		}
+		buffer.WriteRune('\n')
+	}
+
+	for _, c := range doc.Characters {
+		buffer.WriteString(c.Gender + ": " + strings.Join(append([]string{c.Name}, c.OtherNames...), " | ") + "\n")
+	}
+
+	return buffer.String()
+}
+
+func parse_text(t *testing.T, text string) *fountain.Document {
+	t.Helper()
+
+	doc, err := fountain.Parse(strings.NewReader(text), fountain.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+// data_json builds the same JSON as the data command
+// does without --paginate
+func data_json(t *testing.T, doc *fountain.Document) []byte {
+	t.Helper()
+
+	data := init_data(new(Config), doc)
+	prepare_export(data)
+
+	output := Data_Output{
+		Meta:       data.Meta,
+		Title:      data.Title,
+		Characters: data.Characters,
+		Content:    plain_data(data),
+	}
+	output.Meta.Version = DATA_VERSION
+
+	blob, err := json.Marshal(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return blob
+}
+
+func TestDataRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"scenes and action",  "INT. HOUSE - DAY #1#\n\nAction.\n\n.FLASHBACK #1A#\n\nMore action."},
+		{"speech",             "BOB\n(quietly)\nHello.\n\nALICE ^\nHi.\n~A lyric."},
+		{"revisions",          "INT. HOUSE - DAY @blue\n\nBOB @pink\nHello."},
+		{"styles",             "Some **bold**, *italic*, _underlined_, ~~struck~~ and +highlighted+ words."},
+		{"nested styles",      "**Bold with *italic* inside** and _*both* underlined_."},
+		{"escaped markup",     "A \\*literal\\* star, a snake\\_case name and a \\$dollar."},
+		{"lines in one block", "First line.\nSecond line.\n\n> THE END <"},
+		{"lookalike action",   "!INT. is not a heading\n\n!include: secret.fountain\n\n!CUT TO:"},
+		{"sections",           "# Act One\n\n= The setup.\n\n## Part One\n\nAction.\n\n===\n\nNew page."},
+		{"title page",         "Title: Round Trip\nAuthor: Someone\nDraft date: 1 May\n\nAction."},
+		{"gender tables",      "BOB\nHi.\n\nALICE\nHello.\n\n/*\n\t[gender.female]\n\tAlice | Ally\n\t[gender.male]\n\tBob\n*/"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			first := parse_text(t, test.input)
+			blob  := data_json(t, first)
+
+			back, err := fountain.ReadJSON(bytes.NewReader(blob))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			output := fountain.Write(back)
+			second := parse_text(t, output)
+
+			if a, b := describe_document(first), describe_document(second); a != b {
+				t.Errorf("data\n%s\nwritten as\n%s\nreads back as\n%s\nnot\n%s", blob, output, b, a)
+			}
+			if first.Title != second.Title {
+				t.Errorf("title page is %+v, want %+v", second.Title, first.Title)
+			}
+		})
+	}
+}
+
+func TestReadJSONVersions(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		text  string // of the first section, or "" for an error
+	}{
+		{"version 1 keeps markup",  `{"meta":{"version":1},"content":[{"type":"action","text":"**bold**"}]}`,                                          "**bold**"},
+		{"version 2 is plain",      `{"meta":{"version":2},"content":[{"type":"action","text":"**bold**"}]}`,                                          `\*\*bold\*\*`},
+		{"version 2 spans",         `{"meta":{"version":2},"content":[{"type":"action","text":"bold","spans":[{"text":"bold","style":["bold"]}]}]}`, "**bold**"},
+		{"too new",                 `{"meta":{"version":99},"content":[]}`,                                                                            ""},
+		{"no version",              `{"content":[]}`,                                                                                                   ""},
+		{"paginated",               `{"meta":{"version":2,"paginated":true},"content":[]}`,                                                            ""},
+		{"unknown type",            `{"meta":{"version":2},"content":[{"type":"interpretive dance","text":"x"}]}`,                                      ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			doc, err := fountain.ReadJSON(strings.NewReader(test.input))
+
+			if test.text == "" {
+				if err == nil {
+					t.Errorf("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if doc.Content[0].Text != test.text {
+				t.Errorf("text is %q, want %q", doc.Content[0].Text, test.text)
+			}
+		})
+	}
+}
@@ -8,8 +8,10 @@ package main
 
 import "os"
 import "fmt"
+import "sort"
 import "bytes"
 import "strings"
+import "unicode"
 import "encoding/xml"
 import "path/filepath"
 
@@ -20,13 +22,53 @@ func command_convert(config *Config) {
 	switch ext {
 	case FD_EXT:
 		convert_final_draft(config)
+	case OSF_EXT, FADEIN_EXT:
+		convert_osf(config)
+	case HIGHLAND_EXT:
+		convert_highland(config)
+	case SCRIVENER_EXT:
+		convert_scrivener(config)
+	case TXT_EXT:
+		convert_text(config)
+	case JSON_EXT:
+		convert_data(config)
 	case FOUNTAIN_EXT:
-		eprintf("convert: %q is already a Fountain file", config.source_file)
+		export_file(config)
 	default:
 		eprintf("convert: no handler for filetype %q", ext)
 	}
 }
 
+// export_file converts Fountain into whichever format
+// --output-format or the output file's extension asks for
+func export_file(config *Config) {
+	ext := config.output_format
+	if ext == "" {
+		ext = filepath.Ext(config.output_file)
+	}
+
+	switch ext {
+	case FD_EXT:
+		export_final_draft(config)
+	case OSF_EXT, FADEIN_EXT:
+		export_osf(config)
+	case HTML_EXT:
+		export_html(config)
+	case EPUB_EXT:
+		export_epub(config)
+	case DOCX_EXT:
+		export_docx(config)
+	case PDF_EXT:
+		command_render(config)
+	case TXT_EXT:
+		command_archive(config)
+	case FOUNTAIN_EXT:
+		eprintf("convert: %q is already a Fountain file", config.source_file)
+	default:
+		eprintf("convert: no exporter for filetype %q", ext)
+	}
+}
+
 // we discard _a lot_ of crufty data that's not useful to us in
 // Fountain, but try to safely apply stuff we can understand
 type Final_Draft struct {
@@ -34,19 +76,75 @@ type Final_Draft struct {
 	Content []*XML_Paragraph `xml:"Content>Paragraph"`
 	Title   []*XML_Paragraph `xml:"TitlePage>Content>Paragraph"`
 
+	DocumentType string         `xml:"DocumentType,attr,omitempty"`
+	Template     string         `xml:"Template,attr,omitempty"`
+	Version      string         `xml:"Version,attr,omitempty"`
+	Revisions    *XML_Revisions `xml:"Revisions"`
+
+	// only read on import; notes anchored to
+	// a range of the script's text, and the
+	// SmartType list of character names
+	// pointers, so that export leaves them out
+	Notes     *XML_Script_Notes `xml:"ScriptNotes,omitempty"`
+	SmartType *XML_Smart_Type   `xml:"SmartType,omitempty"`
+
 	// need to look at capturing the HeaderAndFooter attributes
 	// for more information, such as visibility and starting
 	// pages.
-	Header []*XML_Paragraph `xml:"HeaderAndFooter>Header>Paragraph"`
-	Footer []*XML_Paragraph `xml:"HeaderAndFooter>Footer>Paragraph"`
+	HeaderAndFooter *XML_Header_Footer `xml:"HeaderAndFooter"`
 }
 
 type XML_Paragraph struct {
-	XMLName   xml.Name     `xml:"Paragraph"`
-	Type      string       `xml:"Type,attr"`
-	Number    string       `xml:"Number,attr"`
-	Alignment string       `xml:"Alignment,attr"`
-	Chunks    []*XML_Chunk `xml:"Text"`
+	XMLName       xml.Name           `xml:"Paragraph"`
+	Type          string             `xml:"Type,attr,omitempty"`
+	Number        string             `xml:"Number,attr,omitempty"`
+	Alignment     string             `xml:"Alignment,attr,omitempty"`
+	StartsNewPage string             `xml:"StartsNewPage,attr,omitempty"`
+	Dual          *XML_Dual_Dialogue `xml:"DualDialogue"`
+	Notes         []*XML_Script_Note `xml:"ScriptNote"`
+	Chunks        []*XML_Chunk       `xml:"Text"`
+}
+
+// notes are either written inside the paragraph they
+// belong to, or in a list at the end of the document
+// with a "start,end" range of characters
+type XML_Script_Notes struct {
+	Notes []*XML_Script_Note `xml:"ScriptNote"`
+}
+
+type XML_Smart_Type struct {
+	Characters []string `xml:"Characters>Character"`
+}
+
+type XML_Script_Note struct {
+	Range   string           `xml:"Range,attr,omitempty"`
+	Content []*XML_Paragraph `xml:"Paragraph"`
+}
+
+type XML_Header_Footer struct {
+	Header []*XML_Paragraph `xml:"Header>Paragraph"`
+	Footer []*XML_Paragraph `xml:"Footer>Paragraph"`
+}
+
+// a paragraph with no text of its own that holds
+// both sides of a dual dialogue block
+type XML_Dual_Dialogue struct {
+	Content []*XML_Paragraph `xml:"Paragraph"`
+}
+
+type XML_Revisions struct {
+	ActiveSet string          `xml:"ActiveSet,attr,omitempty"`
+	Revisions []*XML_Revision `xml:"Revision"`
+}
+
+type XML_Revision struct {
+	ID           int    `xml:"ID,attr"`
+	Name         string `xml:"Name,attr"`
+	Mark         string `xml:"Mark,attr"`
+	Color        string `xml:"Color,attr"`
+	PageColor    string `xml:"PageColor,attr"`
+	FullRevision string `xml:"FullRevision,attr"`
+	Style        string `xml:"Style,attr"`
 }
 
 // the entire screenplay is actually stored in a mixed-type
@@ -67,10 +165,26 @@ type XML_Paragraph struct {
 // will only really reflect into interfaces and it's nasty
 
 type XML_Chunk struct {
-	XMLName xml.Name `xml:"Text"`
-	Style   string   `xml:"Style,attr"` // <Text>         attribute
-	Label   string   `xml:"Type,attr"`  // <DynamicLabel> attribute
-	Text    string   `xml:",chardata"`  // <Text>         content
+	XMLName    xml.Name `xml:"Text"`
+	Style      string   `xml:"Style,attr,omitempty"`      // <Text>         attribute
+	Label      string   `xml:"Type,attr,omitempty"`       // <DynamicLabel> attribute
+	Color      string   `xml:"Color,attr,omitempty"`      // <Text>         attribute
+	Background string   `xml:"Background,attr,omitempty"` // <Text>         attribute
+	RevisionID int      `xml:"RevisionID,attr,omitempty"` // <Text>         attribute
+	Text       string   `xml:",chardata"`                 // <Text>         content
+}
+
+// read_final_draft folds the <DynamicLabel> elements into
+// <Text> ones, as above, and unmarshals the result
+func read_final_draft(byte_stream []byte) (*Final_Draft, error) {
+	byte_stream = bytes.ReplaceAll(byte_stream, []byte("DynamicLabel"), []byte("Text"))
+
+	data := new(Final_Draft)
+
+	if err := xml.Unmarshal(byte_stream, data); err != nil {
+		return nil, err
+	}
+	return data, nil
 }
 
 func convert_final_draft(config *Config) {
@@ -80,21 +194,27 @@ func convert_final_draft(config *Config) {
 		return
 	}
 
-	byte_stream = bytes.ReplaceAll(byte_stream, []byte("DynamicLabel"), []byte("Text"))
-
-	data := new(Final_Draft)
-
-	err = xml.Unmarshal(byte_stream, data)
+	data, err := read_final_draft(byte_stream)
 	if err != nil {
 		eprintf("failed to load %q\n", config.source_file)
 		return
 	}
 
+	success := write_file(fix_path(config.output_file), []byte(final_draft_fountain(data)))
+	if !success {
+		eprintln("failed to write", config.output_file)
+	}
+}
+
+// final_draft_fountain writes a Final Draft document out
+// as Fountain.  other screenplay formats are imported by
+// reading them into a Final_Draft first.
+func final_draft_fountain(data *Final_Draft) string {
 	buffer := new(strings.Builder)
 	buffer.Grow(len(data.Content) * 128)
 
 	// title page
-	{
+	if len(data.Title) > 0 {
 		// because Final Draft title pages are manually placed
 		// we simply assign the "central" items to the "title"
 		// section, and all others to the "notes" section
@@ -136,22 +256,12 @@ func convert_final_draft(config *Config) {
 			// if centered, we assume "title"
 			if paragraph.Alignment == "Center" {
 				title_buffer.WriteString("\n\t")
-
-				for _, chunk := range paragraph.Chunks {
-					if chunk.Text != "" {
-						title_buffer.WriteString(strings.TrimSpace(chunk.Text))
-					}
-				}
+				title_buffer.WriteString(strings.TrimSpace(chunks_text(paragraph.Chunks)))
 
 			// ...otherwise assign to "info"
 			} else {
 				info_buffer.WriteString("\n\t")
-
-				for _, chunk := range paragraph.Chunks {
-					if chunk.Text != "" {
-						info_buffer.WriteString(strings.TrimSpace(chunk.Text))
-					}
-				}
+				info_buffer.WriteString(strings.TrimSpace(chunks_text(paragraph.Chunks)))
 			}
 		}
 
@@ -163,8 +273,8 @@ func convert_final_draft(config *Config) {
 	}
 
 	// header + footer
-	{
-		for _, paragraph := range data.Header {
+	if data.HeaderAndFooter != nil {
+		for _, paragraph := range data.HeaderAndFooter.Header {
 			has_text := false
 
 			for _, chunk := range paragraph.Chunks {
@@ -180,7 +290,7 @@ func convert_final_draft(config *Config) {
 			}
 		}
 
-		for _, paragraph := range data.Footer {
+		for _, paragraph := range data.HeaderAndFooter.Footer {
 			has_text := false
 
 			for _, chunk := range paragraph.Chunks {
@@ -199,71 +309,370 @@ func convert_final_draft(config *Config) {
 
 	// base content
 	{
-		for _, paragraph := range data.Content {
-			// same as before, ignore empty paragraphs
-			has_text := false
+		state := new_final_draft_import(data)
 
-			for _, chunk := range paragraph.Chunks {
-				if len(chunk.Label) != 0 || len(chunk.Text) != 0 {
-					has_text = true
-					break
+		for index, paragraph := range data.Content {
+			if paragraph.Dual != nil {
+				// the second speaker in the block is
+				// the one that gets the caret
+				speakers := 0
+				for i, inner := range paragraph.Dual.Content {
+					if inner.Type == "Character" {
+						speakers += 1
+					}
+					state.write_paragraph(buffer, paragraph.Dual.Content, i, speakers == 2)
 				}
+				continue
 			}
 
-			if !has_text {
+			state.write_paragraph(buffer, data.Content, index, false)
+		}
+
+		// the cast goes in a gender table so it's
+		// ready for someone to fill in
+		if len(state.cast_order) > 0 {
+			buffer.WriteString("\n\n/*\n\t[gender.unknown]\n")
+			for _, key := range state.cast_order {
+				buffer.WriteRune('\t')
+				buffer.WriteString(strings.Join(state.cast[key], " | "))
+				buffer.WriteRune('\n')
+			}
+			buffer.WriteString("*/")
+		}
+
+		if len(state.unknown) > 0 {
+			names := make([]string, 0, len(state.unknown))
+			for name := range state.unknown {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			eprintln("convert: these Final Draft elements aren't supported and were written as action:")
+			for _, name := range names {
+				eprintf("    %-20s %d", name, state.unknown[name])
+			}
+		}
+	}
+
+	buffer.WriteRune('\n')
+
+	// without a title page, the first
+	// paragraph's spacing is left dangling
+	return strings.TrimLeft(buffer.String(), "\n")
+}
+
+type Final_Draft_Import struct {
+	revisions map[int]string         // revision set ID to @tag
+	notes     map[*XML_Paragraph][]string
+
+	cast       map[string][]string // cast_key to spellings
+	cast_order []string
+	unknown    map[string]int      // element types we couldn't map
+
+	last_type string
+}
+
+func new_final_draft_import(data *Final_Draft) *Final_Draft_Import {
+	state := &Final_Draft_Import{
+		revisions: make(map[int]string, 8),
+		notes:     make(map[*XML_Paragraph][]string, 16),
+		cast:      make(map[string][]string, 32),
+		unknown:   make(map[string]int, 8),
+	}
+
+	// "Blue Rev." becomes @blue
+	if data.Revisions != nil {
+		for _, rev := range data.Revisions.Revisions {
+			name, _ := extract_ident(strings.ToLower(strings.TrimSpace(rev.Name)))
+			if name == "" {
+				name = fmt.Sprintf("rev%d", rev.ID)
+			}
+			state.revisions[rev.ID] = name
+		}
+	}
+
+	// notes in the document list are attached to the
+	// paragraph their range starts in, counting each
+	// paragraph break as a single character
+	if data.Notes != nil && len(data.Notes.Notes) > 0 {
+		offset := 0
+		starts := make([]int, 0, len(data.Content))
+		flat   := make([]*XML_Paragraph, 0, len(data.Content))
+
+		for _, paragraph := range data.Content {
+			inner := []*XML_Paragraph{paragraph}
+			if paragraph.Dual != nil {
+				inner = paragraph.Dual.Content
+			}
+			for _, p := range inner {
+				starts = append(starts, offset)
+				flat   = append(flat, p)
+				offset += len([]rune(chunks_text(p.Chunks))) + 1
+			}
+		}
+
+		for _, note := range data.Notes.Notes {
+			start := 0
+			fmt.Sscanf(note.Range, "%d", &start)
+
+			index := sort.SearchInts(starts, start + 1) - 1
+			if index < 0 || index >= len(flat) {
 				continue
 			}
 
-			// handle individual cases that need it differently
-			// this is **not exhaustive** and not fully tested
-			switch paragraph.Type {
-			case "Scene Heading":
-				buffer.WriteString("\n\n")
-				text := write_chunks(paragraph.Chunks, true)
+			if text := note_text(note); text != "" {
+				state.notes[flat[index]] = append(state.notes[flat[index]], text)
+			}
+		}
+	}
 
-				// @todo write a dedicated scene validator
-				n := strings.IndexRune(text, '.')
+	if data.SmartType != nil {
+		for _, name := range data.SmartType.Characters {
+			state.add_cast(name)
+		}
+	}
 
-				// force scenes if we know Fountain wouldn't identify them
-				if n < 0 || !lang_scene(strings.ToLower(clean_string(text[:n]))) {
-					buffer.WriteRune('.')
-				}
+	return state
+}
 
-				buffer.WriteString(text)
+// add_cast records a character name, grouping names that
+// only differ by punctuation, spacing or extensions
+func (state *Final_Draft_Import) add_cast(name string) {
+	if n := strings.IndexRune(name, '('); n > -1 {
+		name = name[:n]
+	}
+	name = title_case(strings.ToLower(strings.TrimSpace(name)))
 
-				// add the scene number if it's encoded
-				if paragraph.Number != "" {
-					buffer.WriteString(fmt.Sprintf(" #%s#", paragraph.Number))
-				}
+	key := cast_key(name)
+	if key == "" {
+		return
+	}
 
-			case "Character":
-				buffer.WriteString("\n\n")
-				text := write_chunks(paragraph.Chunks, true)
+	spellings, exists := state.cast[key]
+	if !exists {
+		state.cast_order = append(state.cast_order, key)
+	}
 
-				// force characters if we know Fountain wouldn't identify them
-				if !is_valid_character(text) {
-					buffer.WriteRune('@')
-				}
+	for _, s := range spellings {
+		if s == name {
+			return
+		}
+	}
 
-				buffer.WriteString(text)
+	state.cast[key] = append(spellings, name)
+}
 
-			case "Dialogue", "Parenthetical":
-				buffer.WriteRune('\n') // no space between char + dialogue
-				buffer.WriteString(write_chunks(paragraph.Chunks, false))
+// only letters and numbers count
+func cast_key(name string) string {
+	buffer := strings.Builder{}
+	for _, c := range strings.ToLower(name) {
+		if unicode.IsLetter(c) || unicode.IsNumber(c) {
+			buffer.WriteRune(c)
+		}
+	}
+	return buffer.String()
+}
 
-			default:
-				buffer.WriteString("\n\n")
-				buffer.WriteString(write_chunks(paragraph.Chunks, false))
+// write_paragraph writes list[index], looking ahead
+// through the rest of the list for the speech that
+// follows a character
+func (state *Final_Draft_Import) write_paragraph(buffer *strings.Builder, list []*XML_Paragraph, index int, is_dual bool) {
+	paragraph := list[index]
+
+	// same as before, ignore empty paragraphs
+	has_text := false
+
+	for _, chunk := range paragraph.Chunks {
+		if len(chunk.Label) != 0 || len(chunk.Text) != 0 {
+			has_text = true
+			break
+		}
+	}
+
+	if !has_text {
+		return
+	}
+
+	in_speech := state.last_type == "Character" || state.last_type == "Dialogue" || state.last_type == "Parenthetical" || state.last_type == "Lyrics"
+	state.last_type = paragraph.Type
+
+	// the tag after the text: notes, then the revision
+	suffix := new(strings.Builder)
+
+	for _, chunk := range paragraph.Notes {
+		if text := note_text(chunk); text != "" {
+			suffix.WriteString(" [[")
+			suffix.WriteString(text)
+			suffix.WriteString("]]")
+		}
+	}
+	for _, text := range state.notes[paragraph] {
+		suffix.WriteString(" [[")
+		suffix.WriteString(text)
+		suffix.WriteString("]]")
+	}
+
+	revision := 0
+
+	switch paragraph.Type {
+	case "Character":
+		// a revision anywhere in the speech goes on the
+		// character, because that's where Meander reads it
+		revision = chunk_revision(paragraph.Chunks)
+
+		for _, next := range list[index + 1:] {
+			if next.Type != "Dialogue" && next.Type != "Parenthetical" && next.Type != "Lyrics" {
+				break
 			}
+			if x := chunk_revision(next.Chunks); x > revision {
+				revision = x
+			}
+		}
+
+	case "Dialogue", "Parenthetical", "Lyrics":
+		if !in_speech {
+			revision = chunk_revision(paragraph.Chunks)
 		}
+
+	default:
+		revision = chunk_revision(paragraph.Chunks)
 	}
 
-	buffer.WriteRune('\n')
+	if revision > 0 {
+		if tag, exists := state.revisions[revision]; exists {
+			suffix.WriteString(" @")
+			suffix.WriteString(tag)
+		}
+	}
 
-	success := write_file(fix_path(config.output_file), []byte(buffer.String()))
-	if !success {
-		eprintln("failed to write", config.output_file)
+	// handle individual cases that need it differently
+	switch paragraph.Type {
+	case "Scene Heading":
+		buffer.WriteString("\n\n")
+		text := write_chunks(paragraph.Chunks, true)
+
+		// force scenes if we know Fountain wouldn't identify them
+		if !is_valid_scene(text) {
+			buffer.WriteRune('.')
+		}
+
+		buffer.WriteString(text)
+		buffer.WriteString(suffix.String())
+
+		// add the scene number if it's encoded
+		if paragraph.Number != "" {
+			buffer.WriteString(fmt.Sprintf(" #%s#", paragraph.Number))
+		}
+
+	case "Character":
+		buffer.WriteString("\n\n")
+		text := write_chunks(paragraph.Chunks, true)
+
+		state.add_cast(text)
+
+		// force characters if we know Fountain wouldn't identify them
+		if !is_valid_character(text) {
+			buffer.WriteRune('@')
+		}
+
+		buffer.WriteString(text)
+		if is_dual {
+			buffer.WriteString(" ^")
+		}
+		buffer.WriteString(suffix.String())
+
+	case "Dialogue", "Parenthetical":
+		if in_speech {
+			buffer.WriteRune('\n') // no space between char + dialogue
+		} else {
+			buffer.WriteString("\n\n")
+		}
+		buffer.WriteString(write_chunks(paragraph.Chunks, false))
+		buffer.WriteString(suffix.String())
+
+	case "Lyrics":
+		if in_speech {
+			buffer.WriteRune('\n')
+		} else {
+			buffer.WriteString("\n\n")
+		}
+		for i, line := range strings.Split(write_chunks(paragraph.Chunks, false), "\n") {
+			if i > 0 {
+				buffer.WriteRune('\n')
+			}
+			buffer.WriteRune('~')
+			buffer.WriteString(line)
+		}
+		buffer.WriteString(suffix.String())
+
+	case "Transition":
+		buffer.WriteString("\n\n")
+		text := write_chunks(paragraph.Chunks, true)
+
+		// force transitions if we know Fountain wouldn't identify them
+		if !is_valid_transition(text) {
+			buffer.WriteString("> ")
+		}
+
+		buffer.WriteString(text)
+		buffer.WriteString(suffix.String())
+
+	default:
+		switch paragraph.Type {
+		case "Action", "General", "Shot", "":
+		default:
+			state.unknown[paragraph.Type] += 1
+		}
+
+		buffer.WriteString("\n\n")
+
+		text := write_chunks(paragraph.Chunks, paragraph.Type == "Shot")
+
+		if paragraph.Alignment == "Center" {
+			buffer.WriteString("> ")
+			buffer.WriteString(text)
+			buffer.WriteString(" <")
+			buffer.WriteString(suffix.String())
+			return
+		}
+
+		// force action if Fountain would take it for
+		// something else
+		if text != "" && (is_valid_scene(text) || is_valid_transition(text) || is_valid_character(text)) {
+			buffer.WriteRune('!')
+		}
+
+		buffer.WriteString(text)
+		buffer.WriteString(suffix.String())
+	}
+}
+
+func chunk_revision(chunks []*XML_Chunk) int {
+	revision := 0
+	for _, chunk := range chunks {
+		if chunk.RevisionID > revision {
+			revision = chunk.RevisionID
+		}
 	}
+	return revision
+}
+
+func chunks_text(chunks []*XML_Chunk) string {
+	buffer := strings.Builder{}
+	for _, chunk := range chunks {
+		buffer.WriteString(chunk.Text)
+	}
+	return buffer.String()
+}
+
+func note_text(note *XML_Script_Note) string {
+	lines := make([]string, 0, len(note.Content))
+	for _, paragraph := range note.Content {
+		if text := strings.TrimSpace(chunks_text(paragraph.Chunks)); text != "" {
+			lines = append(lines, text)
+		}
+	}
+	return strings.Join(lines, " ")
 }
 
 // lookup for FD > Fountain markers
@@ -272,6 +681,7 @@ func final_draft_styles(text string) (string, bool) {
 	case "Italic":    return "*", true
 	case "Bold":      return "**", true
 	case "Underline": return "_", true
+	case "Strikeout": return "~~", true
 	}
 	return "", false
 }
@@ -302,6 +712,13 @@ func write_chunks(input []*XML_Chunk, force_uppercase bool) string {
 			}
 		}
 
+		// anything with a background colour other
+		// than white is treated as a highlight
+		if chunk.Background != "" && strings.Trim(strings.ToUpper(chunk.Background), "#F") != "" {
+			opening = opening + "+"
+			closing = "+" + closing
+		}
+
 		if len(chunk.Label) != 0 {
 			switch chunk.Label {
 			case "Page #":
@@ -312,10 +729,311 @@ func write_chunks(input []*XML_Chunk, force_uppercase bool) string {
 			continue
 		}
 
+		// Fountain markers have to touch the text
+		// they're styling, so any surrounding
+		// spaces are left outside of them
+		text  := strings.TrimSpace(chunk.Text)
+		left  := chunk.Text[:strings.Index(chunk.Text, text)]
+		right := chunk.Text[len(left) + len(text):]
+
+		if text == "" {
+			buffer.WriteString(chunk.Text)
+			continue
+		}
+
+		buffer.WriteString(left)
 		buffer.WriteString(opening)
-		buffer.WriteString(chunk.Text)
+		buffer.WriteString(text)
 		buffer.WriteString(closing)
+		buffer.WriteString(right)
 	}
 
 	return buffer.String()
 }
+
+func export_final_draft(config *Config) {
+	data, success := parse_file(config)
+	if !success {
+		return
+	}
+
+	output := build_final_draft(data)
+
+	blob, err := xml.MarshalIndent(output, "", "\t")
+	if err != nil {
+		eprintln("failed to marshal", config.output_file)
+		return
+	}
+
+	blob = append([]byte(xml.Header), blob...)
+	blob = append(blob, '\n')
+
+	success = write_file(fix_path(config.output_file), blob)
+	if !success {
+		eprintln("failed to write", config.output_file)
+	}
+}
+
+// build_final_draft lays the script out as a Final Draft
+// document, which other screenplay exports start from
+func build_final_draft(data *Fountain) *Final_Draft {
+	prepare_export(data)
+
+	output := new(Final_Draft)
+	output.DocumentType = "Script"
+	output.Template     = "No"
+	output.Version      = "5"
+
+	revisions := final_draft_revisions(data, output)
+
+	// title page
+	{
+		// Final Draft title pages are placed by hand, so we
+		// centre the main credits and put everything else
+		// below them on the left
+		centre := [...]string{
+			data.Title.Title,
+			data.Title.Credit,
+			data.Title.Author,
+			data.Title.Source,
+		}
+		left := [...]string{
+			data.Title.Notes,
+			data.Title.DraftDate,
+			data.Title.Copyright,
+			data.Title.Revision,
+			data.Title.Contact,
+			data.Title.Info,
+		}
+
+		write := func(text, alignment string) {
+			if text == "" {
+				return
+			}
+			if len(output.Title) > 0 {
+				output.Title = append(output.Title, &XML_Paragraph{Alignment: alignment})
+			}
+			for _, line := range text_spans(data, text) {
+				output.Title = append(output.Title, &XML_Paragraph{
+					Alignment: alignment,
+					Chunks:    final_draft_chunks(data, line, NORMAL, 0),
+				})
+			}
+		}
+
+		for _, text := range centre {
+			write(text, "Center")
+		}
+		for _, text := range left {
+			write(text, "Left")
+		}
+	}
+
+	// base content
+	{
+		var dual *XML_Dual_Dialogue
+		new_page := false
+
+		for i := range data.Content {
+			section := &data.Content[i]
+
+			if section.Type == PAGE_BREAK {
+				new_page = true
+				continue
+			}
+			if section.Type < is_printable {
+				continue
+			}
+
+			section_type := section.Type
+			if section_type == SECTION {
+				section_type += Section_Type(section.Level - 1)
+			}
+			if data.template.types[section_type].skip {
+				continue
+			}
+
+			target := &output.Content
+
+			switch section.Type {
+			case DUAL_CHARACTER, DUAL_PARENTHETICAL, DUAL_DIALOGUE, DUAL_LYRIC:
+				if dual == nil || (section.Type == DUAL_CHARACTER && section.Level == 1) {
+					dual = new(XML_Dual_Dialogue)
+					holder := &XML_Paragraph{Dual: dual}
+					if new_page {
+						holder.StartsNewPage = "Yes"
+						new_page = false
+					}
+					output.Content = append(output.Content, holder)
+				}
+				target = &dual.Content
+			default:
+				dual = nil
+			}
+
+			kind, alignment := final_draft_type(section.Type)
+
+			style := NORMAL
+			if section.Type == LYRIC || section.Type == DUAL_LYRIC {
+				style = ITALIC
+			}
+
+			number := ""
+			if section.Type == SCENE {
+				number = export_scene_number(data, section)
+			}
+
+			for _, line := range section_spans(data, section) {
+				paragraph := &XML_Paragraph{
+					Type:      kind,
+					Number:    number,
+					Alignment: alignment,
+					Chunks:    final_draft_chunks(data, line, style, revisions[section.Revision]),
+				}
+				if new_page {
+					paragraph.StartsNewPage = "Yes"
+					new_page = false
+				}
+				*target = append(*target, paragraph)
+			}
+		}
+	}
+
+	return output
+}
+
+func final_draft_type(t Section_Type) (string, string) {
+	switch t {
+	case ACTION:
+		return "Action", ""
+	case SCENE:
+		return "Scene Heading", ""
+	case CHARACTER, DUAL_CHARACTER:
+		return "Character", ""
+	case PARENTHETICAL, DUAL_PARENTHETICAL:
+		return "Parenthetical", ""
+	case DIALOGUE, DUAL_DIALOGUE, LYRIC, DUAL_LYRIC:
+		return "Dialogue", ""
+	case TRANSITION:
+		return "Transition", ""
+	case CENTERED:
+		return "Action", "Center"
+	}
+	return "General", ""
+}
+
+// the inverse of final_draft_styles, with the styles
+// Fountain has and Final Draft keeps elsewhere
+func final_draft_chunks(data *Fountain, spans []Span, extra Leaf_Type, revision int) []*XML_Chunk {
+	chunks := make([]*XML_Chunk, 0, len(spans))
+
+	for _, span := range spans {
+		chunk := &XML_Chunk{
+			Text:       span.text,
+			RevisionID: revision,
+		}
+
+		style  := span.style | extra
+		styles := make([]string, 0, 4)
+
+		if style & BOLD      != 0 { styles = append(styles, "Bold") }
+		if style & ITALIC    != 0 { styles = append(styles, "Italic") }
+		if style & UNDERLINE != 0 { styles = append(styles, "Underline") }
+		if style & STRIKEOUT != 0 { styles = append(styles, "Strikeout") }
+
+		chunk.Style = strings.Join(styles, "+")
+
+		if style & HIGHLIGHT != 0 {
+			chunk.Background = final_draft_color(data.template.highlight_color)
+		}
+		if style & NOTE != 0 {
+			chunk.Color = final_draft_color(data.template.note_color)
+		}
+
+		chunks = append(chunks, chunk)
+	}
+
+	return chunks
+}
+
+// Final Draft stores colours as 16 bits per channel
+func final_draft_color(c Color) string {
+	return fmt.Sprintf("#%02X%02X%02X%02X%02X%02X", c.R, c.R, c.G, c.G, c.B, c.B)
+}
+
+// the usual order of revision colours in production,
+// which Final Draft follows by default
+var final_draft_revision_colors = [...]struct{
+	name  string
+	color Color
+}{
+	{"white",     Color{255, 255, 255}},
+	{"blue",      Color{150, 200, 255}},
+	{"pink",      Color{255, 190, 220}},
+	{"yellow",    Color{255, 255, 150}},
+	{"green",     Color{180, 255, 180}},
+	{"goldenrod", Color{240, 200, 90}},
+	{"buff",      Color{240, 220, 180}},
+	{"salmon",    Color{255, 170, 140}},
+	{"cherry",    Color{220, 60, 90}},
+}
+
+// final_draft_revisions builds the revision sets for every
+// @rev tag in the script and returns their IDs by name.
+// known colours are numbered in production order, anything
+// else follows them in the order it first appears.
+func final_draft_revisions(data *Fountain, output *Final_Draft) map[string]int {
+	found := make(map[string]bool, 8)
+	names := make([]string, 0, 8)
+
+	for _, section := range data.Content {
+		if section.Revision == "" || found[section.Revision] {
+			continue
+		}
+		found[section.Revision] = true
+		names = append(names, section.Revision)
+	}
+
+	if len(names) == 0 {
+		return nil
+	}
+
+	ordered := make([]string, 0, len(names))
+	for _, known := range final_draft_revision_colors {
+		if found[known.name] {
+			ordered = append(ordered, known.name)
+			delete(found, known.name)
+		}
+	}
+	for _, name := range names {
+		if found[name] {
+			ordered = append(ordered, name)
+		}
+	}
+
+	ids := make(map[string]int, len(ordered))
+	output.Revisions = new(XML_Revisions)
+
+	for i, name := range ordered {
+		page_color := Color{255, 255, 255}
+		for _, known := range final_draft_revision_colors {
+			if known.name == name {
+				page_color = known.color
+			}
+		}
+
+		ids[name] = i + 1
+		output.Revisions.Revisions = append(output.Revisions.Revisions, &XML_Revision{
+			ID:           i + 1,
+			Name:         title_case(name) + " Rev.",
+			Mark:         "*",
+			Color:        final_draft_color(Color{}),
+			PageColor:    final_draft_color(page_color),
+			FullRevision: "No",
+		})
+	}
+
+	output.Revisions.ActiveSet = fmt.Sprintf("%d", len(ordered))
+
+	return ids
+}
@@ -0,0 +1,157 @@
+/*
+	Meander
+	A portable Fountain utility for production writing
+	Copyright (C) 2022-2023 Harley Denham
+*/
+
+package main
+
+import "bytes"
+import "strings"
+import "testing"
+import "encoding/xml"
+
+import "github.com/lichendust/meander/fountain"
+
+// final_draft_round_trip exports Fountain as Final Draft
+// and imports it again, the same way the convert command
+// does in each direction.  the title page, lyrics and notes
+// have no exact equivalent, so they're tested separately.
+func final_draft_round_trip(t *testing.T, text string) ([]byte, string) {
+	t.Helper()
+
+	data := init_data(new(Config), parse_text(t, text))
+
+	blob, err := xml.MarshalIndent(build_final_draft(data), "", "\t")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	imported, err := read_final_draft(blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return blob, final_draft_fountain(imported)
+}
+
+func TestFinalDraftRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"scenes and action", "INT. HOUSE - DAY #1#\n\nAction.\n\nEXT. GARDEN - NIGHT #2#\n\nMore action."},
+		{"speech",            "BOB\n(quietly)\nHello.\n\nALICE\nHi."},
+		{"dual dialogue",     "BOB\nOne.\n\nALICE ^\nTwo."},
+		{"transitions",       "Action.\n\nCUT TO:\n\n> THE END <"},
+		{"styles",            "Some **bold**, *italic*, _underlined_ and ~~struck~~ words."},
+		{"lookalike action",  "!INT. is not a heading\n\n!CUT TO:"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			blob, output := final_draft_round_trip(t, test.input)
+
+			// the import adds a gender table of its own
+			first  := parse_text(t, test.input)
+			second := parse_text(t, output)
+			second.Characters = nil
+			first.Characters  = nil
+
+			if a, b := describe_document(first), describe_document(second); a != b {
+				t.Errorf("exported as\n%s\nimported as\n%s\nwhich reads as\n%s\nnot\n%s", blob, output, b, a)
+			}
+		})
+	}
+}
+
+func TestFinalDraftRoundTripExtras(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		notes   bool
+		blob    []string // in the export
+		missing []string // not in the export
+		output  []string // in the import
+	}{
+		{
+			name:    "no notes",
+			input:   "Action. [[a note]]",
+			missing: []string{"<ScriptNote", "<SmartType"},
+		},
+		{
+			// notes are exported as coloured text, so
+			// they come back as part of the action
+			name:   "notes",
+			input:  "Action. [[a note]]",
+			notes:  true,
+			blob:   []string{`Color="#80808080FFFF">a note<`},
+			output: []string{"Action. a note"},
+		},
+		{
+			name:   "lyrics",
+			input:  "BOB\n~Singing a song.",
+			blob:   []string{`<Text Style="Italic">Singing a song.</Text>`},
+			output: []string{"BOB\n*Singing a song.*"},
+		},
+		{
+			// the centred credits all become the title
+			name:   "title page",
+			input:  "Title: Round Trip\nAuthor: Someone\nContact: Somewhere\n\nAction.",
+			output: []string{"title:\n\tRound Trip\n\tSomeone\nnotes:\n\tSomewhere\n"},
+		},
+		{
+			name:   "revisions",
+			input:  "BOB @blue\nHello.",
+			blob:   []string{`<Revision ID="1" Name="Blue Rev."`, `<Text RevisionID="1">BOB</Text>`},
+			output: []string{"BOB @blue"},
+		},
+		{
+			name:   "cast",
+			input:  "BOB\nHello.\n\nALICE\nHi.\n\n/*\n\t[gender.female]\n\tAlice\n*/",
+			output: []string{"[gender.unknown]\n\tBob\n\tAlice"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := new(Config)
+			config.include_notes = test.notes
+
+			doc, err := fountain.Parse(strings.NewReader(test.input), fountain.Options{IncludeNotes: test.notes})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			data := init_data(config, doc)
+
+			blob, err := xml.MarshalIndent(build_final_draft(data), "", "\t")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, x := range test.blob {
+				if !bytes.Contains(blob, []byte(x)) {
+					t.Errorf("export has no %q in\n%s", x, blob)
+				}
+			}
+			for _, x := range test.missing {
+				if bytes.Contains(blob, []byte(x)) {
+					t.Errorf("export has %q in\n%s", x, blob)
+				}
+			}
+
+			imported, err := read_final_draft(blob)
+			if err != nil {
+				t.Fatal(err)
+			}
+			output := final_draft_fountain(imported)
+
+			for _, x := range test.output {
+				if !strings.Contains(output, x) {
+					t.Errorf("import has no %q in\n%s", x, output)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,272 @@
+/*
+	Meander
+	A portable Fountain utility for production writing
+	Copyright (C) 2022-2023 Harley Denham
+*/
+
+package main
+
+import "fmt"
+import "strconv"
+
+// Appearance is one character's part in the script:
+// where they first and last turn up, how often and
+// how much they say
+type Appearance struct {
+	index int // into Characters
+
+	first *Scene
+	last  *Scene
+
+	scenes int
+	words  int
+
+	marks []string // one per scene, as in the cross-plot
+}
+
+func command_crossplot(config *Config) {
+	if !valid_report_format(config) {
+		return
+	}
+
+	data, success := parse_file(config)
+	if !success {
+		return
+	}
+
+	if !number_scenes(config, data) {
+		return
+	}
+
+	scenes := collect_scenes(config, data)
+
+	if len(scenes) == 0 {
+		eprintf("crossplot: %q has no scenes", config.source_file)
+		return
+	}
+
+	// the grid is labelled by scene number,
+	// so every column needs its own
+	if repeated := repeated_numbers(scenes); len(repeated) > 0 {
+		eprintf("crossplot: more than one scene is numbered %q", repeated[0])
+		return
+	}
+
+	list := collect_appearances(data, scenes)
+
+	if len(list) == 0 {
+		eprintf("crossplot: %q has no characters", config.source_file)
+		return
+	}
+
+	if wants_csv(config) {
+		rows := make([][]string, 0, len(list) + 1)
+
+		header := []string{
+			"ID", "Character", "First Scene", "First Page",
+			"Last Scene", "Last Page", "Scenes", "Words",
+		}
+		for _, scene := range scenes {
+			header = append(header, scene.number)
+		}
+		rows = append(rows, header)
+
+		for _, a := range list {
+			row := []string{
+				strconv.Itoa(a.index + 1),
+				data.Characters[a.index].Name,
+				a.first.number,
+				strconv.Itoa(a.first.page),
+				a.last.number,
+				strconv.Itoa(a.last.page),
+				strconv.Itoa(a.scenes),
+				strconv.Itoa(a.words),
+			}
+			rows = append(rows, append(row, a.marks...))
+		}
+
+		if !write_csv(config.output_file, rows) {
+			eprintln("failed to write", config.output_file)
+		}
+		return
+	}
+
+	render_crossplot(config, data, scenes, list)
+}
+
+// collect_appearances marks every scene each character
+// speaks or is seen in, in the order of the character
+// list, so the IDs match the stripboard.  aliases are
+// already merged by the lookup.
+func collect_appearances(data *Fountain, scenes []*Scene) []*Appearance {
+	list := make([]*Appearance, 0, len(data.Characters))
+
+	for i, c := range data.Characters {
+		a := &Appearance{
+			index: i,
+			words: c.Words,
+			marks: make([]string, len(scenes)),
+		}
+
+		for j, scene := range scenes {
+			switch {
+			case has_int(scene.cast, i):
+				a.marks[j] = CROSS_SPEAKING
+			case has_int(scene.silent, i):
+				a.marks[j] = CROSS_SILENT
+			default:
+				continue
+			}
+
+			if a.first == nil {
+				a.first = scene
+			}
+			a.last = scene
+			a.scenes += 1
+		}
+
+		if a.scenes > 0 {
+			list = append(list, a)
+		}
+	}
+
+	return list
+}
+
+// render_crossplot prints a summary table of the characters,
+// followed by the grid of characters against scenes, split
+// across as many pages as it takes to fit every scene
+func render_crossplot(config *Config, data *Fountain, scenes []*Scene, list []*Appearance) {
+	r := new_report(config, data, CROSS_HEADING, true)
+
+	id_width     := len(strconv.Itoa(len(data.Characters))) + 2
+	name_width   := rune_count(CROSS_CHARACTER)
+	number_width := rune_count(CROSS_FIRST)
+	grid_width   := 2
+
+	for _, a := range list {
+		if n := rune_count(data.Characters[a.index].Name); n > name_width {
+			name_width = n
+		}
+	}
+	if name_width > 24 {
+		name_width = 24
+	}
+
+	for _, scene := range scenes {
+		if n := rune_count(scene.number); n > grid_width {
+			grid_width = n
+		}
+	}
+	if grid_width > number_width {
+		number_width = grid_width
+	}
+
+	col_name   := r.left + float64(id_width) * CHAR_WIDTH
+	col_first  := col_name + float64(name_width + 2) * CHAR_WIDTH
+	col_fpage  := col_first + float64(number_width + 2) * CHAR_WIDTH
+	col_last   := col_fpage + 6 * CHAR_WIDTH
+	col_lpage  := col_last + float64(number_width + 2) * CHAR_WIDTH
+	col_scenes := col_lpage + 6 * CHAR_WIDTH
+	col_words  := col_scenes + 8 * CHAR_WIDTH
+
+	header := func() {
+		report_text(r, r.left,     CROSS_ID,        BOLD)
+		report_text(r, col_name,   CROSS_CHARACTER, BOLD)
+		report_text(r, col_first,  CROSS_FIRST,     BOLD)
+		report_text(r, col_fpage,  BREAKDOWN_PAGE,  BOLD)
+		report_text(r, col_last,   CROSS_LAST,      BOLD)
+		report_text(r, col_lpage,  BREAKDOWN_PAGE,  BOLD)
+		report_text(r, col_scenes, CROSS_SCENES,    BOLD)
+		report_text(r, col_words,  CROSS_WORDS,     BOLD)
+		report_rule(r)
+		r.y += LINE_HEIGHT * 2
+	}
+
+	header()
+
+	for _, a := range list {
+		if !report_fits(r, LINE_HEIGHT) {
+			header()
+		}
+
+		report_text(r, r.left,     strconv.Itoa(a.index + 1),                          NORMAL)
+		report_text(r, col_name,   truncate(data.Characters[a.index].Name, name_width), NORMAL)
+		report_text(r, col_first,  a.first.number,                                     NORMAL)
+		report_text(r, col_fpage,  strconv.Itoa(a.first.page),                         NORMAL)
+		report_text(r, col_last,   a.last.number,                                      NORMAL)
+		report_text(r, col_lpage,  strconv.Itoa(a.last.page),                          NORMAL)
+		report_text(r, col_scenes, strconv.Itoa(a.scenes),                             NORMAL)
+		report_text(r, col_words,  strconv.Itoa(a.words),                              NORMAL)
+
+		r.y += LINE_HEIGHT
+	}
+
+	// the grid, one band of scenes at a time
+	const row_height = LINE_HEIGHT + 4
+
+	col_grid   := col_first
+	cell_width := float64(grid_width + 1) * CHAR_WIDTH
+	per_band   := int((r.right - col_grid) / cell_width)
+
+	// a scene number too long for even one cell
+	// to fit just runs over the margin
+	if per_band < 1 {
+		per_band = 1
+	}
+
+	for start := 0; start < len(scenes); start += per_band {
+		end := start + per_band
+		if end > len(scenes) {
+			end = len(scenes)
+		}
+
+		r.doc.AddPage()
+		r.y = r.top
+
+		grid_header := func() {
+			report_text(r, r.left, CROSS_ID, BOLD)
+			report_text(r, col_name, CROSS_CHARACTER, BOLD)
+			for j := start; j < end; j++ {
+				x := col_grid + float64(j - start) * cell_width
+				report_text(r, cross_centre(x, cell_width, scenes[j].number), scenes[j].number, BOLD)
+			}
+			r.y += row_height
+		}
+
+		report_text(r, r.left, fmt.Sprintf(CROSS_LEGEND, CROSS_SPEAKING, CROSS_SILENT), ITALIC)
+		r.y += LINE_HEIGHT * 2
+
+		grid_header()
+
+		for _, a := range list {
+			if !report_fits(r, row_height) {
+				grid_header()
+			}
+
+			report_text(r, r.left,   strconv.Itoa(a.index + 1),                           NORMAL)
+			report_text(r, col_name, truncate(data.Characters[a.index].Name, name_width), NORMAL)
+
+			r.doc.SetStrokeColor(0, 0, 0)
+			r.doc.SetLineWidth(0.25)
+
+			for j := start; j < end; j++ {
+				x := col_grid + float64(j - start) * cell_width
+
+				r.doc.RectFromUpperLeftWithStyle(x, r.y - PICA + 1, cell_width, row_height, "D")
+
+				if mark := a.marks[j]; mark != "" {
+					report_text(r, cross_centre(x, cell_width, mark), mark, BOLD)
+				}
+			}
+
+			r.y += row_height
+		}
+	}
+
+	save_report(r, config.output_file)
+}
+
+func cross_centre(x, width float64, text string) float64 {
+	return x + (width - float64(rune_count(text)) * CHAR_WIDTH) / 2
+}
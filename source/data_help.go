@@ -42,15 +42,24 @@ options as possible:
 $1Commands$0
 --------
 
-    $1render$0    render input file to PDF (default)
-    $1gender$0    display gender analysis statistics
-    $1merge$0     merge a multi-file document
-    $1data$0      create a machine-readable document
-    $1convert$0   (experimental) convert from other software
-    $1help$0      print this message and others
-    $1version$0   print the current version
-    $1credit$0    print the credit and legal text
-    $1fonts$0     export a copy of the bundled fonts
+    $1render$0      render input file to PDF (default)
+    $1gender$0      display gender analysis statistics
+    $1merge$0       merge a multi-file document
+    $1archive$0     render input file to paginated text
+    $1data$0        create a machine-readable document
+    $1check$0       report problems without rendering
+    $1breakdown$0   list every scene for scheduling
+    $1stripboard$0  print colour-coded production strips
+    $1locations$0   list scenes and pages for every set
+    $1crossplot$0   chart characters against scenes
+    $1sides$0       print chosen scenes for actors
+    $1runtime$0     estimate the running time
+    $1words$0       count the words in every section
+    $1convert$0     (experimental) convert from other software
+    $1help$0        print this message and others
+    $1version$0     print the current version
+    $1credit$0      print the credit and legal text
+    $1fonts$0       export a copy of the bundled fonts
 
 $1Help$0
 ----
@@ -59,7 +68,140 @@ Use $1meander help [command]$0 for more information on the
 above commands, but also see the additional help topics 
 available below:
 
-    $1fountain$0  fountain cheat sheet
+    $1fountain$0    fountain cheat sheet
+`
+		case "archive":
+			return `
+$1Archive Usage$0
+-------------
+
+    meander $1archive$0 input.fountain [output.txt] [--flags]
+
+Archive lays out a screenplay exactly as $1render$0 would, then 
+writes each page out as plain, monospaced text instead of a PDF.
+
+Line breaks, indents, scene numbers, headers and footers, 
+(MORE) and (CONT'D) all land where they do on the printed page, 
+so the archive is a lasting record of exactly what was printed 
+that can be read and compared with any text tool.
+
+Pages are separated by a form feed character on a line of its 
+own.
+
+Archive takes the same flags as $1render$0, such as 
+$1--format$0, $1--paper$0 and $1--scene$0, but text styles are 
+not kept and the gender and table of contents pages are left 
+out.
+`
+		case "breakdown":
+			return `
+$1Breakdown Usage$0
+---------------
+
+    meander $1breakdown$0 input.fountain [output.pdf]
+    meander $1breakdown$0 input.fountain --output-format csv
+
+Breakdown lays the script out as it would be rendered, then 
+lists every scene with the details a line producer needs to 
+schedule it:
+
+    + the scene number
+    + INT or EXT, location and time of day,
+      taken from the scene heading
+    + the page the scene starts on
+    + its length in eighths of a page
+    + the characters who speak in it
+    + the characters only mentioned in the
+      action, written in capitals
+    + its synopsis, from any = lines
+
+The breakdown is written as a PDF by default, or as a 
+spreadsheet if the output file ends in .csv or 
+$1--output-format csv$0 is used.  In the CSV, lengths are given 
+as a plain number of eighths.
+
+$1Mentioned Characters$0
+--------------------
+
+Scripts introduce characters in capitals, so any run of 
+capitalised words in the action is taken to be a character, 
+unless they speak in the scene or the run ends in an 
+exclamation mark, which is usually a sound:
+
+    BOB enters with a WAITER.    Waiter
+    Something goes BANG!         (nothing)
+
+Names are matched against the characters from the gender table, 
+so other names are merged into one.
+
+The $1--format$0, $1--paper$0 and $1--scene$0 flags work as 
+they do for $1render$0, and change the page numbers and lengths 
+to match.
+`
+		case "check":
+			return `
+$1Check Usage$0
+-----------
+
+    meander $1check$0 input.fountain [--json]
+
+Check parses and lays out a screenplay without rendering it, 
+then reports anything that Meander had to guess about or 
+quietly work around.
+
+Each problem is printed with the file, line and column it came 
+from, followed by the chain of includes that led to it:
+
+    scene.fountain:12:1: warning: ... [code]
+        included from film.fountain:40
+
+$1Errors$0
+------
+
+    $1missing-include$0
+        an include points at a file that doesn't exist
+    $1missing-label$0
+        an include names a section or scene that
+        isn't in the file
+    $1include-cycle$0
+        a file includes itself, directly or through
+        one of its children; the chain is reported
+    $1template-error$0
+        a [template] entry, or a reading speed in
+        the title page, couldn't be understood
+
+$1Warnings$0
+--------
+
+    $1unbalanced-emphasis$0
+        a style marker was never closed, so it's
+        printed as plain text
+    $1demoted-character$0
+        a character with no dialogue after it, which
+        is treated as action instead
+    $1unknown-title-key$0
+        a title page key Meander doesn't recognise
+    $1duplicate-scene-number$0
+        a forced scene number that is used twice
+    $1scene-number-order$0
+        a forced scene number lower than the one
+        before it
+
+$1Exit Status$0
+-----------
+
+Check exits with a non-zero status if any errors are found, or 
+if the input file doesn't exist.  Warnings alone do not affect 
+the exit status.  This makes it suitable for use in a 
+pre-commit hook.
+
+$1Flags$0
+-----
+
+    $1--json$0
+
+Prints the report as JSON to standard output instead, with a 
+count of errors and warnings and a list of every problem found.
 `
 		case "convert":
 			return `
@@ -68,9 +210,168 @@ $1Convert Usage$0
 
 Experimental!
 
-    meander $1convert$0 input.fdx [output]
+    meander $1convert$0 input.fdx [output.fountain]
+    meander $1convert$0 input.fountain [output.fdx]
+    meander $1convert$0 input.osf [output.fountain]
+    meander $1convert$0 input.fadein [output.fountain]
+    meander $1convert$0 input.fountain [output.osf]
+    meander $1convert$0 input.fountain [output.fadein]
+    meander $1convert$0 input.highland [output.fountain]
+    meander $1convert$0 project.scriv [output.fountain]
+    meander $1convert$0 input.txt [output.fountain]
+    meander $1convert$0 input.fountain [output.html]
+    meander $1convert$0 input.fountain [output.epub]
+    meander $1convert$0 input.fountain [output.docx]
+
+Converts a Final Draft, Open Screenplay Format, Fade In, 
+Highland, Scrivener or plain text file to Fountain, or a 
+Fountain file to Final Draft, OSF, Fade In, HTML, EPUB or Word. 
+ When converting from Fountain, the format is chosen by 
+$1--output-format$0 or the extension of the output file, which 
+defaults to .fdx.
+
+$1Final Draft Import$0
+------------------
+
+Final Draft elements are mapped to their Fountain equivalents, 
+with force-characters added wherever Fountain wouldn't 
+recognise them by itself:
+
+    + dual dialogue gets a ^ on the second speaker
+    + script notes become [[notes]]
+    + transitions, shots, lyrics and general
+      paragraphs keep their meaning
+    + text styles, including strikeout and
+      highlights, become Fountain markup
+    + revision marks become @revision tags named
+      after their revision set
+    + the SmartType character list becomes a
+      [gender.unknown] table at the end of the
+      file, with names that only differ by
+      punctuation combined as aliases
+
+Any element types Meander doesn't know are written as action 
+and listed in a summary when the conversion finishes.
+
+$1Final Draft Export$0
+------------------
+
+Meander's own syntax extensions are carried across where Final 
+Draft has an equivalent:
+
+    + bold, italics, underline and strikeout
+      become Final Draft text styles
+    + highlights become a text background
+    + dual dialogue becomes a dual dialogue block
+    + forced scene numbers become scene numbers
+    + @revision tags become revision sets, with
+      the usual colours in production order
+    + lyrics become italic dialogue
+    + counters and variables are written out as
+      their values
+
+Notes are only exported with $1--notes$0, and are coloured with 
+the template's note colour.  The $1--scene$0 flag can remove or 
+regenerate scene numbers as it does when rendering.
+
+Headers and footers are not exported, leaving Final Draft's own 
+defaults in place.
+
+$1OSF and Fade In$0
+---------------
+
+Open Screenplay Format files (.osf) and Fade In files (.fadein, 
+which are OSF in a zip) are converted in both directions the 
+same way as Final Draft, keeping:
+
+    + scene numbers
+    + dual dialogue
+    + title page text
+    + bold, italics, underline and strikeout
+
+OSF has no revision sets, highlights or notes, so these are 
+left out when exporting.
+
+$1Highland Import$0
+----------------
+
+Highland files are zipped bundles with the script inside them 
+in plain Fountain.  The script is written out as-is, and any 
+attachments are unpacked into an $1assets$0 folder next to it.
+
+$1Scrivener Import$0
+----------------
+
+Scrivener projects are split up to suit Meander's includes.  
+Every document in the draft folder is written to its own 
+Fountain file, in a folder named after the output, and the 
+output file itself is a list of $1include:$0 lines in binder 
+order:
+
+    + folders become sections, up to three
+      levels deep
+    + documents left out of compile are
+      included inside a boneyard
+    + bold, italics, underline and strikeout
+      become Fountain markup
+    + paragraphs are separated by blank lines,
+      unless the document already has them
+
+$1Plain Text Import$0
+-----------------
+
+Scripts that only survive as monospaced text, laid out in the 
+usual industry format, can be read back in.  Each line's type 
+is worked out from where it starts and how it's written:
+
+    + scene headings are flush left and in
+      capitals, keeping any scene numbers in
+      the margins
+    + characters sit at about 3.7", with their
+      dialogue at 2.5" directly beneath
+    + transitions are in capitals, over to the
+      right or ending in "TO:"
+    + capitals in the middle of the page are
+      centred text
+    + two character names side by side start
+      a dual dialogue block, which is split
+      down the middle into two speeches
+    + a first page with no scenes on it, ending
+      in a page break, is the title page
+
+Page numbers, (MORE), (CONT'D) and CONTINUED markers are 
+removed, speeches split over a page are put back together and 
+wrapped lines are rejoined.  Where Fountain would read a line 
+differently, a force-character is added, so it's always worth 
+checking the result.
+
+$1EPUB Export$0
+-----------
+
+Manuscripts, using the $1manuscript$0 or $1manuscriptcompact$0 
+formats, can be exported as reflowable EPUB 3 books:
+
+    + each top-level section starts a chapter
+    + the contents are built from the sections
+    + the title page becomes the book's own title
+      page, title, author and rights
+    + text styles, highlights and notes are kept
 
-Converts a Final Draft XML file to Fountain.
+Layout that only makes sense on paper, such as widths and 
+margins, is left to the reader.
+
+$1DOCX Export$0
+-----------
+
+Manuscripts can be exported as Word documents:
+
+    + each type of element becomes a paragraph
+      style, with the template's indents, line
+      spacing and alignment
+    + sections are headings in Word's navigation
+    + page breaks, headers and footers are kept
+    + #page in a header or footer becomes a live
+      page number
 `
 		case "credit":
 			return `
@@ -87,6 +388,42 @@ Meander includes bundled copies of a font family, distributed
 and licensed under the SIL Open Font License v1.1.  These files 
 may be extracted and perused with the 'meander fonts' command, 
 and are attributed to the following authors:
+`
+		case "crossplot":
+			return `
+$1Cross-Plot Usage$0
+----------------
+
+    meander $1crossplot$0 input.fountain [output.pdf]
+
+Cross-plot charts every character against every scene, as a 
+landscape PDF or, with a .csv output file or $1--output-format 
+csv$0, a spreadsheet.
+
+The first table lists each character's first and last 
+appearance, as a scene number and a page, along with the number 
+of scenes they're in and the number of words of dialogue they 
+speak.
+
+The grid follows, with a column for each scene:
+
+    X    the character speaks in the scene
+    O    the character is introduced in capitals in
+         the action, but doesn't speak
+
+When there are more scenes than fit across a page, the grid 
+carries on over as many pages as it needs.
+
+Names are matched against the gender table, so a character's 
+other names are all counted as them, and each character's ID is 
+the same as their cast ID on the stripboard.  A script without 
+any scene numbers is numbered in order, as $1--scene generate$0 
+would.  A script that numbers only some of its scenes, or gives 
+two scenes the same number, is an error, since the grid can't 
+tell them apart.
+
+The $1--format$0, $1--paper$0 and $1--scene$0 flags work as 
+they do for $1render$0.
 `
 		case "data":
 			return `
@@ -102,20 +439,35 @@ $1Usage$0
 -----
 
     meander $1data$0 input.fountain [output] [--flags]
+    meander $1data$0 --schema
+
+$1Flags$0
+-----
 
-The resulting JSON blob is a dictionary containing four entries:
+    --paginate    lay the script out and record where
+                  everything falls on the page
+    --schema      print the JSON Schema for the format
+
+The $1--format$0, $1--paper$0, $1--scene$0 and $1--notes$0 
+flags work as they do for $1render$0.
+
+The resulting JSON blob is a dictionary containing six entries:
 
     + meta
     + title
+    + files
     + characters
     + content
+    + runtime
 
 $1Meta$0
 ----
 
 Meta stores information about the JSON format itself — the 
 version of Meander that created it and the version of the 
-format and its structures.
+format and its structures.  The format is currently at version 
+2, and is described by the JSON Schema printed with 
+$1--schema$0.
 
 $1Title$0
 -----
@@ -129,12 +481,34 @@ Title is a simple dictionary of the title page information.
         "draft_date": "December 2022"
     }
 
+$1Files$0
+-----
+
+Files lists every file that makes up the document, starting 
+with the input file itself.  Included files also record the 
+position of the include directive that pulled them in, so the 
+full include chain of any element can be followed back to the 
+input.
+
+    "files": [
+        {
+            "path": "myfilm.fountain"
+        },
+        {
+            "path": "scenes/opening.fountain",
+            "included_from": {
+                "file": 0,
+                "line": 12
+            }
+        }
+    ]
+
 $1Characters$0
 ----------
 
 Characters is a list of all speaking characters featured in the 
-screenplay with alternate names, gender information and 
-line-count based on the gender definition table.
+screenplay with alternate names, gender information, line-count 
+and word-count based on the gender definition table.
 
     "characters": [
         {
@@ -144,11 +518,13 @@ line-count based on the gender definition table.
             ],
             "gender": "male",
             "lines_spoken": 168,
+            "words_spoken": 2041,
         },
         {
             "name": "Rosemary",
             "gender": "female",
             "lines_spoken": 220,
+            "words_spoken": 2688,
         }
     ]
 
@@ -163,7 +539,10 @@ Content is a list of all screenplay content, tagged by type.
         {
             "type": "scene",
             "text": "EXT. PORCH - SUNSET",
-            "scene_number": "99-A"
+            "scene_number": "99-A",
+            "file": 1,
+            "line": 3,
+            "column": 1
         },
         {
             "type": "action",
@@ -203,12 +582,104 @@ The possible types are as follows:
     header
     footer
 
+Every element carries its source position: "file" is an index 
+into the files list, alongside the "line" and "column" at which 
+the element begins in that file.
+
+Text is given without any markup.  If any of it is styled, the 
+element also has a list of "spans": runs of text, each with a 
+list of the styles that apply to it.
+
+    {
+        "type": "dialogue",
+        "text": "I said no.",
+        "spans": [
+            { "text": "I said " },
+            { "text": "no", "style": ["bold"] },
+            { "text": "." }
+        ]
+    }
+
+The styles are "bold", "italic", "underline", "strikeout", 
+"highlight" and "note".
+
 The additional "level" field will provide more context unique 
 to each type:
 
     whitespace    number of blank lines on the page
     section       the level of the heading (1, 2, 3)
     dual_xxx      1 is always left, 2 is always right
+
+$1Paginated$0
+---------
+
+With $1--paginate$0, the script is laid out first, as it would 
+be rendered, and each element gains four more fields:
+
+    page       the page it's printed on
+    y          its distance from the top of the
+               page, in points
+    lines      the number of lines it takes up
+    eighths    its height in eighths of the page
+               body, to two decimal places
+
+Elements are listed as they appear on the page, so anything 
+broken across a page is listed once for each part, and the 
+headers, footers, (more)s and CONT'Ds added during layout are 
+included.  Anything the template doesn't print, such as notes 
+and synopses by default, is left out.
+
+$1Runtime$0
+-------
+
+The runtime estimate from $1meander runtime$0, for the whole 
+script, each section and each scene:
+
+    "runtime": {
+        "dialogue_wpm": 150,
+        "action_wpm": 80,
+        "eighths": 3,
+        "seconds": 22.7,
+        "sections": [
+            {
+                "text": "Act One",
+                "level": 1,
+                "eighths": 2,
+                "seconds": 18.8
+            }
+        ],
+        "scenes": [
+            {
+                "text": "INT. KITCHEN - DAY",
+                "scene_number": "1",
+                "section": "Act One",
+                "eighths": 1,
+                "seconds": 13.5
+            }
+        ]
+    }
+
+Lengths are in eighths of a page and times in seconds.  Each 
+section's totals include the sections inside it.
+
+$1Reading It Back$0
+---------------
+
+A data file can be turned back into Fountain, or rendered 
+directly, as long as its $1meta.version$0 is no newer than this 
+version of Meander and it isn't paginated:
+
+    meander $1convert$0 data.json [output.fountain]
+    meander $1render$0 data.json [output.pdf]
+
+Anything that reads Fountain, such as $1check$0 or the other 
+export formats, will also accept a data file.
+
+The Fountain is written in a canonical form rather than as the 
+original was: blank lines and force-characters are added 
+wherever they're needed, so "whitespace" elements can be left 
+out altogether.  Characters with a gender or other names are 
+written back into gender tables.
 `
 		case "fountain":
 			return `
@@ -499,6 +970,12 @@ You can also override the (more) and (cont'd) tags used when
 dialogue is broken across a page boundary.  You should specify 
 them inclusive of brackets.
 
+    Dialogue WPM: 150
+    Action WPM: 80
+
+The reading speeds used by $1meander runtime$0, in words per 
+minute.
+
 
 $1Page Breaks$0
 -----------
@@ -624,6 +1101,27 @@ reset —
 
     $1#WORDCOUNT$0  the total word count
 
+    $1#SECTIONWORDS$0
+                the word count of the section
+                the page is in, including any
+                sections inside it
+
+    $1#SCENEWORDS$0 the word count of the scene the
+                page is in
+
+    $1#DIALOGUEWORDS$0
+                the words of dialogue and lyrics
+                in the whole document
+
+    $1#NARRATIONWORDS$0
+                the words of action and centered
+                text in the whole document
+
+Section and scene counts are taken at the top of each page, so 
+a header gives the count of whichever section or scene the page 
+begins in.  $1meander words$0 lists the same counts for every 
+section.
+
 In fact, the default header in any new Meander document is 
 defined like so —
 
@@ -640,6 +1138,26 @@ Gender performs simple analysis of your characters' gender
 identities, providing a detailed print-out of how they break 
 down across a script.
 
+It counts characters, lines and words spoken for each gender, 
+and lines and words for each character.  If the script has 
+scene headings, it also gives —
+
+    + the scenes in which each gender speaks
+    + the scenes in which two or more characters
+      of the same gender speak to each other,
+      for every gender but the default
+
+The default gender is "unknown", the characters the gender 
+table doesn't cover, unless another is given with 
+$1--default-gender$0:
+
+    meander $1gender$0 input.fountain --default-gender male
+
+Each is given as a share of every scene in the script, and the 
+second is left out if no such scene turns up.  The same figures 
+are printed on the gender page added by $1render 
+--print-gender$0.
+
 The Gender command needs to be given data to ensure it can 
 provide accurate statistics.  It expects this in the form of a 
 boneyard comment:
@@ -700,6 +1218,62 @@ with all the variants, separated by pipes:
 
 The first name in the entry is used as their canonical name for 
 all subsequent output.
+`
+		case "locations":
+			return `
+$1Locations Usage$0
+---------------
+
+    meander $1locations$0 input.fountain [output.csv]
+
+Locations lists every set in the script, with the number of 
+scenes and the total length in pages filmed there, whether it's 
+used as an interior or exterior and the times of day it appears 
+at.
+
+Each scene heading is split into its prefix (INT, EXT and so 
+on), its location and its time of day, which is whatever 
+follows the last dash.  The location is then broken into a 
+hierarchy at each dash or comma, so these two scenes both count 
+towards the house and its kitchen:
+
+    INT. JOHN'S HOUSE - KITCHEN - DAY
+    INT. JOHNS HOUSE, KITCHEN - LATER
+
+Letter casing, apostrophes and other punctuation are ignored 
+when matching, and each set's totals include every set beneath 
+it.  The busiest sets are listed first.
+
+The report is printed to the terminal, unless an output file or 
+$1--output-format csv$0 is given, in which case it's written as 
+a CSV spreadsheet instead.
+
+$1Location Tables$0
+---------------
+
+Spelling variants that can't be matched automatically can be 
+listed in a boneyard comment, just like the gender tables:
+
+/*
+    [location.house]
+    John's House | Jon's House | Johnson Residence
+    Kitchen | Kitchenette
+
+    [location.exterior]
+    Garden | Back Garden
+*/
+
+The first name in each entry is the one used in the report, and 
+any of the others, whether it's a whole location or one part of 
+one, is counted as that name.  The word after the dot is a 
+group, which is included in the CSV.
+
+As with the gender table, the first non-whitespace text inside 
+the boneyard must be a heading.  Location and gender tables can 
+share a boneyard.
+
+The $1--format$0, $1--paper$0 and $1--scene$0 flags work as 
+they do for $1render$0.
 `
 		case "merge":
 			return `
@@ -731,9 +1305,40 @@ The path to the included file should be relative to the file in
 which the directive is written.
 
 They can be specified anywhere in text and can be nested in 
-multiple layers of children.  Critically, because of this 
-nested infinity, there is no cycle-safety.  Meander will get 
-stuck if a loop between included files is created.
+multiple layers of children.  If a file ends up including 
+itself, directly or through one of its children, Meander 
+reports the chain of includes that caused the loop and leaves 
+that directive as plain text.
+
+Includes inside /* boneyards */ and [[notes]] are ignored.
+
+$1Patterns$0
+--------
+
+The path can be a pattern, which includes every matching file 
+in alphabetical order, separated by blank lines:
+
+    $1include: episodes/*.fountain$0
+
+A pattern that matches the file it's written in will skip over 
+it.
+
+$1Labels$0
+------
+
+Adding a label after a # includes only one section or scene of 
+the file:
+
+    $1include: cold_opens.fountain#Episode 3$0
+    $1include: cold_opens.fountain#12A$0
+
+A section runs until the next section of the same or a higher 
+level, and includes any scenes and sub-sections inside it.  A 
+scene runs until the next scene or section.
+
+Sections are matched by their heading, and scenes by either 
+their #scene number# or their full heading.  Matching ignores 
+letter case.
 `
 		case "render":
 			return `
@@ -783,6 +1388,27 @@ parsers will reject the entire title page if the first entry is
 unknown to them, but will quietly skip later ones.  This is 
 never a guarantee, but it may be useful.
 
+$1Output Format$0
+-------------
+
+    $1--output-format -o$0
+
+    pdf             fully formatted PDF document
+    html            single web page, styled by the
+                    template
+    fdx             Final Draft, see $1help convert$0
+    osf             Open Screenplay Format
+    fadein          Fade In
+    txt             paginated plain text, see
+                    $1help archive$0
+    epub            e-book, for manuscripts only
+    docx            Word, for manuscripts only
+
+HTML output keeps the template's margins, widths, casing and 
+alignment as a stylesheet in the page itself, with a class for 
+each type of element.  There are no pages, so headers, footers 
+and page numbers are left out.
+
 $1Force Hidden Syntaxes$0
 ---------------------
 
@@ -795,6 +1421,185 @@ $1Starred Revision Markers$0
 
     $1--stars$0
     $1--stars-only$0
+
+$1Character Highlights$0
+--------------------
+
+    $1--highlight-character$0 NAME[:colour]
+
+Paints a character's cues, parentheticals and dialogue, 
+including dual dialogue, for actors' table-read copies.  Repeat 
+the flag for each character; each one gets the next colour in 
+turn unless one is given:
+
+    yellow, green, blue, pink, orange, purple
+
+or as red, green and blue values, such as 
+$1ALICE:170,240,170$0.  Names are matched against the gender 
+table, so a character's other names are highlighted too.  The 
+flag also works with $1sides$0.
+`
+		case "runtime":
+			return `
+$1Runtime Usage$0
+-------------
+
+    meander $1runtime$0 input.fountain
+
+Runtime estimates how long the script will run on screen, for 
+each scene, each section and the whole script.
+
+The page-a-minute rule is a fair guide to pacing, but it knows 
+nothing about how dense a page is.  Reading speeds are the 
+opposite, so the estimate for each scene is halfway between the 
+two:
+
+    + its length in eighths of a page, at a minute
+      a page
+    + its dialogue and lyrics at 150 words a minute,
+      plus its action at 80 words a minute
+
+Action is slower because it takes longer to play out than to 
+read.  Both speeds can be changed in the title page:
+
+    Dialogue WPM: 160
+    Action WPM: 70
+
+or in a [template] table, which takes precedence:
+
+/*
+    [template]
+    dialogue_wpm: 160
+    action_wpm: 70
+*/
+
+Scenes are listed in outline order, under the sections they 
+fall in, and each section is given the total of everything 
+inside it.  The same figures are included in $1meander data$0.
+
+The $1--format$0, $1--paper$0 and $1--scene$0 flags work as 
+they do for $1render$0.
+`
+		case "sides":
+			return `
+$1Sides Usage$0
+-----------
+
+    meander $1sides$0 input.fountain 12,14-16 [output.pdf]
+
+Sides prints just the pages an actor needs for the chosen 
+scenes, with a cover sheet listing each scene, its heading and 
+the pages it's on.
+
+Scenes are given as a comma-separated list of scene numbers and 
+ranges.  A range takes in every scene between its two ends in 
+script order, so 14-16 includes 15A if there is one.  Scene 
+numbers with hyphens of their own, like 12-A, can be given as 
+they are, or as either end of a range.  A script without any 
+scene numbers is numbered in order, as $1--scene generate$0 
+would, and the numbers are printed on the pages.  A script that 
+numbers only some of its scenes is an error, as is asking for a 
+number that more than one scene has.
+
+The script is laid out exactly as $1render$0 would lay it out, 
+and every page keeps its original page number and scene 
+numbers.  Pages without any of the chosen scenes are left out.  
+Anything from another scene that shares a page with a chosen 
+one is crossed through.
+
+The $1--format$0, $1--paper$0 and $1--scene$0 flags work as 
+they do for $1render$0.
+`
+		case "stripboard":
+			return `
+$1Stripboard Usage$0
+----------------
+
+    meander $1stripboard$0 input.fountain [output.pdf] [--order 
+strips.txt]
+
+Stripboard prints a production strip for every scene, coloured 
+by the usual convention:
+
+    INT. DAY      white
+    EXT. DAY      yellow
+    INT. NIGHT    blue
+    EXT. NIGHT    green
+
+INT/EXT scenes count as interiors and EXT/INT scenes as 
+exteriors.  A scene whose time of day isn't clearly day or 
+night, such as CONTINUOUS or LATER, takes its colour from the 
+scene before it.
+
+Each strip shows the scene number, heading, length in eighths 
+of a page and the cast ID numbers of everyone who speaks in it. 
+ Cast IDs are each character's position in the character list, 
+so characters in the gender table come first in the order 
+they're written there.  The key to the numbers is printed after 
+the strips.
+
+$1Strip Order$0
+-----------
+
+The $1--order$0 flag names a plain-text file that sets the 
+order of the strips.  If the file doesn't exist yet, Meander 
+writes it for you in script order, ready to be rearranged:
+
+    # lines starting with # are ignored
+    = Day 1
+    @12   12    EXT. ROOF - DAY
+    @3    3     INT. KITCHEN - NIGHT
+    = End of Day 1
+    @4    4     INT. CAR - NIGHT
+
+The first word of each line is the scene's position in the 
+script, counting from one, such as @12.  The number after it is 
+checked against the script: if scenes have been added or taken 
+out since the file was made, and the scene at that position now 
+has a different number, Meander follows the number instead and 
+tells you the scene has moved.  The heading is only there to 
+remind you which scene it is.  A scene can also be given by its 
+number alone, as long as no other scene has the same one.  
+Lines starting with = become black banner strips.
+
+A script without any scene numbers is numbered in order, as 
+$1--scene generate$0 would.  If only some scenes have numbers, 
+Meander stops and asks for them all to be numbered, so that a 
+position can't be mistaken for a forced number.
+
+Any scene left out of the file is put at the end of the board 
+under a "Not Scheduled" banner, so nothing goes missing when 
+the script changes.
+
+The $1--format$0, $1--paper$0 and $1--scene$0 flags work as 
+they do for $1render$0.
+`
+		case "words":
+			return `
+$1Words Usage$0
+-----------
+
+    meander $1words$0 input.fountain
+
+Words lists the word count of every section and scene in 
+outline order, each indented beneath the sections it falls in, 
+and the total for the whole document.  A section's count 
+includes everything inside it, up to the next section at the 
+same level or above.
+
+Alongside the full count are two narrower ones —
+
+    + dialogue, which is dialogue and lyrics
+    + narration, which is action and centered
+      text
+
+Character names, scene headings, parentheticals and transitions 
+count towards the full count but neither of the others.
+
+The same counts are available in headers and footers as 
+$1#SECTIONWORDS$0, $1#SCENEWORDS$0, $1#DIALOGUEWORDS$0 and 
+$1#NARRATIONWORDS$0.  See $1meander help fountain$0 for more on 
+counters.
 `
 	}
 	return ""
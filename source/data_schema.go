@@ -0,0 +1,15 @@
+/*
+	Meander
+	A portable Fountain utility for production writing
+	Copyright (C) 2022-2023 Harley Denham
+*/
+
+package main
+
+import _ "embed"
+
+// the JSON Schema for the output of the data command,
+// printed by "meander data --schema".  its version
+// has to be kept in step with DATA_VERSION.
+//go:embed data_schema.json
+var DATA_SCHEMA string
@@ -0,0 +1,525 @@
+/*
+	Meander
+	A portable Fountain utility for production writing
+	Copyright (C) 2022-2023 Harley Denham
+*/
+
+package main
+
+import "fmt"
+import "math"
+import "bytes"
+import "strings"
+import "archive/zip"
+import "encoding/xml"
+import "path/filepath"
+
+import "github.com/lichendust/meander/fountain"
+
+const DOCX_EXT = ".docx"
+
+const (
+	DOCX_MAIN = "http://schemas.openxmlformats.org/wordprocessingml/2006/main"
+	DOCX_RELS = "http://schemas.openxmlformats.org/officeDocument/2006/relationships"
+	DOCX_PACK = "http://schemas.openxmlformats.org/package/2006/relationships"
+)
+
+const DOCX_CONTENT_TYPES = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+	<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+	<Default Extension="xml" ContentType="application/xml"/>
+	<Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>
+	<Override PartName="/word/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.styles+xml"/>
+	<Override PartName="/word/header1.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.header+xml"/>
+	<Override PartName="/word/footer1.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.footer+xml"/>
+	<Override PartName="/docProps/core.xml" ContentType="application/vnd.openxmlformats-package.core-properties+xml"/>
+</Types>
+`
+
+const DOCX_PACKAGE_RELS = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="` + DOCX_PACK + `">
+	<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/>
+	<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/package/2006/relationships/metadata/core-properties" Target="docProps/core.xml"/>
+</Relationships>
+`
+
+const DOCX_DOCUMENT_RELS = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="` + DOCX_PACK + `">
+	<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>
+	<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/header" Target="header1.xml"/>
+	<Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/footer" Target="footer1.xml"/>
+</Relationships>
+`
+
+// export_docx writes a manuscript as a Word document.  each
+// type of element gets a paragraph style built from the
+// template, so the document can be restyled in Word itself
+func export_docx(config *Config) {
+	data, success := parse_file(config)
+	if !success {
+		return
+	}
+
+	if !is_manuscript(data, "DOCX") {
+		return
+	}
+
+	vet_template(data.template)
+	prepare_export(data)
+
+	title := rewrite_ext(filepath.Base(config.source_file), "")
+	if data.Title.Title != "" {
+		title = plain_text(data, data.Title.Title)
+	}
+
+	buffer := new(bytes.Buffer)
+	writer := zip.NewWriter(buffer)
+
+	write := func(name, content string) bool {
+		file, err := writer.Create(name)
+		if err != nil {
+			return false
+		}
+		_, err = file.Write([]byte(content))
+		return err == nil
+	}
+
+	success = write("[Content_Types].xml", DOCX_CONTENT_TYPES)
+	success = success && write("_rels/.rels", DOCX_PACKAGE_RELS)
+	success = success && write("word/_rels/document.xml.rels", DOCX_DOCUMENT_RELS)
+	success = success && write("word/styles.xml", docx_styles(data.template))
+	success = success && write("word/document.xml", docx_document(data))
+	success = success && write("word/header1.xml", docx_header(data, data.header, "hdr"))
+	success = success && write("word/footer1.xml", docx_header(data, data.footer, "ftr"))
+	success = success && write("docProps/core.xml", docx_core(data, title))
+
+	if !success || writer.Close() != nil {
+		eprintln("failed to build", config.output_file)
+		return
+	}
+
+	success = write_file(fix_path(config.output_file), buffer.Bytes())
+	if !success {
+		eprintln("failed to write", config.output_file)
+	}
+}
+
+func docx_document(data *Fountain) string {
+	template := data.template
+
+	buffer := strings.Builder{}
+	buffer.Grow(len(data.Content) * 256)
+
+	buffer.WriteString(xml.Header)
+	fmt.Fprintf(&buffer, "<w:document xmlns:w=\"%s\" xmlns:r=\"%s\">\n<w:body>\n", DOCX_MAIN, DOCX_RELS)
+
+	has_title := data.Title.HasAny && docx_title_page(&buffer, data)
+
+	new_page := false
+	gap      := 0
+
+	for i := range data.Content {
+		section := &data.Content[i]
+
+		switch section.Type {
+		case WHITESPACE:
+			gap += section.Level
+			continue
+		case PAGE_BREAK:
+			new_page = true
+			gap = 0
+			continue
+		}
+
+		if section.Type < is_printable {
+			continue
+		}
+
+		section_type := section.Type
+		if section_type == SECTION {
+			section_type += Section_Type(section.Level - 1)
+		}
+
+		t := &template.types[section_type]
+		if t.skip {
+			continue
+		}
+
+		buffer.WriteString("<w:p><w:pPr>")
+		fmt.Fprintf(&buffer, "<w:pStyle w:val=\"%s\"/>", section_type)
+		if new_page {
+			buffer.WriteString("<w:pageBreakBefore/>")
+		}
+		if gap > 0 && !template.ignore_whitespace {
+			fmt.Fprintf(&buffer, "<w:spacing w:before=\"%d\"/>", twips(template.line_height * float64(gap) + t.space_above))
+		}
+		buffer.WriteString("</w:pPr>")
+
+		if t.casing == LOWERCASE {
+			the_copy := *section
+			the_copy.Text = strings.ToLower(the_copy.Text)
+			section = &the_copy
+		}
+
+		docx_runs(&buffer, data, section_spans(data, section))
+
+		buffer.WriteString("</w:p>\n")
+
+		new_page = false
+		gap = 0
+	}
+
+	// the section properties set up the page itself
+	buffer.WriteString("<w:sectPr>")
+	buffer.WriteString("<w:headerReference w:type=\"default\" r:id=\"rId2\"/>")
+	buffer.WriteString("<w:footerReference w:type=\"default\" r:id=\"rId3\"/>")
+
+	if template.landscape {
+		fmt.Fprintf(&buffer, "<w:pgSz w:w=\"%d\" w:h=\"%d\" w:orient=\"landscape\"/>", twips(template.paper.W), twips(template.paper.H))
+	} else {
+		fmt.Fprintf(&buffer, "<w:pgSz w:w=\"%d\" w:h=\"%d\"/>", twips(template.paper.W), twips(template.paper.H))
+	}
+
+	fmt.Fprintf(&buffer, "<w:pgMar w:top=\"%d\" w:right=\"%d\" w:bottom=\"%d\" w:left=\"%d\" w:header=\"%d\" w:footer=\"%d\" w:gutter=\"0\"/>",
+		twips(template.margin_top - LINE_HEIGHT),
+		twips(template.paper.W - template.margin_right),
+		twips(template.margin_bottom),
+		twips(template.margin_left),
+		twips(math.Max(template.header_margin - LINE_HEIGHT, 0)),
+		twips(math.Max(template.paper.H - template.footer_margin - LINE_HEIGHT, 0)),
+	)
+
+	// the title page gets its own, empty, header and footer
+	if has_title {
+		buffer.WriteString("<w:titlePg/>")
+	}
+
+	buffer.WriteString("</w:sectPr>\n</w:body>\n</w:document>\n")
+
+	return buffer.String()
+}
+
+// docx_title_page writes a manuscript title page: the contact
+// details in the top corner and the title, credit and author
+// centred halfway down, followed by a page break
+func docx_title_page(buffer *strings.Builder, data *Fountain) bool {
+	paragraph := func(text, justify string, space_above float64) {
+		for i, line := range text_spans(data, text) {
+			buffer.WriteString("<w:p><w:pPr>")
+			if i == 0 && space_above > 0 {
+				fmt.Fprintf(buffer, "<w:spacing w:before=\"%d\"/>", twips(space_above))
+			}
+			fmt.Fprintf(buffer, "<w:jc w:val=\"%s\"/></w:pPr>", justify)
+			docx_runs(buffer, data, [][]Span{line})
+			buffer.WriteString("</w:p>\n")
+		}
+	}
+
+	has_any := false
+
+	for _, text := range [...]string{
+		data.Title.Contact,
+		data.Title.Notes,
+		data.Title.Copyright,
+	} {
+		if text != "" {
+			paragraph(text, "left", 0)
+			has_any = true
+		}
+	}
+
+	space := (data.template.paper.H - data.template.margin_top - data.template.margin_bottom) / 3
+	if !has_any {
+		space += LINE_HEIGHT
+	}
+
+	for _, entry := range [...]struct{
+		text  string
+		space float64
+	}{
+		{data.Title.Title,  space},
+		{data.Title.Credit, LINE_HEIGHT * 2},
+		{data.Title.Author, LINE_HEIGHT},
+		{data.Title.Source, LINE_HEIGHT * 2},
+	} {
+		if entry.text != "" {
+			paragraph(entry.text, "center", entry.space)
+			has_any = true
+			space = 0
+		}
+	}
+
+	for _, text := range [...]string{
+		data.Title.Revision,
+		data.Title.DraftDate,
+		data.Title.Info,
+	} {
+		if text != "" {
+			paragraph(text, "right", LINE_HEIGHT * 2)
+			has_any = true
+		}
+	}
+
+	if has_any {
+		buffer.WriteString("<w:p><w:r><w:br w:type=\"page\"/></w:r></w:p>\n")
+	}
+
+	return has_any
+}
+
+// docx_runs writes each span as a run, with a line
+// break between the lines of a section
+func docx_runs(buffer *strings.Builder, data *Fountain, lines [][]Span) {
+	for i, line := range lines {
+		if i > 0 {
+			buffer.WriteString("<w:r><w:br/></w:r>")
+		}
+
+		for _, span := range line {
+			buffer.WriteString("<w:r>")
+
+			if span.style & (span_styles | UNDERLINE | STRIKEOUT | HIGHLIGHT) != 0 {
+				buffer.WriteString("<w:rPr>")
+				docx_run_style(buffer, data.template, span.style)
+				buffer.WriteString("</w:rPr>")
+			}
+
+			buffer.WriteString("<w:t xml:space=\"preserve\">")
+			xml.EscapeText(buffer, []byte(span.text))
+			buffer.WriteString("</w:t></w:r>")
+		}
+	}
+}
+
+// run properties have to come in the order the schema
+// lists them, or Word refuses to open the file
+func docx_run_style(buffer *strings.Builder, template *Template, style Leaf_Type) {
+	if style & BOLD      != 0 { buffer.WriteString("<w:b/>") }
+	if style & ITALIC    != 0 { buffer.WriteString("<w:i/>") }
+	if style & STRIKEOUT != 0 { buffer.WriteString("<w:strike/>") }
+	if style & NOTE      != 0 { fmt.Fprintf(buffer, "<w:color w:val=\"%s\"/>", docx_color(template.note_color)) }
+	if style & UNDERLINE != 0 { buffer.WriteString("<w:u w:val=\"single\"/>") }
+	if style & HIGHLIGHT != 0 { fmt.Fprintf(buffer, "<w:shd w:val=\"clear\" w:color=\"auto\" w:fill=\"%s\"/>", docx_color(template.highlight_color)) }
+}
+
+// docx_header lays out a header or footer in the same
+// "left | centre | right" form the renderer uses, with
+// #page as a live page number field and any other
+// counters written out as their values
+func docx_header(data *Fountain, text, tag string) string {
+	template := data.template
+	width    := template.margin_right - template.margin_left
+
+	buffer := strings.Builder{}
+	buffer.WriteString(xml.Header)
+	fmt.Fprintf(&buffer, "<w:%s xmlns:w=\"%s\" xmlns:r=\"%s\">\n<w:p><w:pPr>", tag, DOCX_MAIN, DOCX_RELS)
+	fmt.Fprintf(&buffer, "<w:tabs><w:tab w:val=\"center\" w:pos=\"%d\"/><w:tab w:val=\"right\" w:pos=\"%d\"/></w:tabs>", twips(width / 2), twips(width))
+	buffer.WriteString("</w:pPr>")
+
+	split := strings.SplitN(text, "|", 3)
+	if text == "" {
+		split = nil
+	}
+
+	for i, part := range split {
+		// two parts are pinned to either side
+		if i > 0 {
+			buffer.WriteString("<w:r><w:tab/></w:r>")
+			if len(split) == 2 {
+				buffer.WriteString("<w:r><w:tab/></w:r>")
+			}
+		}
+		docx_header_part(&buffer, data, strings.TrimSpace(part))
+	}
+
+	fmt.Fprintf(&buffer, "</w:p>\n</w:%s>\n", tag)
+
+	return buffer.String()
+}
+
+func docx_header_part(buffer *strings.Builder, data *Fountain, text string) {
+	// everything up to a #page goes through the line
+	// breaker whole, so that other counters are filled
+	// in with their values, as they are when rendering
+	start := 0
+
+	for start < len(text) {
+		index := strings.IndexRune(text[start:], '#')
+		if index < 0 {
+			break
+		}
+		index += start
+
+		word, width := extract_ident(text[index + 1:])
+		if homogenise(word) != "page" {
+			start = index + 1
+			continue
+		}
+
+		if index > 0 {
+			docx_runs(buffer, data, text_spans(data, text[:index]))
+		}
+
+		buffer.WriteString("<w:r><w:fldChar w:fldCharType=\"begin\"/></w:r>")
+		buffer.WriteString("<w:r><w:instrText xml:space=\"preserve\"> PAGE </w:instrText></w:r>")
+		buffer.WriteString("<w:r><w:fldChar w:fldCharType=\"separate\"/></w:r>")
+		buffer.WriteString("<w:r><w:t>1</w:t></w:r>")
+		buffer.WriteString("<w:r><w:fldChar w:fldCharType=\"end\"/></w:r>")
+
+		text  = text[index + 1 + width:]
+		start = 0
+	}
+
+	if text != "" {
+		docx_runs(buffer, data, text_spans(data, text))
+	}
+}
+
+// docx_styles turns each template entry into a paragraph
+// style, named for its type
+func docx_styles(template *Template) string {
+	buffer := strings.Builder{}
+
+	line_height := template.line_height
+	if line_height == 0 {
+		line_height = LINE_HEIGHT
+	}
+
+	buffer.WriteString(xml.Header)
+	fmt.Fprintf(&buffer, "<w:styles xmlns:w=\"%s\">\n", DOCX_MAIN)
+
+	buffer.WriteString("<w:docDefaults><w:rPrDefault><w:rPr>")
+	buffer.WriteString("<w:rFonts w:ascii=\"Courier Prime\" w:hAnsi=\"Courier Prime\" w:cs=\"Courier New\"/>")
+	fmt.Fprintf(&buffer, "<w:color w:val=\"%s\"/>", docx_color(template.text_color))
+	fmt.Fprintf(&buffer, "<w:sz w:val=\"%d\"/><w:szCs w:val=\"%d\"/>", int(FONT_SIZE * 2), int(FONT_SIZE * 2))
+	buffer.WriteString("</w:rPr></w:rPrDefault><w:pPrDefault><w:pPr>")
+	fmt.Fprintf(&buffer, "<w:spacing w:before=\"0\" w:after=\"0\" w:line=\"%d\" w:lineRule=\"exact\"/>", twips(line_height))
+	buffer.WriteString("</w:pPr></w:pPrDefault></w:docDefaults>\n")
+
+	buffer.WriteString("<w:style w:type=\"paragraph\" w:default=\"1\" w:styleId=\"Normal\"><w:name w:val=\"Normal\"/></w:style>\n")
+
+	width := template.margin_right - template.margin_left
+
+	for i := is_printable + 1; i < TYPE_COUNT; i++ {
+		if i == is_section || i == fountain.BEGIN_CHARACTER || i == fountain.END_CHARACTER {
+			continue
+		}
+
+		t := &template.types[i]
+		if t.skip {
+			continue
+		}
+
+		name := title_case(strings.ReplaceAll(i.String(), "_", " "))
+
+		fmt.Fprintf(&buffer, "<w:style w:type=\"paragraph\" w:customStyle=\"1\" w:styleId=\"%s\">", i)
+		fmt.Fprintf(&buffer, "<w:name w:val=\"%s\"/><w:basedOn w:val=\"Normal\"/><w:qFormat/>", name)
+
+		buffer.WriteString("<w:pPr>")
+
+		switch i {
+		case SCENE, SECTION, SECTION2, SECTION3, CHARACTER, DUAL_CHARACTER, PARENTHETICAL, DUAL_PARENTHETICAL:
+			buffer.WriteString("<w:keepNext/>")
+		}
+
+		// exact line heights keep double-spaced
+		// manuscripts spaced as they are on paper
+		this_height := t.line_height
+		if this_height == 0 {
+			this_height = line_height
+		}
+		fmt.Fprintf(&buffer, "<w:spacing w:before=\"%d\" w:line=\"%d\" w:lineRule=\"exact\"/>", twips(t.space_above), twips(this_height))
+
+		left  := 0.0
+		right := 0.0
+
+		switch t.justify {
+		default:
+			left = t.margin
+			if t.width > 0 {
+				right = width - t.margin - t.width
+			}
+		case RIGHT:
+			right = t.margin
+			if t.width > 0 {
+				left = width - t.margin - t.width
+			}
+		case CENTER:
+			if t.width > 0 {
+				left  = (width - t.width) / 2
+				right = left
+			}
+		}
+
+		fmt.Fprintf(&buffer, "<w:ind w:left=\"%d\" w:right=\"%d\"", twips(math.Max(left, 0)), twips(math.Max(right, 0)))
+		if t.para_indent > 0 {
+			fmt.Fprintf(&buffer, " w:firstLine=\"%d\"", twips(float64(t.para_indent) * CHAR_WIDTH))
+		}
+		buffer.WriteString("/>")
+
+		switch t.justify {
+		case RIGHT:
+			buffer.WriteString("<w:jc w:val=\"right\"/>")
+		case CENTER:
+			buffer.WriteString("<w:jc w:val=\"center\"/>")
+		}
+
+		switch i {
+		case SECTION:
+			buffer.WriteString("<w:outlineLvl w:val=\"0\"/>")
+		case SECTION2:
+			buffer.WriteString("<w:outlineLvl w:val=\"1\"/>")
+		case SECTION3:
+			buffer.WriteString("<w:outlineLvl w:val=\"2\"/>")
+		}
+
+		buffer.WriteString("</w:pPr>")
+
+		if t.style & (BOLD | ITALIC | STRIKEOUT | UNDERLINE | HIGHLIGHT) != 0 || t.casing == UPPERCASE {
+			buffer.WriteString("<w:rPr>")
+			if t.style & BOLD   != 0 { buffer.WriteString("<w:b/>") }
+			if t.style & ITALIC != 0 { buffer.WriteString("<w:i/>") }
+			if t.casing == UPPERCASE { buffer.WriteString("<w:caps/>") }
+			if t.style & STRIKEOUT != 0 { buffer.WriteString("<w:strike/>") }
+			if t.style & UNDERLINE != 0 { buffer.WriteString("<w:u w:val=\"single\"/>") }
+			if t.style & HIGHLIGHT != 0 { fmt.Fprintf(&buffer, "<w:shd w:val=\"clear\" w:color=\"auto\" w:fill=\"%s\"/>", docx_color(template.highlight_color)) }
+			buffer.WriteString("</w:rPr>")
+		}
+
+		buffer.WriteString("</w:style>\n")
+	}
+
+	buffer.WriteString("</w:styles>\n")
+
+	return buffer.String()
+}
+
+func docx_core(data *Fountain, title string) string {
+	buffer := strings.Builder{}
+	buffer.WriteString(xml.Header)
+	buffer.WriteString("<cp:coreProperties xmlns:cp=\"http://schemas.openxmlformats.org/package/2006/metadata/core-properties\" xmlns:dc=\"http://purl.org/dc/elements/1.1/\" xmlns:dcterms=\"http://purl.org/dc/terms/\" xmlns:xsi=\"http://www.w3.org/2001/XMLSchema-instance\">\n")
+
+	buffer.WriteString("<dc:title>")
+	xml.EscapeText(&buffer, []byte(title))
+	buffer.WriteString("</dc:title>\n")
+
+	if author := plain_text(data, data.Title.Author); author != "" {
+		buffer.WriteString("<dc:creator>")
+		xml.EscapeText(&buffer, []byte(author))
+		buffer.WriteString("</dc:creator>\n")
+	}
+
+	fmt.Fprintf(&buffer, "<dcterms:created xsi:type=\"dcterms:W3CDTF\">%s</dcterms:created>\n", now().UTC().Format("2006-01-02T15:04:05Z"))
+	buffer.WriteString("</cp:coreProperties>\n")
+
+	return buffer.String()
+}
+
+// Word measures most things in twentieths of a point
+func twips(x float64) int {
+	return int(math.Round(x * 20))
+}
+
+func docx_color(c Color) string {
+	return fmt.Sprintf("%02X%02X%02X", c.R, c.G, c.B)
+}
@@ -0,0 +1,450 @@
+/*
+	Meander
+	A portable Fountain utility for production writing
+	Copyright (C) 2022-2023 Harley Denham
+*/
+
+package main
+
+import "fmt"
+import "html"
+import "bytes"
+import "strings"
+import "archive/zip"
+import "crypto/sha1"
+import "encoding/xml"
+import "path/filepath"
+
+import "github.com/lichendust/meander/fountain"
+
+const EPUB_EXT = ".epub"
+
+// there's no way to set a script's language yet
+const EPUB_LANGUAGE = "en"
+
+const EPUB_CONTAINER = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+	<rootfiles>
+		<rootfile full-path="EPUB/package.opf" media-type="application/oebps-package+xml"/>
+	</rootfiles>
+</container>
+`
+
+type Epub_Package struct {
+	XMLName  xml.Name      `xml:"http://www.idpf.org/2007/opf package"`
+	Version  string        `xml:"version,attr"`
+	UniqueID string        `xml:"unique-identifier,attr"`
+	Language string        `xml:"xml:lang,attr"`
+	Metadata Epub_Metadata `xml:"metadata"`
+	Manifest []Epub_Item   `xml:"manifest>item"`
+	Spine    []Epub_Ref    `xml:"spine>itemref"`
+}
+
+type Epub_Metadata struct {
+	DC         string      `xml:"xmlns:dc,attr"`
+	Identifier Epub_ID     `xml:"dc:identifier"`
+	Title      string      `xml:"dc:title"`
+	Creator    string      `xml:"dc:creator,omitempty"`
+	Rights     string      `xml:"dc:rights,omitempty"`
+	Language   string      `xml:"dc:language"`
+	Meta       []Epub_Meta `xml:"meta"`
+}
+
+type Epub_ID struct {
+	ID   string `xml:"id,attr"`
+	Text string `xml:",chardata"`
+}
+
+type Epub_Meta struct {
+	Property string `xml:"property,attr"`
+	Text     string `xml:",chardata"`
+}
+
+type Epub_Item struct {
+	ID         string `xml:"id,attr"`
+	Href       string `xml:"href,attr"`
+	MediaType  string `xml:"media-type,attr"`
+	Properties string `xml:"properties,attr,omitempty"`
+}
+
+type Epub_Ref struct {
+	IDRef string `xml:"idref,attr"`
+}
+
+type Epub_Chapter struct {
+	file  string
+	title string
+	body  strings.Builder
+}
+
+// an entry in the navigation document
+type Epub_Heading struct {
+	level int
+	text  string
+	href  string
+}
+
+// export_epub writes a reflowable EPUB 3 book from a
+// manuscript.  each top-level section starts a chapter,
+// and the sections beneath it make up the contents
+func export_epub(config *Config) {
+	data, success := parse_file(config)
+	if !success {
+		return
+	}
+
+	if !is_manuscript(data, "EPUB") {
+		return
+	}
+
+	vet_template(data.template)
+	prepare_export(data)
+
+	title := rewrite_ext(filepath.Base(config.source_file), "")
+	if data.Title.Title != "" {
+		title = plain_text(data, data.Title.Title)
+	}
+
+	chapters, headings := epub_chapters(data, title)
+
+	buffer := new(bytes.Buffer)
+	writer := zip.NewWriter(buffer)
+
+	write := func(name string, content []byte, method uint16) bool {
+		file, err := writer.CreateHeader(&zip.FileHeader{
+			Name:     name,
+			Method:   method,
+			Modified: now(),
+		})
+		if err != nil {
+			return false
+		}
+		_, err = file.Write(content)
+		return err == nil
+	}
+
+	// the mimetype has to come first and be left
+	// uncompressed, so readers can sniff it
+	success = write("mimetype", []byte("application/epub+zip"), zip.Store)
+	success = success && write("META-INF/container.xml", []byte(EPUB_CONTAINER), zip.Deflate)
+	success = success && write("EPUB/style.css", []byte(epub_stylesheet(data.template)), zip.Deflate)
+	success = success && write("EPUB/nav.xhtml", []byte(epub_nav(title, headings)), zip.Deflate)
+
+	pack := epub_package(data, title)
+
+	if data.Title.HasAny {
+		body := strings.Builder{}
+		epub_title_page(&body, data)
+
+		success = success && write("EPUB/title.xhtml", []byte(epub_document(title, body.String())), zip.Deflate)
+
+		pack.Manifest = append(pack.Manifest, Epub_Item{ID: "title", Href: "title.xhtml", MediaType: "application/xhtml+xml"})
+		pack.Spine    = append(pack.Spine, Epub_Ref{"title"})
+	}
+
+	for i, chapter := range chapters {
+		id := fmt.Sprintf("chapter_%03d", i + 1)
+
+		success = success && write("EPUB/" + chapter.file, []byte(epub_document(chapter.title, chapter.body.String())), zip.Deflate)
+
+		pack.Manifest = append(pack.Manifest, Epub_Item{ID: id, Href: chapter.file, MediaType: "application/xhtml+xml"})
+		pack.Spine    = append(pack.Spine, Epub_Ref{id})
+	}
+
+	blob, err := xml.MarshalIndent(pack, "", "\t")
+	if err != nil {
+		eprintln("failed to marshal", config.output_file)
+		return
+	}
+	blob = append([]byte(xml.Header), blob...)
+
+	success = success && write("EPUB/package.opf", blob, zip.Deflate)
+
+	if !success || writer.Close() != nil {
+		eprintln("failed to build", config.output_file)
+		return
+	}
+
+	success = write_file(fix_path(config.output_file), buffer.Bytes())
+	if !success {
+		eprintln("failed to write", config.output_file)
+	}
+}
+
+// epub_chapters splits the content at each top-level section
+// and collects every section heading for the contents
+func epub_chapters(data *Fountain, title string) ([]*Epub_Chapter, []Epub_Heading) {
+	chapters := make([]*Epub_Chapter, 0, 32)
+	headings := make([]Epub_Heading, 0, 32)
+
+	var chapter *Epub_Chapter
+	has_text := false
+	gap      := 0
+
+	new_chapter := func(heading string) {
+		// anything before the first section still
+		// needs a home, so it gets the book's title
+		if chapter != nil && !has_text && chapter.title == title {
+			chapter.title = heading
+			return
+		}
+		chapter = &Epub_Chapter{
+			file:  fmt.Sprintf("chapter_%03d.xhtml", len(chapters) + 1),
+			title: heading,
+		}
+		chapters = append(chapters, chapter)
+		has_text = false
+	}
+
+	new_chapter(title)
+
+	for i := range data.Content {
+		section := &data.Content[i]
+
+		switch section.Type {
+		case WHITESPACE:
+			gap += section.Level
+			continue
+		case PAGE_BREAK:
+			chapter.body.WriteString("<hr class=\"page_break\"/>\n")
+			gap = 0
+			continue
+		}
+
+		if section.Type < is_printable {
+			continue
+		}
+
+		section_type := section.Type
+		if section_type == SECTION {
+			section_type += Section_Type(section.Level - 1)
+		}
+
+		t := &data.template.types[section_type]
+
+		if section.Type == SECTION {
+			heading := plain_text(data, section.Text)
+
+			if section.Level == 1 {
+				new_chapter(heading)
+			}
+
+			href := chapter.file
+			if !t.skip {
+				href += fmt.Sprintf("#heading_%d", len(headings) + 1)
+			}
+			headings = append(headings, Epub_Heading{section.Level, heading, href})
+		}
+
+		if t.skip {
+			continue
+		}
+
+		tag   := "p"
+		class := section_type.String()
+		attr  := ""
+
+		switch section_type {
+		case SECTION, SECTION2, SECTION3:
+			tag  = fmt.Sprintf("h%d", section.Level)
+			attr = fmt.Sprintf(" id=\"heading_%d\"", len(headings))
+		case SCENE:
+			tag = "h4"
+		}
+
+		if gap > 0 && !data.template.ignore_whitespace {
+			class += " gap"
+		}
+
+		fmt.Fprintf(&chapter.body, "<%s class=\"%s\"%s>", tag, class, attr)
+		html_lines(&chapter.body, data, section_spans(data, section))
+		fmt.Fprintf(&chapter.body, "</%s>\n", tag)
+
+		has_text = true
+		gap = 0
+	}
+
+	return chapters, headings
+}
+
+func epub_title_page(buffer *strings.Builder, data *Fountain) {
+	buffer.WriteString("<section class=\"title_page\" epub:type=\"titlepage\">\n")
+
+	if data.Title.Title != "" {
+		buffer.WriteString("<h1 class=\"title\">")
+		html_lines(buffer, data, text_spans(data, data.Title.Title))
+		buffer.WriteString("</h1>\n")
+	}
+
+	for _, entry := range [...]struct{
+		class string
+		text  string
+	}{
+		{"credit",     data.Title.Credit},
+		{"author",     data.Title.Author},
+		{"source",     data.Title.Source},
+		{"notes",      data.Title.Notes},
+		{"draft_date", data.Title.DraftDate},
+		{"copyright",  data.Title.Copyright},
+		{"contact",    data.Title.Contact},
+		{"info",       data.Title.Info},
+	} {
+		if entry.text == "" {
+			continue
+		}
+		buffer.WriteString("<p class=\"" + entry.class + "\">")
+		html_lines(buffer, data, text_spans(data, entry.text))
+		buffer.WriteString("</p>\n")
+	}
+
+	buffer.WriteString("</section>\n")
+}
+
+// epub_nav nests the section headings into lists by level
+func epub_nav(title string, headings []Epub_Heading) string {
+	buffer := strings.Builder{}
+	buffer.WriteString("<nav epub:type=\"toc\" id=\"toc\">\n<h1>Contents</h1>")
+
+	depth := 0
+
+	for _, heading := range headings {
+		level := heading.level
+		if level > depth + 1 {
+			level = depth + 1 // skipped levels are pulled up
+		}
+
+		switch {
+		case level > depth:
+			buffer.WriteString("\n<ol>\n")
+		default:
+			buffer.WriteString("</li>\n")
+			for ; depth > level; depth -= 1 {
+				buffer.WriteString("</ol>\n</li>\n")
+			}
+		}
+
+		depth = level
+		fmt.Fprintf(&buffer, "<li><a href=\"%s\">%s</a>", heading.href, html.EscapeString(heading.text))
+	}
+
+	for ; depth > 0; depth -= 1 {
+		buffer.WriteString("</li>\n</ol>\n")
+	}
+
+	// a reader needs something to point at, even
+	// if the manuscript has no sections at all
+	if len(headings) == 0 {
+		fmt.Fprintf(&buffer, "\n<ol>\n<li><a href=\"chapter_001.xhtml\">%s</a></li>\n</ol>\n", html.EscapeString(title))
+	}
+
+	buffer.WriteString("</nav>\n")
+
+	return epub_document(title, buffer.String())
+}
+
+func epub_document(title, body string) string {
+	buffer := strings.Builder{}
+	buffer.Grow(len(body) + 512)
+
+	buffer.WriteString(xml.Header)
+	buffer.WriteString("<!DOCTYPE html>\n")
+	fmt.Fprintf(&buffer, "<html xmlns=\"http://www.w3.org/1999/xhtml\" xmlns:epub=\"http://www.idpf.org/2007/ops\" lang=\"%s\" xml:lang=\"%s\">\n", EPUB_LANGUAGE, EPUB_LANGUAGE)
+	buffer.WriteString("<head>\n<meta charset=\"utf-8\"/>\n")
+	buffer.WriteString("<title>" + html.EscapeString(title) + "</title>\n")
+	buffer.WriteString("<link rel=\"stylesheet\" type=\"text/css\" href=\"style.css\"/>\n")
+	buffer.WriteString("</head>\n<body>\n")
+	buffer.WriteString(body)
+	buffer.WriteString("</body>\n</html>\n")
+
+	return buffer.String()
+}
+
+func epub_package(data *Fountain, title string) *Epub_Package {
+	pack := &Epub_Package{
+		Version:  "3.0",
+		UniqueID: "book_id",
+		Language: EPUB_LANGUAGE,
+		Metadata: Epub_Metadata{
+			DC:         "http://purl.org/dc/elements/1.1/",
+			Identifier: Epub_ID{"book_id", epub_identifier(data, title)},
+			Title:      title,
+			Creator:    plain_text(data, data.Title.Author),
+			Rights:     plain_text(data, data.Title.Copyright),
+			Language:   EPUB_LANGUAGE,
+			Meta: []Epub_Meta{
+				{"dcterms:modified", now().UTC().Format("2006-01-02T15:04:05Z")},
+			},
+		},
+		Manifest: []Epub_Item{
+			{ID: "nav",   Href: "nav.xhtml", MediaType: "application/xhtml+xml", Properties: "nav"},
+			{ID: "style", Href: "style.css", MediaType: "text/css"},
+		},
+	}
+
+	return pack
+}
+
+// the identifier is made from the title and author, so
+// a new draft replaces the old one on a reader rather
+// than sitting alongside it
+func epub_identifier(data *Fountain, title string) string {
+	hash := sha1.Sum([]byte(title + "\x00" + data.Title.Author))
+
+	hash[6] = hash[6] & 0x0f | 0x50 // version 5
+	hash[8] = hash[8] & 0x3f | 0x80
+
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", hash[0:4], hash[4:6], hash[6:8], hash[8:10], hash[10:16])
+}
+
+// epub_stylesheet keeps the parts of the template that make
+// sense when the reader picks the font, size and margins
+func epub_stylesheet(template *Template) string {
+	buffer := strings.Builder{}
+
+	buffer.WriteString("p, h1, h2, h3, h4 { margin: 0; font-size: 1em; font-weight: normal; }\n")
+	buffer.WriteString(".title_page { text-align: center; margin-top: 30%; }\n")
+	buffer.WriteString(".title_page .title { font-size: 1.5em; margin-bottom: 2em; }\n")
+	buffer.WriteString(".title_page p { margin-top: 1em; }\n")
+	buffer.WriteString(".page_break { border: none; margin: 0; page-break-after: always; break-after: page; }\n")
+	buffer.WriteString(".gap { margin-top: 1em; }\n")
+	fmt.Fprintf(&buffer, ".note { color: %s; }\n", css_color(template.note_color))
+	fmt.Fprintf(&buffer, "mark { color: inherit; background: %s; }\n", css_color(template.highlight_color))
+
+	for i := is_printable + 1; i < TYPE_COUNT; i++ {
+		if i == is_section || i == fountain.BEGIN_CHARACTER || i == fountain.END_CHARACTER {
+			continue
+		}
+
+		t := &template.types[i]
+		if t.skip {
+			continue
+		}
+
+		rules := make([]string, 0, 8)
+
+		switch t.justify {
+		case RIGHT:
+			rules = append(rules, "text-align: right")
+		case CENTER:
+			rules = append(rules, "text-align: center")
+		}
+
+		if t.space_above > 0 {
+			rules = append(rules, fmt.Sprintf("margin-top: %.4gem", t.space_above / PICA))
+		}
+		if t.para_indent > 0 {
+			rules = append(rules, fmt.Sprintf("text-indent: %.4gem", float64(t.para_indent) / 2))
+		}
+
+		rules = css_text_rules(template, t, rules)
+
+		if len(rules) > 0 {
+			fmt.Fprintf(&buffer, ".%s { %s; }\n", i, strings.Join(rules, "; "))
+		}
+	}
+
+	// chapters start on a new page
+	buffer.WriteString("h1.section { page-break-before: always; break-before: page; }\n")
+
+	return buffer.String()
+}
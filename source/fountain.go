@@ -6,39 +6,68 @@
 
 package main
 
-import "bytes"
+import "os"
 import "strings"
 import "strconv"
 import "unicode"
-import "unicode/utf8"
 import "path/filepath"
 
 import lib "github.com/signintech/gopdf"
 
+import "github.com/lichendust/meander/fountain"
+
+const DATA_VERSION = fountain.DATA_VERSION
+
+type Section_Type = fountain.SectionType
+type Character    = fountain.Character
+type Title_Page   = fountain.TitlePage
+type Source_File  = fountain.SourceFile
+
+const (
+	WHITESPACE         = fountain.WHITESPACE
+	PAGE_BREAK         = fountain.PAGE_BREAK
+	HEADER             = fountain.HEADER
+	FOOTER             = fountain.FOOTER
+	is_printable       = fountain.IS_PRINTABLE
+	ACTION             = fountain.ACTION
+	SCENE              = fountain.SCENE
+	CHARACTER          = fountain.CHARACTER
+	DUAL_CHARACTER     = fountain.DUAL_CHARACTER
+	PARENTHETICAL      = fountain.PARENTHETICAL
+	DUAL_PARENTHETICAL = fountain.DUAL_PARENTHETICAL
+	DIALOGUE           = fountain.DIALOGUE
+	DUAL_DIALOGUE      = fountain.DUAL_DIALOGUE
+	LYRIC              = fountain.LYRIC
+	DUAL_LYRIC         = fountain.DUAL_LYRIC
+	TRANSITION         = fountain.TRANSITION
+	SYNOPSIS           = fountain.SYNOPSIS
+	CENTERED           = fountain.CENTERED
+	is_section         = fountain.IS_SECTION
+	SECTION            = fountain.SECTION
+	SECTION2           = fountain.SECTION2
+	SECTION3           = fountain.SECTION3
+	TYPE_COUNT         = fountain.TYPE_COUNT
+	TYPE_NONE          = fountain.TYPE_NONE
+)
+
+var is_character_train     = fountain.IsCharacterTrain
+var is_valid_character     = fountain.IsValidCharacter
+var is_valid_scene         = fountain.IsValidScene
+var is_valid_transition    = fountain.IsValidTransition
+var string_to_section_type = fountain.StringToSectionType
+var count_words            = fountain.CountWords
+
+// Fountain wraps the parsed document with everything
+// the layout engine and renderer need to track
 type Fountain struct {
-	Meta struct {
-		Source  string `json:"source"`
-		Version uint8  `json:"version"`
-	} `json:"meta"`
-
-	Title struct {
-		has_any   bool
-		Title     string `json:"title,omitempty"`
-		Credit    string `json:"credit,omitempty"`
-		Author    string `json:"author,omitempty"`
-		Source    string `json:"source,omitempty"`
-		Notes     string `json:"notes,omitempty"`
-		DraftDate string `json:"draft_date,omitempty"`
-		Copyright string `json:"copyright,omitempty"`
-		Revision  string `json:"revision,omitempty"`
-		Contact   string `json:"contact,omitempty"`
-		Info      string `json:"info,omitempty"`
-	} `json:"title"`
-
-	Characters []Character `json:"characters,omitempty"`
-	Content    []Section   `json:"content,omitempty"`
-
-	config *Config
+	Meta       fountain.Meta          `json:"meta"`
+	Title      Title_Page             `json:"title"`
+	Files      []Source_File          `json:"files,omitempty"`
+	Characters []Character            `json:"characters,omitempty"`
+	Content    []Section              `json:"content,omitempty"`
+
+	config   *Config
+	document *fountain.Document
 
 	template *Template
 
@@ -48,23 +77,23 @@ type Fountain struct {
 	more_tag string
 	cont_tag string
 
-	chars_lookup   map[string]int
 	counter_lookup map[string]*Counter
 
-	line_number int
-}
-
-type Character struct {
-	Name       string   `json:"name"`
-	Gender     string   `json:"gender"`
-	OtherNames []string `json:"other_names,omitempty"`
-	Lines      int      `json:"lines_spoken,omitempty"`
+	// problems found along the way; the
+	// renderer only records some of them
+	// when lint is set by the check command
+	lint        bool
+	diagnostics []fountain.Diagnostic
 }
 
 type Section struct {
+	fountain.Section
+
 	page   int
 	skip   bool
+	struck bool // crossed through, as on sides
 	is_raw bool
+	words  int // in the section or scene it heads
 
 	pos_x        float64
 	pos_y        float64
@@ -73,14 +102,12 @@ type Section struct {
 	para_indent  float64 // applies to first line only; added to margin
 	justify      uint8
 
-	Type        Section_Type `json:"type"`
-	Text        string       `json:"text,omitempty"`
-	SceneNumber string       `json:"scene_number,omitempty"`
-	Revision    string       `json:"revision,omitempty"`
-	Level       int          `json:"level,omitempty"`
-
 	longest_line int
 	lines []Line
+
+	// for raw sections, which have no lines to
+	// carry it; nil for no highlight
+	highlight_color *Color
 }
 
 /*
@@ -123,6 +150,8 @@ type Line struct {
 	underline []int
 	strikeout []int
 	highlight []int
+
+	highlight_color *Color // the template's colour if nil
 }
 
 type Leaf struct {
@@ -141,6 +170,7 @@ type Inline_Format struct {
 	could_close   bool
 	space_only    bool
 	counter_reset int
+	offset        int // in the section's text
 }
 
 type Counter struct {
@@ -148,843 +178,142 @@ type Counter struct {
 	value int
 }
 
-func init_data(config *Config) *Fountain {
-	data := new(Fountain)
-
-	data.Meta.Source  = MEANDER
-	data.Meta.Version = DATA_VERSION
-
-	if !config.paper_set {
-		config.paper_size = *lib.PageSizeLetter
+func parse_file(config *Config) (*Fountain, bool) {
+	file, err := os.Open(fix_path(config.source_file))
+	if err != nil {
+		eprintf("%q not found", config.source_file)
+		return nil, false
 	}
-	if !config.template_set {
-		config.template = SCREENPLAY
+	defer file.Close()
+
+	var doc *fountain.Document
+
+	// data from "meander data" can be laid
+	// out just like the script it came from
+	if filepath.Ext(config.source_file) == JSON_EXT {
+		doc, err = fountain.ReadJSON(file)
+	} else {
+		doc, err = fountain.Parse(file, fountain.Options{
+			Path:         config.source_file,
+			IncludeNotes: config.include_notes,
+		})
 	}
-
-	data.config = config
-	return data
-}
-
-type Section_Type uint8
-const (
-	WHITESPACE Section_Type = iota
-
-	PAGE_BREAK
-
-	HEADER
-	FOOTER
-
-	is_printable
-
-	ACTION
-	SCENE
-
-	begin_character
-	CHARACTER
-	DUAL_CHARACTER
-	PARENTHETICAL
-	DUAL_PARENTHETICAL
-	DIALOGUE
-	DUAL_DIALOGUE
-	LYRIC
-	DUAL_LYRIC
-	end_character
-
-	TRANSITION
-	SYNOPSIS
-	CENTERED
-
-	is_section
-
-	SECTION
-	SECTION2
-	SECTION3
-
-	TYPE_COUNT
-	TYPE_NONE
-)
-
-func (x Section_Type) MarshalJSON() ([]byte, error) {
-	buffer := new(bytes.Buffer)
-	buffer.Grow(32)
-
-	buffer.WriteRune('"')
-	buffer.WriteString(x.String())
-	buffer.WriteRune('"')
-
-	return buffer.Bytes(), nil
-}
-
-func syntax_parser(config *Config, data *Fountain, text string) {
-	data.chars_lookup   = make(map[string]int, 32)
-	data.counter_lookup = make(map[string]*Counter, 32)
-	data.Characters     = make([]Character, 0, 32) // we pre-empt needing these
-
-	// only remove newlines in case the first
-	// element is indented action
-	text = consume_newlines(text)
-
-	// @todo line_number needs to be supported for maths
-
-	// title page mini-parser
-	for {
-		n, success := find_title_colon(text)
-		if !success {
-			break
-		}
-
-		word := homogenise(text[:n])
-		text = text[n + 1:]
-
-		word = homogenise(strings.TrimSpace(word))
-
-		title_buffer := strings.Builder{}
-		title_buffer.Grow(64)
-
-		break_main_loop := false
-
-		// begin parsing
-		for {
-			// grab the first line manually
-			line := extract_to_newline(text)
-			text = text[len(line):] // consume the line
-
-			title_buffer.WriteString(strings.TrimSpace(line))
-
-			if len(text) == 0 {
-				break
-			}
-
-			if text[0] == '\n' {
-				text = text[1:] // consume the newline
-
-				if len(text) == 0 {
-					break
-				}
-
-				if len(text) > 0 && text[0] == '\n' {
-					break_main_loop = true
-					break
-				}
-				if !unicode.IsSpace(rune(text[0])) {
-					break
-				}
-
-				sub_line := extract_to_newline(text)
-				text = text[len(sub_line):]
-
-				title_buffer.WriteRune('\n')
-				title_buffer.WriteString(strings.TrimSpace(sub_line))
-			}
-		}
-
-		sub_line := left_trim(title_buffer.String())
-
-		if sub_line != "" {
-			switch word {
-			case "title":
-				data.Title.Title = sub_line
-				data.Title.has_any = true
-			case "credit":
-				data.Title.Credit = sub_line
-				data.Title.has_any = true
-			case "author":
-				data.Title.Author = sub_line
-				data.Title.has_any = true
-			case "source":
-				data.Title.Source = sub_line
-				data.Title.has_any = true
-			case "notes":
-				data.Title.Notes = sub_line
-				data.Title.has_any = true
-			case "draftdate":
-				data.Title.DraftDate = sub_line
-				data.Title.has_any = true
-			case "copyright":
-				data.Title.Copyright = sub_line
-				data.Title.has_any = true
-			case "revision":
-				data.Title.Revision = sub_line
-				data.Title.has_any = true
-			case "contact":
-				data.Title.Contact = sub_line
-				data.Title.has_any = true
-			case "info":
-				data.Title.Info = sub_line
-				data.Title.has_any = true
-
-			case "header":
-				data.header = sub_line
-			case "footer":
-				data.footer = sub_line
-
-			case "conttag":
-				data.cont_tag = sub_line
-			case "moretag":
-				data.more_tag = sub_line
-
-			case "format", "template":
-				if !config.template_set {
-					x, success := set_format(sub_line)
-					if success {
-						config.template     = x
-						config.template_set = true
-					}
-				}
-			case "paper":
-				if !config.paper_set {
-					x, success := set_paper(sub_line)
-					if success {
-						config.paper_size = x
-						config.paper_set  = true
-					}
-				}
-			}
-		}
-
-		if break_main_loop {
-			break
-		}
+	if err != nil {
+		eprintf("failed to parse %q: %v", config.source_file, err)
+		return nil, false
 	}
 
-	data.template = build_template(config, config.template)
-
-	{
-		// remove boneyards in a single step:
-		// it's the only syntax that crosses a
-		// line-boundary, so we deal with it now
-
-		copy := new(strings.Builder)
-		copy.Grow(len(text))
-
-		eat_spaces    := false
-		eat_newlines  := false
-		last_rune     := '_'
-
-		is_escaped := false
-
-		for len(text) > 0 {
-			if !config.include_notes {
-				if text[0] == '[' && len(text) > 1 && text[1] == '[' {
-					if is_escaped {
-						text = text[2:]
-						copy.WriteString("[[")
-						is_escaped = false
-						continue
-					}
-
-					n := rune_pair(text[2:], ']', ']')
-
-					if n < 0 {
-						copy.WriteString("[[")
-						text = text[2:]
-						continue
-					}
+	data := init_data(config, doc)
 
-					text = text[n + 2:]
-
-					eat_newlines = (last_rune == '\n')
-					eat_spaces   = (last_rune == ' ')
-					continue
-				}
-			}
-
-			if text[0] == '/' && len(text) > 1 && text[1] == '*' {
-				if is_escaped {
-					text = text[2:]
-					copy.WriteString("/*")
-					is_escaped = false
-					continue
-				}
-
-				n := rune_pair(text[2:], '*', '/')
-
-				if n < 0 {
-					copy.WriteString("/*")
-					text = text[2:]
-					continue
-				}
-
-				parse_data_table(data, text[2:n])
-
-				text = text[n + 2:]
-
-				eat_newlines = (last_rune == '\n')
-				eat_spaces   = (last_rune == ' ')
-				continue
-			}
-
-			r, width := get_rune(text)
-			text = text[width:]
-
-			if r == '\\' {
-				last_rune = '\\'
-
-				if is_escaped {
-					copy.WriteRune('\\')
-					is_escaped = false
-					continue
-				}
-
-				is_escaped = true
-				continue
-			}
-
-			if is_escaped {
-				copy.WriteRune('\\')
-			}
-			is_escaped = false
-
-			last_rune = r
-
-			if r == '\n' && eat_newlines {
-				continue
-			} else {
-				eat_newlines = false
+	// everything but check only hears about errors,
+	// which is all the renderer ever reported before
+	if config.command != COMMAND_CHECK {
+		for _, d := range data.diagnostics {
+			if d.Severity == fountain.ERROR {
+				print_diagnostic(data.document, d)
 			}
-			if r == ' ' && eat_spaces {
-				continue
-			} else {
-				eat_spaces = false
-			}
-
-			copy.WriteRune(r)
-		}
-
-		text = copy.String() // return de-boned string
-	}
-
-	// update any missing configuration by
-	// applying defaults
-	{
-		if data.header == "" {
-			data.header = "| #page."
-		}
-		if data.cont_tag == "" {
-			data.cont_tag = DEFAULT_CONT_TAG
-		}
-		if data.more_tag == "" {
-			data.more_tag = DEFAULT_MORE_TAG
-		}
-		if data.Title.Title == "" {
-			data.Title.Title = filepath.Base(config.source_file)
 		}
 	}
 
-	data.counter_lookup["wordcount"] = &Counter{value: word_count(text)}
-
-	//
-	// line parsing
-	//
-	nodes := make([]Section, 0, 256)
-
-	for {
-		if len(left_trim(text)) == 0 {
-			break
-		}
-
-		count := count_rune(text, '\n')
-
-		if count > 0 {
-			if count == 1 {
-				text = text[1:]
-				continue
-			}
-
-			nodes = append(nodes, Section{
-				Type:  WHITESPACE,
-				Level: count - 1,
-			})
-			text = text[count:]
-			continue
-		}
-
-		dirty_line := extract_to_newline(text)
-		clean_line := strings.TrimSpace(dirty_line)
-		text = text[len(dirty_line):]
-
-		if len(clean_line) == 0 {
-			nodes = append(nodes, Section{
-				Type:  WHITESPACE,
-				Level: 1,
-			})
-			continue
-		}
-
-		// this is just a simple guard-rail for lines that have
-		// only a single character; they can't be anything but
-		// action by definition and we cut a whole bunch of
-		// additional len() checks out from subsequent code
-		if len(clean_line) == 1 {
-			nodes = append(nodes, Section{
-				Type:  ACTION,
-				Text:  clean_line,
-			})
-			continue
-		}
-
-		the_type := ACTION
-		level    := 0
-
-		switch clean_line[0] {
-		case '!':
-			the_type   = ACTION
-			clean_line = left_trim(clean_line[1:])
-
-			nodes = append(nodes, Section{
-				Type:  the_type,
-				Text:  clean_line,
-			})
-			continue
-
-		case '@':
-			the_type   = CHARACTER
-			clean_line = clean_line[1:]
-
-			if clean_line[len(clean_line) - 1] == '^' {
-				clean_line = clean_line[:len(clean_line) - 1]
-				level += 1
-			}
-
-		case '~':
-			n := count_rune(clean_line, '~')
-			if n == 2 {
-				break
-			}
-
-			the_type   = LYRIC
-			clean_line = left_trim(clean_line[1:])
-
-		case '=':
-			n := count_rune(clean_line, '=')
-
-			if n >= 3 {
-				the_type = PAGE_BREAK
-			} else if n == 1 {
-				the_type   = SYNOPSIS
-				clean_line = left_trim(clean_line[1:])
-			}
-
-		case '#':
-			// if we consider it to be a variable, we action it
-			if r, _ := get_rune(clean_line[1:]); !(r == '#' || unicode.IsSpace(r)) {
-				the_type = ACTION
-
-			// otherwise it's a section and we treat it
-			// accordingly.
-			} else {
-				n := count_rune(clean_line, '#')
-
-				the_type   = SECTION
-				level      = n
-				clean_line = left_trim(clean_line[n:])
-
-				if level > 3 {
-					level = 3
-				}
-			}
-
-		case '(':
-			if clean_line[len(clean_line) - 1] == ')' {
-				if last_node, success := get_last_section(nodes); success {
-					if is_character_train(last_node.Type) {
-						the_type = PARENTHETICAL
-					}
-				}
-			}
-
-		case '>':
-			clean_line = left_trim(clean_line[1:])
-
-			the_type = TRANSITION
-
-			if clean_line[len(clean_line) - 1] == '<' {
-				the_type   = CENTERED
-				clean_line = right_trim(clean_line[:len(clean_line) - 1])
-			}
-
-		case '.':
-			if left_trim(clean_line[1:])[0] != '.' {
-				name, number, success := get_scene_number(left_trim(clean_line[1:]))
-				if !success {
-					name = left_trim(clean_line[1:])
-				}
-
-				nodes = append(nodes, Section{
-					Type:        SCENE,
-					Text:        name,
-					SceneNumber: number,
-				})
-				continue
-			}
-		}
-
-		// if we're still marked as action
-		// check general syntaxes
-		if the_type == ACTION {
-			if n := strings.IndexRune(clean_line, ':'); n > 0 {
-				count := 0
-
-				for _, c := range clean_line[:n] {
-					if !unicode.IsLetter(c) {
-						break
-					}
-					count += 1
-				}
-
-				if n == count {
-					switch homogenise(clean_line[:n]) {
-					case "header":
-						the_type   = HEADER
-						clean_line = left_trim(clean_line[n + 1:])
-					case "footer":
-						the_type   = FOOTER
-						clean_line = left_trim(clean_line[n + 1:])
-					}
-
-					if the_type != ACTION {
-						nodes = append(nodes, Section{
-							Type: the_type,
-							Text: clean_line,
-						})
-						continue
-					}
-				}
-			}
-
-			if is_valid_scene(clean_line) {
-				// can we combine this with the scene in the switch above? ^^
-				name, number, success := get_scene_number(left_trim(clean_line))
-				if !success {
-					name = clean_line
-				}
-
-				nodes = append(nodes, Section{
-					Type:        SCENE,
-					Text:        name,
-					SceneNumber: number,
-				})
-				continue
-
-			} else if is_valid_transition(clean_line) {
-				the_type = TRANSITION
+	return data, true
+}
 
-			} else if last_node, success := get_last_section(nodes); success && is_character_train(last_node.Type) {
-				the_type = DIALOGUE
+func init_data(config *Config, doc *fountain.Document) *Fountain {
+	data := new(Fountain)
 
-			} else if is_valid_character(clean_line) {
-				the_type = CHARACTER
+	data.Meta        = doc.Meta
+	data.Meta.Source = MEANDER
+	data.Title       = doc.Title
+	data.Files       = doc.Files
+	data.Characters  = doc.Characters
+	data.document    = doc
 
-				if clean_line[len(clean_line) - 1] == '^' {
-					clean_line = strings.TrimSpace(clean_line[:len(clean_line) - 1])
-					level += 1
-				}
+	data.header   = doc.Settings.Header
+	data.footer   = doc.Settings.Footer
+	data.cont_tag = doc.Settings.ContTag
+	data.more_tag = doc.Settings.MoreTag
 
-				// @todo this rule should not have precedence over the DIALOGUE force below
-				// exceptionally short lines of dialogue are mistakenly indented: "I—"
-
-			} else {
-				clean_line = dirty_line // plain action uses dirty_line
-			}
+	if !config.template_set && doc.Settings.Format != "" {
+		if x, success := set_format(doc.Settings.Format); success {
+			config.template     = x
+			config.template_set = true
 		}
-
-		nodes = append(nodes, Section{
-			Type:  the_type,
-			Level: level,
-			Text:  clean_line,
-		})
 	}
-
-	var last_char *Section
-	any_visible := false
-
-	for i := range nodes {
-		node := &nodes[i]
-
-		handle_rev_tags(node)
-
-		if !any_visible && node.Type > is_printable && !is_character_train(node.Type) {
-			any_visible = true
-		}
-
-		if node.Type == CHARACTER || node.Type == DUAL_CHARACTER {
-			if i < len(nodes) - 1 {
-				if !is_character_train(nodes[i + 1].Type) {
-					node.Type = ACTION
-					continue
-				}
-			} else {
-				node.Type = ACTION
-				continue
-			}
-
-			if node.Level == 1 {
-				if last_char == nil || last_char.Level == 2 || any_visible {
-					node.Level = 0
-				} else if last_char.Level == 0 {
-					last_char.Level = 1
-					node.Level = 2
-				}
-			}
-
-			last_char = node
-			any_visible = false
-
-			name := strings.ToLower(node.Text)
-
-			for i, c := range name {
-				if c == '(' {
-					name = strings.TrimSpace(name[:i])
-					break
-				}
-			}
-
-			if x, success := data.chars_lookup[name]; success {
-				c := &data.Characters[x]
-				c.Lines += 1
-			} else {
-				data.chars_lookup[name] = len(data.Characters)
-				data.Characters = append(data.Characters, Character{
-					Name:   title_case(name),
-					Gender: "unknown",
-					Lines:  1,
-				})
-			}
+	if !config.paper_set && doc.Settings.Paper != "" {
+		if x, success := set_paper(doc.Settings.Paper); success {
+			config.paper_size = x
+			config.paper_set  = true
 		}
 	}
 
-	has_dual := false
-	revision := ""
-	level    := 0
-
-	for i := range nodes {
-		node := &nodes[i]
-
-		if node.Type == CHARACTER {
-			has_dual = node.Level > 0
-			revision = node.Revision
-
-			if has_dual {
-				node.Type += 1
-				level = node.Level
-			}
-			continue
-		}
-
-		if is_character_train(node.Type) {
-			if has_dual {
-				node.Type += 1
-				node.Level = level
-			}
-			node.Revision = revision
-			continue
-		}
-
-		revision = ""
-		has_dual = false
-	}
-
-	data.Content = nodes
-}
-
-func parse_data_table(data *Fountain, text string) {
-	text = strings.TrimSpace(text)
-
-	if !(len(text) > 8) {
-		return
+	if !config.paper_set {
+		config.paper_size = *lib.PageSizeLetter
 	}
-
-	test_string := strings.ToLower(text[:9])
-	if test_string[:8] != "[gender." && test_string != "[template" {
-		return
+	if !config.template_set {
+		config.template = SCREENPLAY
 	}
 
-	const MODE_GENDER   = 0
-	const MODE_TEMPLATE = 1
-	current_mode := MODE_GENDER
-
-	current_gender   := ""
-	current_template := TYPE_NONE
-
-	for len(text) > 0 {
-		line := extract_to_newline(text)
-		text = text[len(line):]
-		line = strings.TrimSpace(line)
-		text = left_trim(text)
-
-		if line == "" {
-			continue
-		}
-
-		if line[0] == '[' {
-			if line[len(line) - 1] != ']' {
-				return
-			}
-
-			line = strings.ToLower(strings.TrimSpace(line[1:len(line) - 1]))
-
-			if strings.HasPrefix(line, "gender.") {
-				current_mode   = MODE_GENDER
-				current_gender = line[7:]
-				continue
-			}
-
-			current_template = TYPE_NONE
-
-			if strings.HasPrefix(line, "template.") {
-				current_mode = MODE_TEMPLATE
-				t, success := string_to_section_type(line[9:])
-				if success {
-					current_template = t
-				}
-				continue
-			} else if line == "template" {
-				current_mode = MODE_TEMPLATE
-				continue
-			} else {
-				// @error in data table heading
-			}
-			continue
-		}
-
-		if current_mode == MODE_GENDER {
-			names := strings.Split(line, "|")
-			for i, entry := range names {
-				names[i] = strings.TrimSpace(entry)
-			}
-			name := names[0]
-			names = names[1:]
+	data.config   = config
+	data.template = build_template(config, config.template)
 
-			n := len(data.Characters)
+	data.diagnostics = doc.Diagnostics
 
-			if len(names) > 0 {
-				for _, x := range names {
-					data.chars_lookup[strings.ToLower(x)] = n
-				}
-			} else {
-				names = nil
-			}
-
-			data.chars_lookup[strings.ToLower(name)] = n
-			data.Characters = append(data.Characters, Character{
-				Name:       name,
-				Gender:     current_gender,
-				OtherNames: names,
-			})
+	// reading speeds from the title page, which
+	// a [template] table can still override.  bad
+	// values are reported the same way as they are
+	// in a table, so that check picks them up too
+	if doc.Settings.DialogueWPM != "" {
+		if x, success := parse_wpm(doc.Settings.DialogueWPM); success {
+			data.template.dialogue_wpm = x
 		} else {
-			template_entry_parser(data.template, current_template, line, data.line_number)
-		}
-	}
-}
-
-func get_last_section(nodes []Section) (*Section, bool) {
-	if len(nodes) > 0 {
-		return &nodes[len(nodes) - 1], true
-	}
-	return nil, false
-}
-
-func consume_title_page(input string) string {
-	if n := strings.IndexRune(input, '\n'); n > -1 {
-		if is_title_element(input[:n]) {
-			if n := rune_pair(input, '\n', '\n'); n > -1 {
-				return strings.TrimSpace(input[n:])
-			}
+			diagnose(data, fountain.ERROR, CODE_TEMPLATE_ERROR, doc.Settings.Positions["dialoguewpm"], "invalid dialogue wpm %q in title page", doc.Settings.DialogueWPM)
 		}
 	}
-
-	return input
-}
-
-func is_valid_character(line string) bool {
-	for i, c := range line {
-		if !is_format_char(c) {
-			line = line[i:]
-			break
+	if doc.Settings.ActionWPM != "" {
+		if x, success := parse_wpm(doc.Settings.ActionWPM); success {
+			data.template.action_wpm = x
+		} else {
+			diagnose(data, fountain.ERROR, CODE_TEMPLATE_ERROR, doc.Settings.Positions["actionwpm"], "invalid action wpm %q in title page", doc.Settings.ActionWPM)
 		}
 	}
 
-	// characters must start with a letter
-	if !unicode.IsLetter(rune(line[0])) {
-		return false
+	for _, rule := range doc.Settings.Templates {
+		pos := rule.Position
+		template_entry_parser(data.template, rule.Type, rule.Text, func(format string, guff ...any) {
+			diagnose(data, fountain.ERROR, CODE_TEMPLATE_ERROR, pos, format, guff...)
+		})
 	}
 
-	has_letters := false
-	first_char := true
-
-	for len(line) > 0 {
-		c, rune_width := get_rune(line)
-
-		// allow for rev markers
-		if c == '@' {
-			if _, w := extract_ident(line[rune_width:]); w > 0 {
-				line = line[rune_width + w:]
-				continue
-			}
-		}
-
-		if c == '(' && !first_char {
-			for i, c := range line {
-				if c == ')' {
-					line = line[i:]
-					break
-				}
-			}
-		}
-
-		if unicode.IsLetter(c) {
-			has_letters = true
-
-			if !unicode.IsUpper(c) {
-				return false
-			}
+	// update any missing configuration by
+	// applying defaults
+	{
+		if data.header == "" {
+			data.header = "| #page."
 		}
-
-		line = line[rune_width:]
-		first_char = false
-	}
-
-	return has_letters
-}
-
-func find_title_colon(input string) (int, bool) {
-	for i, c := range input {
-		if c == '\n' {
-			return 0, false
+		if data.cont_tag == "" {
+			data.cont_tag = DEFAULT_CONT_TAG
 		}
-		if c == ':' {
-			return i, true
+		if data.more_tag == "" {
+			data.more_tag = DEFAULT_MORE_TAG
 		}
 	}
-	return 0, false
-}
 
-func is_title_element(line string) bool {
-	found_colon := false
+	data.counter_lookup = make(map[string]*Counter, 32)
+	data.counter_lookup["wordcount"] = &Counter{value: doc.WordCount}
 
-	for i, c := range line {
-		if c == ':' {
-			line = strings.TrimSpace(homogenise(line[:i]))
-			found_colon = true
-			break
-		}
+	data.Content = make([]Section, len(doc.Content))
+	for i, section := range doc.Content {
+		data.Content[i].Section = section
 	}
 
-	if found_colon && lang_title_page(line) {
-		return true
-	}
+	_, words := count_scoped_words(data)
 
-	return false
-}
+	data.counter_lookup["dialoguewords"]  = &Counter{value: words.dialogue}
+	data.counter_lookup["narrationwords"] = &Counter{value: words.narration}
+	data.counter_lookup["sectionwords"]   = &Counter{value: 0}
+	data.counter_lookup["scenewords"]     = &Counter{value: 0}
 
-func is_valid_transition(line string) bool {
-	for i := len(line) - 1; i >= 0; i-- {
-		c := line[i]
-		if ascii_space[c] == 1 {
-			return lang_transition(strings.ToLower(line[i+1:]))
-		}
-	}
-	return false
+	return data
 }
 
 // this conversion system obviously isn't
@@ -1093,65 +422,6 @@ func clamp(m int) int {
 	return m
 }
 
-func get_scene_number(text string) (string, string, bool) {
-	if text[len(text) - 1] == '#' {
-		n := 0
-		t := text[:len(text) - 1]
-
-		for i := len(t) - 1; i > 0; i-- {
-			the_rune, _ := utf8.DecodeLastRuneInString(t[:i + 1])
-
-			if unicode.IsSpace(the_rune) {
-				break
-			}
-			if the_rune == '#' {
-				n = i
-				break
-			}
-		}
-
-		if n != 0 {
-			return strings.TrimSpace(text[:n]), t[n + 1:], true
-		}
-	}
-
-	return "", "", false
-}
-
-func is_character_train(node_type Section_Type) bool {
-	return node_type > begin_character && node_type < end_character
-}
-
-func is_valid_scene(line string) bool {
-	word := ""
-
-	for i, c := range line {
-		if c == '.' {
-			word = line[:i]
-			break
-		}
-
-		if c >= utf8.RuneSelf {
-			if unicode.IsSpace(c) {
-				word = line[:i]
-				break
-			}
-			continue
-		}
-
-		if ascii_space[c] == 1 {
-			word = line[:i]
-			break
-		}
-	}
-
-	if len(word) > 0 {
-		return lang_scene(strings.ToLower(clean_string(word)))
-	}
-
-	return false
-}
-
 func copy_without_style(incoming Section) Section {
 	new_lines := make([]Line, len(incoming.lines))
 
@@ -1175,37 +445,3 @@ func copy_without_style(incoming Section) Section {
 
 	return incoming
 }
-
-func handle_rev_tags(node *Section) {
-	index := strings.IndexRune(node.Text, '@')
-	if index < 0 {
-		return
-	}
-
-	word, width := extract_ident(node.Text[index + 1:])
-	if width > 0 {
-		node.Revision = strings.ToLower(word)
-
-		buffer := new(strings.Builder)
-		buffer.Grow(len(node.Text))
-
-		offset := index + width + 1
-
-		if offset == len(node.Text) {
-			buffer.WriteString(strings.TrimSpace(node.Text[:index]))
-		} else {
-			buffer.WriteString(node.Text[:index])
-			buffer.WriteString(strings.TrimSpace(node.Text[offset:]))
-		}
-
-		node.Text = buffer.String()
-	}
-}
-
-const Section_Type_NAMES = "whitespacepage_breakheaderfooteris_printableactionscenebegin_charactercharacterdual_characterparentheticaldual_parentheticaldialoguedual_dialoguelyricdual_lyricend_charactertransitionsynopsiscenteredis_sectionsectionsection2section3type_count"
-
-var Section_Type_INDICES = [...]uint8{0, 10, 20, 26, 32, 44, 50, 55, 70, 79, 93, 106, 124, 132, 145, 150, 160, 173, 183, 191, 199, 209, 216, 224, 232, 242}
-
-func (i Section_Type) String() string {
-	return Section_Type_NAMES[Section_Type_INDICES[i]:Section_Type_INDICES[i+1]]
-}
@@ -14,18 +14,15 @@ import "strings"
 const BAR_LENGTH = 20
 
 func command_gender(config *Config) {
-	text, success := merge(config.source_file)
+	data, success := parse_file(config)
 	if !success {
 		return
 	}
 
-	data := init_data(config)
-	syntax_parser(config, data, text)
-
-	println_color("\n   ", clean_string(data.Title.Title), "Gender Analysis")
-	print_data(crunch_chars_by_gender(data), "Character Count by Gender")
-	print_data(crunch_lines_by_gender(data), "Lines by Gender")
-	print_data(crunch_chars_by_lines(data),  "Lines by Character")
+	println_color("\n   ", clean_string(data.Title.Title), GENDER_HEADING)
+	for _, table := range crunch_gender(data) {
+		print_data(table.set, table.title)
+	}
 	print("\n")
 }
 
@@ -96,20 +93,32 @@ func crunch_chars_by_gender(data *Fountain) *Analytics_Set {
 }
 
 func crunch_lines_by_gender(data *Fountain) *Analytics_Set {
+	return crunch_by_gender(data, func(c *Character) int { return c.Lines })
+}
+
+func crunch_words_by_gender(data *Fountain) *Analytics_Set {
+	return crunch_by_gender(data, func(c *Character) int { return c.Words })
+}
+
+// crunch_by_gender totals something every character
+// has a count of, such as their lines, for each gender
+func crunch_by_gender(data *Fountain, value func(*Character) int) *Analytics_Set {
 	array := make(Analytics_Entries, 0, 12)
 
-	total_lines    := 0
+	total_value    := 0
 	longest_gender := 0
 
 	counter := make(map[string]int, 12)
 
-	for _, c := range data.Characters {
+	for i := range data.Characters {
+		c := &data.Characters[i]
+
 		if c.Gender == "ignore" {
 			continue
 		}
 
-		total_lines += c.Lines
-		counter[c.Gender] += c.Lines
+		total_value += value(c)
+		counter[c.Gender] += value(c)
 
 		x := rune_count(c.Gender)
 		if x > longest_gender {
@@ -134,29 +143,39 @@ func crunch_lines_by_gender(data *Fountain) *Analytics_Set {
 	return &Analytics_Set{
 		longest_gender,
 		0,
-		total_lines,
+		total_value,
 		largest_group,
 		array,
 	}
 }
 
 func crunch_chars_by_lines(data *Fountain) *Analytics_Set {
+	return crunch_by_character(data, func(c *Character) int { return c.Lines })
+}
+
+func crunch_chars_by_words(data *Fountain) *Analytics_Set {
+	return crunch_by_character(data, func(c *Character) int { return c.Words })
+}
+
+func crunch_by_character(data *Fountain, value func(*Character) int) *Analytics_Set {
 	array := make(Analytics_Entries, 0, len(data.Characters))
 
-	total_lines    := 0
-	most_lines     := 0
+	total_value    := 0
+	most_value     := 0
 	longest_gender := 0
 	longest_char   := 0
 
-	for _, c := range data.Characters {
+	for i := range data.Characters {
+		c := &data.Characters[i]
+
 		if c.Gender == "ignore" {
 			continue
 		}
 
-		total_lines += c.Lines
+		total_value += value(c)
 
-		if c.Lines > most_lines {
-			most_lines = c.Lines
+		if value(c) > most_value {
+			most_value = value(c)
 		}
 
 		x := rune_count(c.Name)
@@ -169,7 +188,7 @@ func crunch_chars_by_lines(data *Fountain) *Analytics_Set {
 		}
 
 		array = append(array, Analytics_Entry{
-			value:    c.Lines,
+			value:    value(c),
 			name_one: c.Name,
 			name_two: c.Gender,
 		})
@@ -180,12 +199,160 @@ func crunch_chars_by_lines(data *Fountain) *Analytics_Set {
 	return &Analytics_Set{
 		longest_char,
 		longest_gender,
-		total_lines,
-		most_lines,
+		total_value,
+		most_value,
+		array,
+	}
+}
+
+// speakers_by_scene lists the characters who speak in each
+// scene, leaving out anyone the gender table ignores
+func speakers_by_scene(data *Fountain) [][]int {
+	scenes := make([][]int, 0, 64)
+
+	for _, section := range data.Content {
+		switch section.Type {
+		case SCENE:
+			scenes = append(scenes, make([]int, 0, 8))
+
+		case CHARACTER, DUAL_CHARACTER:
+			if len(scenes) == 0 {
+				continue
+			}
+
+			i, ok := find_character(data, section.Text)
+			if !ok || data.Characters[i].Gender == "ignore" {
+				continue
+			}
+
+			cast := &scenes[len(scenes) - 1]
+			if !has_int(*cast, i) {
+				*cast = append(*cast, i)
+			}
+		}
+	}
+
+	return scenes
+}
+
+// crunch_scenes_by_gender counts the scenes in which
+// at least one character of each gender speaks, as a
+// share of every scene in the script
+func crunch_scenes_by_gender(data *Fountain, scenes [][]int) *Analytics_Set {
+	return crunch_scenes(data, scenes, func(gender string, speakers int) bool {
+		return true
+	})
+}
+
+// crunch_pairs_by_gender counts the scenes in which two or
+// more characters of the same gender speak to each other,
+// for every gender but the default
+func crunch_pairs_by_gender(data *Fountain, scenes [][]int, default_gender string) *Analytics_Set {
+	return crunch_scenes(data, scenes, func(gender string, speakers int) bool {
+		return speakers >= 2 && gender != default_gender
+	})
+}
+
+func crunch_scenes(data *Fountain, scenes [][]int, counts func(string, int) bool) *Analytics_Set {
+	array := make(Analytics_Entries, 0, 12)
+
+	longest_gender := 0
+
+	counter := make(map[string]int, 12)
+
+	for _, cast := range scenes {
+		speakers := make(map[string]int, 4)
+
+		for _, i := range cast {
+			speakers[data.Characters[i].Gender] += 1
+		}
+
+		for gender_name, n := range speakers {
+			if !counts(gender_name, n) {
+				continue
+			}
+
+			counter[gender_name] += 1
+
+			x := rune_count(gender_name)
+			if x > longest_gender {
+				longest_gender = x
+			}
+		}
+	}
+
+	largest_group := 0
+
+	for gender_name, count := range counter {
+		if count > largest_group {
+			largest_group = count
+		}
+		array = append(array, Analytics_Entry{
+			value:    count,
+			name_one: gender_name,
+		})
+	}
+
+	sort.Sort(array)
+
+	return &Analytics_Set{
+		longest_gender,
+		0,
+		len(scenes),
+		largest_group,
 		array,
 	}
 }
 
+// default_gender is the gender the scenes with two or more
+// speakers leave out: the one given by --default-gender, or
+// otherwise those the gender table doesn't cover at all
+func default_gender(data *Fountain) string {
+	if x := strings.TrimSpace(data.config.default_gender); x != "" {
+		return strings.ToLower(x)
+	}
+	return "unknown"
+}
+
+type Analytics_Table struct {
+	title string
+	set   *Analytics_Set
+}
+
+// crunch_gender runs every part of the gender analysis, in
+// the order they're printed; the scene tables are left out
+// for anything without scene headings, and the pairs table
+// when there are no pairs to count
+func crunch_gender(data *Fountain) []Analytics_Table {
+	tables := make([]Analytics_Table, 0, 7)
+
+	tables = append(tables,
+		Analytics_Table{GENDER_CHARS_BY_GENDER, crunch_chars_by_gender(data)},
+		Analytics_Table{GENDER_LINES_BY_GENDER, crunch_lines_by_gender(data)},
+		Analytics_Table{GENDER_WORDS_BY_GENDER, crunch_words_by_gender(data)},
+	)
+
+	if scenes := speakers_by_scene(data); len(scenes) > 0 {
+		tables = append(tables, Analytics_Table{GENDER_SCENES_BY_GENDER, crunch_scenes_by_gender(data, scenes)})
+
+		g := default_gender(data)
+
+		if pairs := crunch_pairs_by_gender(data, scenes, g); len(pairs.data) > 0 {
+			tables = append(tables, Analytics_Table{
+				fmt.Sprintf(GENDER_PAIRS_BY_GENDER, title_case(g)),
+				pairs,
+			})
+		}
+	}
+
+	tables = append(tables,
+		Analytics_Table{GENDER_CHARS_BY_LINES, crunch_chars_by_lines(data)},
+		Analytics_Table{GENDER_CHARS_BY_WORDS, crunch_chars_by_words(data)},
+	)
+
+	return tables
+}
+
 func print_dashes(n int) {
 	println(strings.Repeat("-", n))
 }
@@ -0,0 +1,108 @@
+/*
+	Meander
+	A portable Fountain utility for production writing
+	Copyright (C) 2022-2023 Harley Denham
+*/
+
+package main
+
+import "io"
+import "os"
+import "strings"
+import "archive/zip"
+import "path/filepath"
+
+const HIGHLAND_EXT = ".highland"
+
+// a .highland file is a zipped TextBundle: a folder
+// holding the script as text.fountain (or text.md for
+// older versions), some metadata, and an assets folder
+// for any images or attachments
+func convert_highland(config *Config) {
+	archive, err := zip.OpenReader(config.source_file)
+	if err != nil {
+		eprintf("failed to load %q", config.source_file)
+		return
+	}
+	defer archive.Close()
+
+	var script *zip.File
+	assets := make([]*zip.File, 0, 8)
+
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() || strings.HasPrefix(filepath.Base(file.Name), ".") {
+			continue
+		}
+
+		if strings.Contains(file.Name, "assets/") {
+			assets = append(assets, file)
+			continue
+		}
+
+		name := filepath.Base(file.Name)
+		ext  := filepath.Ext(name)
+
+		// prefer the bundle's own text file, but fall
+		// back on the first Fountain file we see
+		if strings.TrimSuffix(name, ext) == "text" || (script == nil && ext == FOUNTAIN_EXT) {
+			script = file
+		}
+	}
+
+	if script == nil {
+		eprintf("convert: %q doesn't contain a script", config.source_file)
+		return
+	}
+
+	text, success := read_zip_file(script)
+	if !success {
+		eprintf("failed to load %q", config.source_file)
+		return
+	}
+
+	success = write_file(fix_path(config.output_file), text)
+	if !success {
+		eprintln("failed to write", config.output_file)
+		return
+	}
+
+	// assets are unpacked into a folder beside the
+	// script, keeping their paths inside the bundle
+	if len(assets) == 0 {
+		return
+	}
+
+	root := filepath.Join(filepath.Dir(fix_path(config.output_file)), "assets")
+
+	for _, file := range assets {
+		name := file.Name[strings.Index(file.Name, "assets/") + len("assets/"):]
+		path := filepath.Join(root, filepath.FromSlash(name))
+
+		// don't let a badly-made archive write
+		// outside of the assets folder
+		if !strings.HasPrefix(path, root + string(filepath.Separator)) {
+			continue
+		}
+
+		blob, success := read_zip_file(file)
+		if !success {
+			eprintln("failed to load", file.Name)
+			continue
+		}
+
+		if os.MkdirAll(filepath.Dir(path), os.ModePerm) != nil || !write_file(path, blob) {
+			eprintln("failed to write", path)
+		}
+	}
+}
+
+func read_zip_file(file *zip.File) ([]byte, bool) {
+	reader, err := file.Open()
+	if err != nil {
+		return nil, false
+	}
+	defer reader.Close()
+
+	blob, err := io.ReadAll(reader)
+	return blob, err == nil
+}
@@ -0,0 +1,109 @@
+/*
+	Meander
+	A portable Fountain utility for production writing
+	Copyright (C) 2022-2023 Harley Denham
+*/
+
+package main
+
+import "strings"
+
+// highlight_characters paints the cues, parentheticals and
+// dialogue of every --highlight-character, each in its own
+// colour, for personalised table-read scripts.  it has to
+// run after pagination, once the lines are laid out, and
+// returns false if any of the names aren't in the script.
+func highlight_characters(config *Config, data *Fountain) bool {
+	if len(config.highlight_chars) == 0 {
+		return true
+	}
+
+	colors := make(map[int]Color, len(config.highlight_chars))
+
+	for i, arg := range config.highlight_chars {
+		name  := arg
+		color := HIGHLIGHT_COLORS[i % len(HIGHLIGHT_COLORS)].color
+
+		if n := strings.LastIndexByte(arg, ':'); n >= 0 {
+			x, success := highlight_color(arg[n + 1:])
+			if !success {
+				eprintf("error: unknown highlight colour %q", arg[n + 1:])
+				return false
+			}
+			name, color = arg[:n], x
+		}
+
+		// the lookup holds the other names from the gender
+		// table too, so they all come out as one character
+		index, ok := find_character(data, name)
+		if !ok {
+			eprintf("error: there's no character called %q to highlight", strings.TrimSpace(name))
+			return false
+		}
+
+		colors[index] = color
+	}
+
+	active := false
+	color  := Color{}
+
+	for i := range data.Content {
+		section := &data.Content[i]
+
+		if !is_character_train(section.Type) {
+			// headers and footers can fall in the
+			// middle of a speech that's split across
+			// two pages, so they don't end it
+			if section.Type > is_printable {
+				active = false
+			}
+			continue
+		}
+
+		if section.Type == CHARACTER || section.Type == DUAL_CHARACTER {
+			active = false
+			if index, ok := find_character(data, section.Text); ok {
+				color, active = colors[index]
+			}
+		}
+
+		if !active {
+			continue
+		}
+
+		// a pointer, so that black can be told
+		// apart from there being no highlight
+		color := color
+
+		if section.is_raw {
+			section.highlight_color = &color
+			continue
+		}
+
+		for j := range section.lines {
+			line := &section.lines[j]
+			line.highlight       = []int{0, line.length}
+			line.highlight_color = &color
+		}
+	}
+
+	return true
+}
+
+// highlight_color reads a colour by name, such as "green",
+// or as red, green and blue values, such as "170,240,170"
+func highlight_color(text string) (Color, bool) {
+	text = strings.ToLower(strings.TrimSpace(text))
+
+	for _, c := range HIGHLIGHT_COLORS {
+		if c.name == text {
+			return c.color, true
+		}
+	}
+
+	if len(strings.Fields(strings.ReplaceAll(text, ",", " "))) != 3 {
+		return Color{}, false
+	}
+
+	return parse_color(strings.ReplaceAll(text, ",", " "))
+}
@@ -0,0 +1,368 @@
+/*
+	Meander
+	A portable Fountain utility for production writing
+	Copyright (C) 2022-2023 Harley Denham
+*/
+
+package main
+
+import "fmt"
+import "html"
+import "math"
+import "strconv"
+import "strings"
+import "path/filepath"
+
+import "github.com/lichendust/meander/fountain"
+
+const HTML_EXT = ".html"
+
+// export_html writes the script as a single HTML page, with
+// a stylesheet built from the active template.  there are no
+// pages, so headers, footers and pagination are left behind
+// and the browser is left to do the wrapping.
+func export_html(config *Config) {
+	data, success := parse_file(config)
+	if !success {
+		return
+	}
+
+	vet_template(data.template)
+	prepare_export(data)
+
+	title := rewrite_ext(filepath.Base(config.source_file), "")
+	if data.Title.Title != "" {
+		title = plain_text(data, data.Title.Title)
+	}
+
+	buffer := strings.Builder{}
+	buffer.Grow(len(data.Content) * 128)
+
+	buffer.WriteString("<!DOCTYPE html>\n<html>\n<head>\n")
+	buffer.WriteString("<meta charset=\"utf-8\">\n")
+	buffer.WriteString("<meta name=\"generator\" content=\"" + MEANDER + "\">\n")
+	buffer.WriteString("<title>" + html.EscapeString(title) + "</title>\n")
+	buffer.WriteString("<style>\n")
+	buffer.WriteString(html_stylesheet(data.template))
+	buffer.WriteString("</style>\n</head>\n<body>\n<article class=\"script\">\n")
+
+	html_title_page(&buffer, data)
+	html_content(&buffer, data)
+
+	buffer.WriteString("</article>\n</body>\n</html>\n")
+
+	success = write_file(fix_path(config.output_file), []byte(buffer.String()))
+	if !success {
+		eprintln("failed to write", config.output_file)
+	}
+}
+
+func html_title_page(buffer *strings.Builder, data *Fountain) {
+	if !data.Title.HasAny {
+		return
+	}
+
+	buffer.WriteString("<header class=\"title_page\">\n")
+
+	if data.Title.Title != "" {
+		buffer.WriteString("<h1 class=\"title\">")
+		html_lines(buffer, data, text_spans(data, data.Title.Title))
+		buffer.WriteString("</h1>\n")
+	}
+
+	for _, entry := range [...]struct{
+		class string
+		text  string
+	}{
+		{"credit",     data.Title.Credit},
+		{"author",     data.Title.Author},
+		{"source",     data.Title.Source},
+		{"notes",      data.Title.Notes},
+		{"draft_date", data.Title.DraftDate},
+		{"copyright",  data.Title.Copyright},
+		{"revision",   data.Title.Revision},
+		{"contact",    data.Title.Contact},
+		{"info",       data.Title.Info},
+	} {
+		if entry.text == "" {
+			continue
+		}
+		buffer.WriteString("<p class=\"" + entry.class + "\">")
+		html_lines(buffer, data, text_spans(data, entry.text))
+		buffer.WriteString("</p>\n")
+	}
+
+	buffer.WriteString("</header>\n")
+}
+
+func html_content(buffer *strings.Builder, data *Fountain) {
+	// character, parenthetical and dialogue are kept together
+	// in a speech block, and the two halves of dual dialogue
+	// sit side by side in a dual block
+	in_speech := false
+	in_dual   := false
+
+	close_speech := func() {
+		if in_speech {
+			buffer.WriteString("</div>\n")
+			in_speech = false
+		}
+	}
+	close_dual := func() {
+		close_speech()
+		if in_dual {
+			buffer.WriteString("</div>\n")
+			in_dual = false
+		}
+	}
+
+	// blank lines in the source become space above
+	// whatever comes next, as they do when paginating
+	gap := 0
+
+	for i := range data.Content {
+		section := &data.Content[i]
+
+		switch section.Type {
+		case WHITESPACE:
+			close_speech()
+			gap += section.Level
+			continue
+
+		case PAGE_BREAK:
+			close_dual()
+			buffer.WriteString("<hr class=\"page_break\">\n")
+			gap = 0
+			continue
+		}
+
+		if section.Type < is_printable {
+			continue
+		}
+
+		section_type := section.Type
+		if section_type == SECTION {
+			section_type += Section_Type(section.Level - 1)
+		}
+
+		if data.template.types[section_type].skip {
+			continue
+		}
+
+		switch section.Type {
+		case CHARACTER:
+			close_dual()
+			buffer.WriteString("<div class=\"speech\">\n")
+			in_speech = true
+
+		case DUAL_CHARACTER:
+			if section.Level == 1 || !in_dual {
+				close_dual()
+				buffer.WriteString("<div class=\"dual\">\n")
+				in_dual = true
+			} else {
+				close_speech()
+				gap = 0
+			}
+			buffer.WriteString("<div class=\"speech\">\n")
+			in_speech = true
+
+		case PARENTHETICAL, DIALOGUE, LYRIC, DUAL_PARENTHETICAL, DUAL_DIALOGUE, DUAL_LYRIC:
+			// carries on in the open speech
+
+		default:
+			close_dual()
+		}
+
+		tag := "p"
+		switch section_type {
+		case SECTION:
+			tag = "h2"
+		case SECTION2:
+			tag = "h3"
+		case SECTION3:
+			tag = "h4"
+		case SCENE:
+			tag = "h5"
+		}
+
+		buffer.WriteString("<" + tag + " class=\"" + section_type.String())
+		if gap > 0 && !data.template.ignore_whitespace {
+			buffer.WriteString(" gap")
+		}
+		buffer.WriteString("\"")
+
+		if gap > 1 && !data.template.ignore_whitespace {
+			fmt.Fprintf(buffer, " style=\"margin-top: %s\"", css_pt(data.template.line_height * float64(gap)))
+		}
+		if section.Revision != "" {
+			buffer.WriteString(" data-revision=\"" + html.EscapeString(section.Revision) + "\"")
+		}
+		buffer.WriteString(">")
+
+		if section.Type == SCENE {
+			if number := export_scene_number(data, section); number != "" {
+				buffer.WriteString("<span class=\"scene_number\">" + html.EscapeString(number) + "</span>")
+			}
+		}
+
+		html_lines(buffer, data, section_spans(data, section))
+
+		buffer.WriteString("</" + tag + ">\n")
+
+		gap = 0
+	}
+
+	close_dual()
+}
+
+// html_lines writes out each line of spans, joined by
+// line breaks, with each span in the tags for its style
+func html_lines(buffer *strings.Builder, data *Fountain, lines [][]Span) {
+	for i, line := range lines {
+		if i > 0 {
+			buffer.WriteString("<br/>")
+		}
+
+		for _, span := range line {
+			text := html.EscapeString(span.text)
+
+			if span.style & NOTE      != 0 { text = "<span class=\"note\">" + text + "</span>" }
+			if span.style & HIGHLIGHT != 0 { text = "<mark>" + text + "</mark>" }
+			if span.style & STRIKEOUT != 0 { text = "<s>" + text + "</s>" }
+			if span.style & UNDERLINE != 0 { text = "<u>" + text + "</u>" }
+			if span.style & ITALIC    != 0 { text = "<em>" + text + "</em>" }
+			if span.style & BOLD      != 0 { text = "<strong>" + text + "</strong>" }
+
+			buffer.WriteString(text)
+		}
+	}
+}
+
+// html_stylesheet lays the template out in CSS.  everything
+// is measured in points, as the template is, and the text
+// column spans the space between the template's margins
+func html_stylesheet(template *Template) string {
+	buffer := strings.Builder{}
+
+	line_height := template.line_height
+	if line_height == 0 {
+		line_height = LINE_HEIGHT
+	}
+
+	fmt.Fprintf(&buffer, "@page { size: %s %s; margin: %s %s %s %s; }\n",
+		css_pt(template.paper.W),
+		css_pt(template.paper.H),
+		css_pt(template.margin_top),
+		css_pt(template.paper.W - template.margin_right),
+		css_pt(template.margin_bottom),
+		css_pt(template.margin_left),
+	)
+
+	fmt.Fprintf(&buffer, "body { margin: 0; padding: %s 0; background: #fff; color: %s; }\n", css_pt(template.margin_top), css_color(template.text_color))
+	fmt.Fprintf(&buffer, ".script { width: %s; margin: 0 auto; font-family: \"Courier Prime\", \"Courier New\", Courier, monospace; font-size: 12pt; line-height: %s; }\n", css_pt(template.margin_right - template.margin_left), css_pt(line_height))
+
+	buffer.WriteString("p, h1, h2, h3, h4, h5 { margin: 0; font-size: inherit; font-weight: inherit; }\n")
+	buffer.WriteString(".title_page { margin-bottom: 4in; break-after: page; }\n")
+	buffer.WriteString(".title_page p { margin-top: 1em; }\n")
+	buffer.WriteString(".page_break { border: none; margin: 0; break-after: page; }\n")
+	buffer.WriteString(".speech { break-inside: avoid; }\n")
+	buffer.WriteString(".dual { display: grid; }\n")
+	buffer.WriteString(".dual > .speech { grid-area: 1 / 1; }\n")
+	fmt.Fprintf(&buffer, ".dual > .speech + .speech { margin-left: %s; }\n", css_pt(template.dual_right_offset))
+	fmt.Fprintf(&buffer, ".gap { margin-top: %s; }\n", css_pt(line_height))
+	buffer.WriteString(".scene { position: relative; }\n")
+	buffer.WriteString(".scene_number { position: absolute; right: 100%; margin-right: 1em; }\n")
+	fmt.Fprintf(&buffer, ".note { color: %s; }\n", css_color(template.note_color))
+	fmt.Fprintf(&buffer, "mark { color: inherit; background: %s; }\n", css_color(template.highlight_color))
+
+	if template.title_page_align == CENTER {
+		buffer.WriteString(".title_page { text-align: center; }\n")
+	}
+
+	for i := is_printable + 1; i < TYPE_COUNT; i++ {
+		if i == is_section || i == fountain.BEGIN_CHARACTER || i == fountain.END_CHARACTER {
+			continue
+		}
+
+		t := &template.types[i]
+		if t.skip {
+			continue
+		}
+
+		rules := make([]string, 0, 12)
+
+		switch t.justify {
+		default:
+			rules = append(rules, "margin-left: " + css_pt(t.margin))
+		case RIGHT:
+			rules = append(rules, "margin-left: auto", "margin-right: " + css_pt(t.margin), "text-align: right")
+		case CENTER:
+			rules = append(rules, "margin-left: auto", "margin-right: auto", "text-align: center")
+		}
+
+		if t.width > 0 {
+			rules = append(rules, "max-width: " + css_pt(t.width))
+		}
+		if t.space_above > 0 {
+			rules = append(rules, "padding-top: " + css_pt(t.space_above))
+		}
+		if t.line_height > 0 && t.line_height != line_height {
+			rules = append(rules, "line-height: " + css_pt(t.line_height))
+		}
+		if t.para_indent > 0 {
+			rules = append(rules, fmt.Sprintf("text-indent: %dch", t.para_indent))
+		}
+
+		rules = css_text_rules(template, t, rules)
+
+		fmt.Fprintf(&buffer, ".%s { %s; }\n", i, strings.Join(rules, "; "))
+	}
+
+	return buffer.String()
+}
+
+// css_text_rules adds the parts of a template entry that
+// change the text itself, rather than where it sits
+func css_text_rules(template *Template, t *Template_Entry, rules []string) []string {
+	switch t.casing {
+	case UPPERCASE:
+		rules = append(rules, "text-transform: uppercase")
+	case LOWERCASE:
+		rules = append(rules, "text-transform: lowercase")
+	}
+
+	if t.style & BOLD != 0 {
+		rules = append(rules, "font-weight: bold")
+	}
+	if t.style & ITALIC != 0 {
+		rules = append(rules, "font-style: italic")
+	}
+
+	decoration := make([]string, 0, 2)
+	if t.style & UNDERLINE != 0 {
+		decoration = append(decoration, "underline")
+	}
+	if t.style & STRIKEOUT != 0 {
+		decoration = append(decoration, "line-through")
+	}
+	if len(decoration) > 0 {
+		rules = append(rules, "text-decoration: " + strings.Join(decoration, " "))
+	}
+
+	if t.style & HIGHLIGHT != 0 {
+		rules = append(rules, "background: " + css_color(template.highlight_color))
+	}
+
+	return rules
+}
+
+// points are rounded off so the odd bit of template
+// maths doesn't leave a trail of digits behind it
+func css_pt(x float64) string {
+	return strconv.FormatFloat(math.Round(x * 100) / 100, 'f', -1, 64) + "pt"
+}
+
+func css_color(c Color) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
@@ -6,59 +6,114 @@
 
 package main
 
-// language.go is an extension of fountain.go that reveals
-// the underlying syntax that's matched for; basically we can
-// easily extend language support in this file
+// language.go holds the user-facing strings used by the
+// renderer; the syntax matching lives in the fountain package
 
-const GENDER_HEADING         = "Gender Analysis"
-const GENDER_CHARS_BY_GENDER = "Character Count by Gender"
-const GENDER_LINES_BY_GENDER = "Lines by Gender"
-const GENDER_CHARS_BY_LINES  = "Lines by Character"
+const GENDER_HEADING          = "Gender Analysis"
+const GENDER_CHARS_BY_GENDER  = "Character Count by Gender"
+const GENDER_LINES_BY_GENDER  = "Lines by Gender"
+const GENDER_WORDS_BY_GENDER  = "Words by Gender"
+const GENDER_SCENES_BY_GENDER = "Speaking Scenes by Gender"
+const GENDER_PAIRS_BY_GENDER  = "Scenes with Two or More Speakers by Gender (besides %s)"
+const GENDER_CHARS_BY_LINES   = "Lines by Character"
+const GENDER_CHARS_BY_WORDS   = "Words by Character"
+
+const BREAKDOWN_HEADING   = "Scene Breakdown"
+const BREAKDOWN_SCENE     = "Scene"
+const BREAKDOWN_SETTING   = "I/E"
+const BREAKDOWN_LOCATION  = "Location"
+const BREAKDOWN_TIME      = "Time"
+const BREAKDOWN_PAGE      = "Page"
+const BREAKDOWN_LENGTH    = "Length"
+const BREAKDOWN_CAST      = "Cast"
+const BREAKDOWN_MENTIONED = "Mentioned"
+const BREAKDOWN_SYNOPSIS  = "Synopsis"
+
+const STRIP_HEADING      = "Stripboard"
+const STRIP_CAST         = "Cast: "
+const STRIP_CAST_HEADING = "Cast"
+const STRIP_UNSCHEDULED  = "Not Scheduled"
+
+const STRIP_ORDER_HEADER = `# strip order: one scene per line, top to bottom, known by
+# its position in the script, such as @12, or by its number
+# lines starting with = are banners, such as "= End of Day 1"
+# scenes left out of this file go at the end of the board
+
+`
+
+const LOCATION_HEADING = "Location Report"
+const LOCATION_COLUMN  = "Location"
+const LOCATION_SCENES  = "Scenes"
+const LOCATION_PAGES   = "Pages"
+const LOCATION_UNKNOWN = "Unknown"
+
+const CROSS_HEADING   = "Cross-Plot"
+const CROSS_ID        = "ID"
+const CROSS_CHARACTER = "Character"
+const CROSS_FIRST     = "First"
+const CROSS_LAST      = "Last"
+const CROSS_SCENES    = "Scenes"
+const CROSS_WORDS     = "Words"
+const CROSS_SPEAKING  = "X"
+const CROSS_SILENT    = "O"
+const CROSS_LEGEND    = "%s speaks in the scene    %s appears without speaking"
+
+const SIDES_HEADING = "Sides"
+const SIDES_SCENE   = "Heading"
+const SIDES_PAGES   = "Pages"
+
+// the colours for --highlight-character, given
+// out in order unless one is asked for by name
+var HIGHLIGHT_COLORS = [...]struct{
+	name  string
+	color Color
+}{
+	{"yellow", Color{255, 249, 115}},
+	{"green",  Color{170, 240, 170}},
+	{"blue",   Color{170, 210, 255}},
+	{"pink",   Color{255, 190, 220}},
+	{"orange", Color{255, 205, 140}},
+	{"purple", Color{215, 190, 255}},
+}
+
+const RUNTIME_HEADING = "Runtime Estimate"
+const RUNTIME_COLUMN  = "Runtime"
+const RUNTIME_TOTAL   = "Total"
+const RUNTIME_RATES   = "dialogue at %g words a minute, action at %g"
+
+const WORDS_HEADING   = "Word Count"
+const WORDS_COLUMN    = "Section"
+const WORDS_DIALOGUE  = "Dialogue"
+const WORDS_NARRATION = "Narration"
 
 const DEFAULT_MORE_TAG = "(more)"
 const DEFAULT_CONT_TAG = "(CONT'D)"
 
-// text is lowercased
-func lang_scene(text string) bool {
-	switch text {
-	case "int":     return true
-	case "ext":     return true
-	case "int/ext": return true
-	case "ext/int": return true
-	case "i/e":     return true
-	case "e/i":     return true
-	case "est":     return true
-	case "scene":   return true
-	}
-	return false
+// words in capitals in the action that are never
+// characters, for the scene breakdown
+var CAPS_IGNORED = [...]string{
+	"V.O.", "O.S.", "O.C.", "CONT'D", "POV",
+	"INT", "EXT", "DAY", "NIGHT", "CONTINUOUS", "LATER",
+	"CUT TO", "FADE IN", "FADE OUT", "DISSOLVE TO",
+	"CLOSE ON", "ANGLE ON", "BACK TO", "BACK TO SCENE",
+	"INSERT", "SUPER", "TITLE", "FLASHBACK", "END FLASHBACK",
+	"MONTAGE", "END MONTAGE", "INTERCUT", "SLOW MOTION",
+	"THE END", "TV", "OK",
 }
 
-// text is lowercased
-func lang_transition(text string) bool {
-	return text == "to:"
+// titles that keep their full stop inside a name
+var CAPS_TITLES = [...]string{
+	"MR.", "MRS.", "MS.", "DR.", "ST.", "PROF.",
+	"SGT.", "CAPT.", "LT.", "COL.", "GEN.", "REV.",
 }
 
-// text is homogenised
-func lang_title_page(text string) bool {
-	switch text {
-	// fountain
-	case "title":     return true
-	case "credit":    return true
-	case "author":    return true
-	case "source":    return true
-	case "contact":   return true
-	case "revision":  return true
-	case "copyright": return true
-	case "draftdate": return true
-	case "notes":     return true
-
-	// meander
-	case "paper":   return true
-	case "format":  return true
-	case "conttag": return true
-	case "moretag": return true
-	case "header":  return true
-	case "footer":  return true
-	}
-	return false
-}
+// the usual stripboard colours
+var STRIP_INT_DAY   = Color{255, 255, 255} // white
+var STRIP_EXT_DAY   = Color{255, 236, 110} // yellow
+var STRIP_INT_NIGHT = Color{150, 190, 240} // blue
+var STRIP_EXT_NIGHT = Color{150, 215, 140} // green
+
+// words in a scene's time of day that decide its colour;
+// anything else follows the scene before
+var STRIP_NIGHT = [...]string{"NIGHT", "EVENING", "DUSK", "SUNSET", "MIDNIGHT"}
+var STRIP_DAY   = [...]string{"DAY", "MORNING", "AFTERNOON", "NOON", "DAWN", "SUNRISE"}
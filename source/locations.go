@@ -0,0 +1,298 @@
+/*
+	Meander
+	A portable Fountain utility for production writing
+	Copyright (C) 2022-2023 Harley Denham
+*/
+
+package main
+
+import "fmt"
+import "sort"
+import "strings"
+import "unicode"
+
+import "github.com/lichendust/meander/fountain"
+
+// one level of a location, such as the kitchen in
+// "JOHN'S HOUSE - KITCHEN"; a set's totals include
+// everything filmed in the sets beneath it
+type Set struct {
+	name  string
+	group string
+
+	scenes   int
+	eighths  int
+	settings []string
+	times    []string
+
+	parent   *Set
+	children []*Set
+	lookup   map[string]*Set
+}
+
+func command_locations(config *Config) {
+	if config.output_format != "" && config.output_format != CSV_EXT {
+		eprintln("error: the location report can only be written as csv")
+		return
+	}
+
+	data, success := parse_file(config)
+	if !success {
+		return
+	}
+
+	scenes := collect_scenes(config, data)
+
+	if len(scenes) == 0 {
+		eprintf("locations: %q has no scenes", config.source_file)
+		return
+	}
+
+	root := build_sets(data, scenes)
+
+	if config.output_file != "" {
+		rows := make([][]string, 0, 64)
+		rows = append(rows, []string{
+			"Location", "Parent", "Group", "I/E", "Time", "Scenes", "Eighths", "Pages",
+		})
+
+		walk_sets(root, func(set *Set, depth int) {
+			parent := ""
+			if set.parent != root {
+				parent = set_path(set.parent)
+			}
+			rows = append(rows, []string{
+				set_path(set),
+				parent,
+				set.group,
+				strings.Join(set.settings, ", "),
+				strings.Join(set.times, ", "),
+				fmt.Sprintf("%d", set.scenes),
+				fmt.Sprintf("%d", set.eighths),
+				fmt.Sprintf("%.3f", float64(set.eighths) / 8),
+			})
+		})
+
+		if !write_csv(config.output_file, rows) {
+			eprintln("failed to write", config.output_file)
+		}
+		return
+	}
+
+	print_sets(data, root)
+}
+
+// build_sets files every scene under its canonical location,
+// adding it to the totals of each level on the way down
+func build_sets(data *Fountain, scenes []*Scene) *Set {
+	lookup := location_lookup(data.document.Locations)
+
+	root := &Set{lookup: make(map[string]*Set, 32)}
+
+	for _, scene := range scenes {
+		parts, groups := canonical_location(lookup, scene.location)
+
+		set := root
+
+		for i, part := range parts {
+			key := location_key(part)
+
+			child, ok := set.lookup[key]
+			if !ok {
+				child = &Set{
+					name:   part,
+					group:  groups[i],
+					parent: set,
+					lookup: make(map[string]*Set, 4),
+				}
+				set.lookup[key] = child
+				set.children = append(set.children, child)
+			}
+			set = child
+
+			set.scenes  += 1
+			set.eighths += scene.eighths
+
+			if scene.setting != "" {
+				set.settings = append_unique(set.settings, scene.setting)
+			}
+			if scene.time != "" {
+				set.times = append_unique(set.times, strings.ToUpper(scene.time))
+			}
+		}
+	}
+
+	sort_sets(root)
+
+	return root
+}
+
+// the busiest sets come first, then the order
+// in which they first appear in the script
+func sort_sets(set *Set) {
+	sort.SliceStable(set.children, func(i, j int) bool {
+		return set.children[i].eighths > set.children[j].eighths
+	})
+	for _, child := range set.children {
+		sort_sets(child)
+	}
+}
+
+func walk_sets(set *Set, f func(*Set, int)) {
+	var walk func(*Set, int)
+	walk = func(set *Set, depth int) {
+		for _, child := range set.children {
+			f(child, depth)
+			walk(child, depth + 1)
+		}
+	}
+	walk(set, 0)
+}
+
+func set_path(set *Set) string {
+	parts := make([]string, 0, 4)
+	for ; set != nil && set.parent != nil; set = set.parent {
+		parts = append([]string{set.name}, parts...)
+	}
+	return strings.Join(parts, " - ")
+}
+
+// location_lookup maps the key of every name in the
+// [location.x] tables to its entry
+func location_lookup(locations []fountain.Location) map[string]*fountain.Location {
+	lookup := make(map[string]*fountain.Location, len(locations) * 2)
+
+	for i := range locations {
+		l := &locations[i]
+		lookup[location_key(l.Name)] = l
+		for _, name := range l.OtherNames {
+			lookup[location_key(name)] = l
+		}
+	}
+
+	return lookup
+}
+
+// canonical_location splits a location into its levels, such
+// as "JOHN'S HOUSE - KITCHEN" or "JOHNS HOUSE, KITCHEN", and
+// swaps each one, or the whole thing, for the name given in
+// a [location.x] table, alongside the x of that table.
+// anything not in a table is given in title case, as the
+// characters are.
+func canonical_location(lookup map[string]*fountain.Location, text string) ([]string, []string) {
+	whole := ""
+
+	if l, ok := lookup[location_key(text)]; ok {
+		text  = l.Name
+		whole = l.Group
+	}
+
+	parts  := split_location(text)
+	groups := make([]string, len(parts), len(parts) + 1)
+
+	for i, part := range parts {
+		if l, ok := lookup[location_key(part)]; ok {
+			parts[i]  = l.Name
+			groups[i] = l.Group
+		} else if is_caps_word(part) {
+			parts[i] = title_case(strings.ToLower(part))
+		}
+	}
+
+	if len(parts) == 0 {
+		parts  = append(parts, LOCATION_UNKNOWN)
+		groups = append(groups, "")
+	}
+
+	// a table entry for the whole location
+	// belongs to its last level
+	if whole != "" {
+		groups[len(groups) - 1] = whole
+	}
+
+	return parts, groups
+}
+
+func split_location(text string) []string {
+	for _, dash := range [...]string{" – ", " — ", ","} {
+		text = strings.ReplaceAll(text, dash, " - ")
+	}
+
+	parts := make([]string, 0, 4)
+
+	for _, part := range strings.Split(text, " - ") {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+
+	return parts
+}
+
+// location_key reduces a location to a form that small
+// differences in spelling don't change: capitals, without
+// apostrophes, and with any other punctuation as spaces
+func location_key(text string) string {
+	buffer := strings.Builder{}
+	buffer.Grow(len(text))
+
+	space := false
+
+	for _, c := range strings.ToUpper(text) {
+		switch {
+		case c == '\'' || c == '’':
+			continue
+		case unicode.IsLetter(c) || unicode.IsNumber(c):
+			if space && buffer.Len() > 0 {
+				buffer.WriteRune(' ')
+			}
+			space = false
+			buffer.WriteRune(c)
+		default:
+			space = true
+		}
+	}
+
+	return buffer.String()
+}
+
+func print_sets(data *Fountain, root *Set) {
+	longest_name    := rune_count(LOCATION_COLUMN)
+	longest_setting := rune_count(BREAKDOWN_SETTING)
+
+	walk_sets(root, func(set *Set, depth int) {
+		if n := rune_count(set.name) + depth * 4; n > longest_name {
+			longest_name = n
+		}
+		if n := rune_count(strings.Join(set.settings, ", ")); n > longest_setting {
+			longest_setting = n
+		}
+	})
+
+	println_color("\n   ", clean_string(data.Title.Title), LOCATION_HEADING)
+
+	print("\n    ")
+	print_padded(LOCATION_COLUMN,   longest_name)
+	print_padded(LOCATION_SCENES,   6)
+	print_padded(LOCATION_PAGES,    7)
+	print_padded(BREAKDOWN_SETTING, longest_setting)
+	println(BREAKDOWN_TIME)
+
+	print("    ")
+	print_dashes(longest_name + longest_setting + 30)
+
+	walk_sets(root, func(set *Set, depth int) {
+		if depth == 0 && set != root.children[0] {
+			println()
+		}
+
+		print("    ")
+		print_padded(strings.Repeat("    ", depth) + set.name, longest_name)
+		print_padded(fmt.Sprintf("%d", set.scenes), 6)
+		print_padded(format_eighths(set.eighths), 7)
+		print_padded(strings.Join(set.settings, ", "), longest_setting)
+		println(strings.Join(set.times, ", "))
+	})
+
+	print("\n")
+}
@@ -7,6 +7,7 @@
 package main
 
 import "os"
+import "path/filepath"
 
 import lib "github.com/signintech/gopdf"
 
@@ -21,11 +22,18 @@ func main() {
 
 	switch config.command {
 	case COMMAND_RENDER:
+		if config.output_format != "" && config.output_format != PDF_EXT {
+			export_file(config)
+			break
+		}
 		command_render(config)
 
 	case COMMAND_MERGE:
 		command_merge(config)
 
+	case COMMAND_ARCHIVE:
+		command_archive(config)
+
 	case COMMAND_DATA:
 		command_data(config)
 
@@ -35,6 +43,32 @@ func main() {
 	case COMMAND_CONVERT:
 		command_convert(config)
 
+	case COMMAND_BREAKDOWN:
+		command_breakdown(config)
+
+	case COMMAND_STRIPBOARD:
+		command_stripboard(config)
+
+	case COMMAND_LOCATIONS:
+		command_locations(config)
+
+	case COMMAND_CROSSPLOT:
+		command_crossplot(config)
+
+	case COMMAND_SIDES:
+		command_sides(config)
+
+	case COMMAND_RUNTIME:
+		command_runtime(config)
+
+	case COMMAND_WORDS:
+		command_words(config)
+
+	case COMMAND_CHECK:
+		if !command_check(config) {
+			os.Exit(1)
+		}
+
 	case COMMAND_FONTS:
 		export_fonts()
 
@@ -60,13 +94,24 @@ func main() {
 }
 
 const FOUNTAIN_EXT = ".fountain"
+const PDF_EXT      = ".pdf"
+const JSON_EXT     = ".json"
 
 const (
 	COMMAND_RENDER uint8 = iota
 	COMMAND_MERGE
+	COMMAND_ARCHIVE
 	COMMAND_GENDER
 	COMMAND_DATA
 	COMMAND_CONVERT
+	COMMAND_CHECK
+	COMMAND_BREAKDOWN
+	COMMAND_STRIPBOARD
+	COMMAND_LOCATIONS
+	COMMAND_CROSSPLOT
+	COMMAND_SIDES
+	COMMAND_RUNTIME
+	COMMAND_WORDS
 	COMMAND_HELP
 	COMMAND_VERSION
 	COMMAND_CREDIT
@@ -89,6 +134,17 @@ type Config struct {
 	include_sections  bool
 	include_gender    bool
 	table_of_contents bool
+	json_output       bool
+
+	data_paginate bool
+	data_schema   bool
+
+	strip_order string
+	sides_list  string
+
+	highlight_chars []string
+
+	default_gender string
 
 	template_set    bool
 	template        Format
@@ -101,8 +157,9 @@ type Config struct {
 	starred_only   bool
 	starred_target string
 
-	source_file string
-	output_file string
+	source_file   string
+	output_file   string
+	output_format string // an extension, if set
 }
 
 func arg_scene_type(x string) (uint8, bool) {
@@ -117,6 +174,30 @@ func arg_scene_type(x string) (uint8, bool) {
 	return SCENE_INPUT, false
 }
 
+func arg_output_format(x string) (string, bool) {
+	switch homogenise(x) {
+	case "pdf":
+		return PDF_EXT, true
+	case "fdx", "finaldraft":
+		return FD_EXT, true
+	case "osf":
+		return OSF_EXT, true
+	case "fadein":
+		return FADEIN_EXT, true
+	case "html", "htm":
+		return HTML_EXT, true
+	case "txt", "text":
+		return TXT_EXT, true
+	case "epub":
+		return EPUB_EXT, true
+	case "docx", "word":
+		return DOCX_EXT, true
+	case "csv":
+		return CSV_EXT, true
+	}
+	return "", false
+}
+
 func get_arguments() (*Config, bool) {
 	const SEE_HELP_RENDER = "see $1meander help render$0 for full usage"
 
@@ -145,6 +226,10 @@ func get_arguments() (*Config, bool) {
 			config.command = COMMAND_MERGE
 			continue
 
+		case "archive":
+			config.command = COMMAND_ARCHIVE
+			continue
+
 		case "data":
 			config.command = COMMAND_DATA
 			continue
@@ -157,6 +242,38 @@ func get_arguments() (*Config, bool) {
 			config.command = COMMAND_CONVERT
 			continue
 
+		case "check":
+			config.command = COMMAND_CHECK
+			continue
+
+		case "breakdown":
+			config.command = COMMAND_BREAKDOWN
+			continue
+
+		case "stripboard":
+			config.command = COMMAND_STRIPBOARD
+			continue
+
+		case "locations":
+			config.command = COMMAND_LOCATIONS
+			continue
+
+		case "crossplot":
+			config.command = COMMAND_CROSSPLOT
+			continue
+
+		case "sides":
+			config.command = COMMAND_SIDES
+			continue
+
+		case "runtime":
+			config.command = COMMAND_RUNTIME
+			continue
+
+		case "words":
+			config.command = COMMAND_WORDS
+			continue
+
 		case "help":
 			config.command = COMMAND_HELP
 			return config, true
@@ -174,6 +291,13 @@ func get_arguments() (*Config, bool) {
 			return config, true
 		}
 
+		// the scene list for sides comes straight after the
+		// input file, and can be as short as a single scene
+		if config.command == COMMAND_SIDES && patharg == 1 && config.sides_list == "" && arg != "" && arg[0] != '-' {
+			config.sides_list = arg
+			continue
+		}
+
 		// there shouldn't be any arguments shorter than 2
 		// that we aren't expecting as additional values
 		if len(arg) < 2 {
@@ -188,7 +312,9 @@ func get_arguments() (*Config, bool) {
 		} else {
 			switch patharg {
 			case 0:
-				config.source_file = arg
+				// folders like .scriv projects often get
+				// a trailing slash from tab-completion
+				config.source_file = filepath.Clean(arg)
 			case 1:
 				config.output_file = arg
 			default:
@@ -215,6 +341,15 @@ func get_arguments() (*Config, bool) {
 		case "print-gender", "g":
 			config.include_gender = true
 
+		case "json":
+			config.json_output = true
+
+		case "paginate":
+			config.data_paginate = true
+
+		case "schema":
+			config.data_schema = true
+
 		case "stars-only":
 			config.starred_only = true
 			fallthrough
@@ -278,27 +413,102 @@ func get_arguments() (*Config, bool) {
 			config.paper_size = x
 			index += 1
 
+		case "highlight-character":
+			if index > max {
+				eprintln(apply_color("error: the --highlight-character flag requires a name\n\n" + SEE_HELP_RENDER))
+				return config, false
+			}
+
+			config.highlight_chars = append(config.highlight_chars, args[index])
+			index += 1
+
+		case "default-gender":
+			if index > max {
+				eprintln(apply_color("error: the --default-gender flag requires a gender\n\nsee $1meander help gender$0 for full usage"))
+				return config, false
+			}
+
+			config.default_gender = args[index]
+			index += 1
+
+		case "order":
+			if index > max {
+				eprintln(apply_color("error: the --order flag requires a file\n\nsee $1meander help stripboard$0 for full usage"))
+				return config, false
+			}
+
+			config.strip_order = args[index]
+			index += 1
+
+		case "output-format", "o":
+			if index > max {
+				eprintln(apply_color("error: the --output-format flag requires a value\n\n    pdf\n    fdx\n    osf\n    fadein\n    html\n    txt\n    epub\n    docx\n    csv\n\n" + SEE_HELP_RENDER))
+				return config, false
+			}
+
+			x, success := arg_output_format(args[index])
+			if !success {
+				eprintln("error: unknown output format")
+				return config, false
+			}
+
+			config.output_format = x
+			index += 1
+
 		default:
 			eprintf("error: %q flag is unknown", arg)
 			return config, false
 		}
 	}
 
+	// the schema doesn't need a file
+	if config.command == COMMAND_DATA && config.data_schema {
+		return config, true
+	}
+
 	if config.source_file == "" {
 		eprintln("error: no input file specified!")
+		if config.command == COMMAND_CHECK {
+			os.Exit(1)
+		}
 		return config, false
 	}
 
 	if config.output_file == "" {
 		switch config.command {
 		case COMMAND_RENDER:
-			config.output_file = rewrite_ext(config.source_file, ".pdf")
+			if config.output_format != "" {
+				config.output_file = rewrite_ext(config.source_file, config.output_format)
+			} else {
+				config.output_file = rewrite_ext(config.source_file, PDF_EXT)
+			}
 		case COMMAND_MERGE:
 			config.output_file = rewrite_ext(config.source_file, "_merged" + FOUNTAIN_EXT)
+		case COMMAND_ARCHIVE:
+			config.output_file = rewrite_ext(config.source_file, TXT_EXT)
 		case COMMAND_CONVERT:
-			config.output_file = rewrite_ext(config.source_file, FOUNTAIN_EXT)
+			if config.output_format != "" {
+				config.output_file = rewrite_ext(config.source_file, config.output_format)
+			} else if filepath.Ext(config.source_file) == FOUNTAIN_EXT {
+				config.output_file = rewrite_ext(config.source_file, FD_EXT)
+			} else {
+				config.output_file = rewrite_ext(config.source_file, FOUNTAIN_EXT)
+			}
 		case COMMAND_DATA:
-			config.output_file = rewrite_ext(config.source_file, ".json")
+			config.output_file = rewrite_ext(config.source_file, JSON_EXT)
+		case COMMAND_BREAKDOWN:
+			config.output_file = rewrite_ext(config.source_file, "_breakdown" + report_ext(config))
+		case COMMAND_STRIPBOARD:
+			config.output_file = rewrite_ext(config.source_file, "_stripboard" + PDF_EXT)
+		case COMMAND_LOCATIONS:
+			// the report goes to the terminal unless csv is asked for
+			if config.output_format == CSV_EXT {
+				config.output_file = rewrite_ext(config.source_file, "_locations" + CSV_EXT)
+			}
+		case COMMAND_CROSSPLOT:
+			config.output_file = rewrite_ext(config.source_file, "_crossplot" + report_ext(config))
+		case COMMAND_SIDES:
+			config.output_file = rewrite_ext(config.source_file, "_sides" + PDF_EXT)
 		}
 	}
 
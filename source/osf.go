@@ -0,0 +1,275 @@
+/*
+	Meander
+	A portable Fountain utility for production writing
+	Copyright (C) 2022-2023 Harley Denham
+*/
+
+package main
+
+import "os"
+import "bytes"
+import "strings"
+import "archive/zip"
+import "encoding/xml"
+import "path/filepath"
+
+const OSF_EXT    = ".osf"
+const FADEIN_EXT = ".fadein"
+
+// Fade In saves Open Screenplay Format as a zip
+// with the document inside it under this name
+const FADEIN_DOCUMENT = "document.xml"
+
+// Open Screenplay Format has the same shape as Final Draft
+// (a flat list of typed paragraphs made of styled runs of
+// text) so we read it into a Final_Draft and hand it on,
+// and build exports from one going the other way
+type OSF_Document struct {
+	XMLName    xml.Name         `xml:"document"`
+	Type       string           `xml:"type,attr,omitempty"`
+	Version    string           `xml:"version,attr,omitempty"`
+	Title      []*OSF_Paragraph `xml:"titlepage>para"`
+	Paragraphs []*OSF_Paragraph `xml:"paragraphs>para"`
+}
+
+type OSF_Paragraph struct {
+	Number string      `xml:"number,attr,omitempty"`
+	Style  OSF_Style   `xml:"style"`
+	Text   []*OSF_Text `xml:"text"`
+}
+
+type OSF_Style struct {
+	Base  string `xml:"basestylename,attr,omitempty"`
+	Align string `xml:"align,attr,omitempty"`
+	Dual  string `xml:"dualdialogue,attr,omitempty"`
+}
+
+type OSF_Text struct {
+	Bold      string `xml:"bold,attr,omitempty"`
+	Italic    string `xml:"italic,attr,omitempty"`
+	Underline string `xml:"underline,attr,omitempty"`
+	Strikeout string `xml:"strikeout,attr,omitempty"`
+	Text      string `xml:",chardata"`
+}
+
+func convert_osf(config *Config) {
+	byte_stream, success := load_osf(config.source_file)
+	if !success {
+		eprintf("failed to load %q", config.source_file)
+		return
+	}
+
+	data := new(OSF_Document)
+
+	err := xml.Unmarshal(byte_stream, data)
+	if err != nil {
+		eprintf("failed to load %q", config.source_file)
+		return
+	}
+
+	success = write_file(fix_path(config.output_file), []byte(final_draft_fountain(osf_to_final_draft(data))))
+	if !success {
+		eprintln("failed to write", config.output_file)
+	}
+}
+
+// load_osf reads a plain .osf file, or pulls the
+// document out of a zipped .fadein one
+func load_osf(file_name string) ([]byte, bool) {
+	if filepath.Ext(file_name) != FADEIN_EXT {
+		byte_stream, err := os.ReadFile(file_name)
+		return byte_stream, err == nil
+	}
+
+	archive, err := zip.OpenReader(file_name)
+	if err != nil {
+		return nil, false
+	}
+	defer archive.Close()
+
+	for _, file := range archive.File {
+		if file.Name == FADEIN_DOCUMENT {
+			return read_zip_file(file)
+		}
+	}
+
+	return nil, false
+}
+
+func osf_to_final_draft(data *OSF_Document) *Final_Draft {
+	output := new(Final_Draft)
+
+	for _, paragraph := range data.Title {
+		output.Title = append(output.Title, osf_paragraph(paragraph))
+	}
+
+	// dual dialogue is marked on every paragraph in the
+	// block, so we gather a run of them into one holder,
+	// starting a new one if a third speaker turns up
+	var dual *XML_Dual_Dialogue
+	speakers := 0
+
+	for _, paragraph := range data.Paragraphs {
+		converted := osf_paragraph(paragraph)
+
+		if paragraph.Style.Dual != "1" {
+			dual = nil
+			output.Content = append(output.Content, converted)
+			continue
+		}
+
+		if converted.Type == "Character" {
+			speakers += 1
+		}
+
+		if dual == nil || (converted.Type == "Character" && speakers > 2) {
+			dual     = new(XML_Dual_Dialogue)
+			speakers = 1
+			output.Content = append(output.Content, &XML_Paragraph{Dual: dual})
+		}
+
+		dual.Content = append(dual.Content, converted)
+	}
+
+	return output
+}
+
+func osf_paragraph(paragraph *OSF_Paragraph) *XML_Paragraph {
+	output := &XML_Paragraph{
+		Type:   paragraph.Style.Base,
+		Number: paragraph.Number,
+	}
+
+	switch paragraph.Style.Base {
+	case "Lyric", "Lyrics":
+		output.Type = "Lyrics"
+	}
+
+	switch strings.ToLower(paragraph.Style.Align) {
+	case "center", "centre":
+		output.Alignment = "Center"
+	case "right":
+		output.Alignment = "Right"
+	}
+
+	for _, text := range paragraph.Text {
+		styles := make([]string, 0, 4)
+
+		if text.Bold      == "1" { styles = append(styles, "Bold") }
+		if text.Italic    == "1" { styles = append(styles, "Italic") }
+		if text.Underline == "1" { styles = append(styles, "Underline") }
+		if text.Strikeout == "1" { styles = append(styles, "Strikeout") }
+
+		output.Chunks = append(output.Chunks, &XML_Chunk{
+			Style: strings.Join(styles, "+"),
+			Text:  text.Text,
+		})
+	}
+
+	return output
+}
+
+func export_osf(config *Config) {
+	data, success := parse_file(config)
+	if !success {
+		return
+	}
+
+	output := final_draft_to_osf(build_final_draft(data))
+
+	blob, err := xml.MarshalIndent(output, "", "\t")
+	if err != nil {
+		eprintln("failed to marshal", config.output_file)
+		return
+	}
+
+	blob = append([]byte(xml.Header), blob...)
+	blob = append(blob, '\n')
+
+	ext := config.output_format
+	if ext == "" {
+		ext = filepath.Ext(config.output_file)
+	}
+
+	if ext == FADEIN_EXT {
+		buffer := new(bytes.Buffer)
+		writer := zip.NewWriter(buffer)
+
+		file, err := writer.CreateHeader(&zip.FileHeader{
+			Name:     FADEIN_DOCUMENT,
+			Method:   zip.Deflate,
+			Modified: now(),
+		})
+		if err == nil {
+			_, err = file.Write(blob)
+		}
+		if err != nil || writer.Close() != nil {
+			eprintln("failed to build", config.output_file)
+			return
+		}
+
+		blob = buffer.Bytes()
+	}
+
+	success = write_file(fix_path(config.output_file), blob)
+	if !success {
+		eprintln("failed to write", config.output_file)
+	}
+}
+
+// final_draft_to_osf is the reverse of osf_to_final_draft.
+// revision sets and highlights have no place to go in
+// OSF, so they're dropped.
+func final_draft_to_osf(data *Final_Draft) *OSF_Document {
+	output := &OSF_Document{
+		Type:    "Open Screenplay Format document",
+		Version: "40",
+	}
+
+	for _, paragraph := range data.Title {
+		output.Title = append(output.Title, osf_from_final_draft(paragraph, false))
+	}
+
+	for _, paragraph := range data.Content {
+		if paragraph.Dual != nil {
+			for _, inner := range paragraph.Dual.Content {
+				output.Paragraphs = append(output.Paragraphs, osf_from_final_draft(inner, true))
+			}
+			continue
+		}
+		output.Paragraphs = append(output.Paragraphs, osf_from_final_draft(paragraph, false))
+	}
+
+	return output
+}
+
+func osf_from_final_draft(paragraph *XML_Paragraph, is_dual bool) *OSF_Paragraph {
+	output := &OSF_Paragraph{
+		Number: paragraph.Number,
+		Style:  OSF_Style{
+			Base:  paragraph.Type,
+			Align: strings.ToLower(paragraph.Alignment),
+		},
+	}
+
+	if is_dual {
+		output.Style.Dual = "1"
+	}
+
+	for _, chunk := range paragraph.Chunks {
+		text := &OSF_Text{Text: chunk.Text}
+
+		for _, style := range strings.Split(chunk.Style, "+") {
+			switch style {
+			case "Bold":      text.Bold      = "1"
+			case "Italic":    text.Italic    = "1"
+			case "Underline": text.Underline = "1"
+			case "Strikeout": text.Strikeout = "1"
+			}
+		}
+
+		output.Text = append(output.Text, text)
+	}
+
+	return output
+}
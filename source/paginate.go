@@ -11,6 +11,8 @@ import "sort"
 import "strings"
 import "strconv"
 
+import "github.com/lichendust/meander/fountain"
+
 func line_override(line *Line, style Leaf_Type) {
 	if style & UNDERLINE != 0 { line.underline = []int{0, line.length} }
 	if style & STRIKEOUT != 0 { line.strikeout = []int{0, line.length} }
@@ -63,6 +65,10 @@ func paginate(config *Config, data *Fountain) {
 
 	var last_char *Section
 
+	// the sections and scenes starting since the last page,
+	// which the counters catch up with before the next header
+	next_scope := find_word_scope(original_content, 4)
+
 	new_page := func() {
 		do_header(data, data.footer, FOOTER, page_number)
 
@@ -72,10 +78,15 @@ func paginate(config *Config, data *Fountain) {
 		first_on_page = true
 
 		data.counter_lookup["page"].value = page_number
+		next_scope = set_word_scope(data, next_scope)
 
 		do_header(data, data.header, HEADER, page_number)
 	}
 
+	data.counter_lookup["sectionwords"].value = 0
+	data.counter_lookup["scenewords"].value   = 0
+	next_scope = set_word_scope(data, next_scope)
+
 	// initial header/footer, if any
 	do_header(data, data.header, HEADER, page_number)
 	do_header(data, data.footer, FOOTER, page_number)
@@ -83,6 +94,11 @@ func paginate(config *Config, data *Fountain) {
 	for content_index := range original_content {
 		section := &original_content[content_index]
 
+		// anything left over from the last section
+		// didn't need a new page, so it's safe to
+		// catch up now without upsetting the footer
+		next_scope = set_word_scope(data, next_scope)
+
 		if section.Type == WHITESPACE && inside_dual_dialogue == 1 {
 			continue
 		}
@@ -116,6 +132,10 @@ func paginate(config *Config, data *Fountain) {
 			section.Type += Section_Type(section.Level - 1)
 		}
 
+		if section.Type == SCENE || section.Type > is_section {
+			next_scope = append(next_scope, section)
+		}
+
 		t := template.types[section.Type]
 
 		section.skip = t.skip
@@ -241,9 +261,11 @@ func paginate(config *Config, data *Fountain) {
 							justify: local_t.justify,
 							page:    old_page_number,
 							is_raw:  true,
-							Type:    PARENTHETICAL + dual_offset,
-							Text:    "(more)",
-							Level:   section.Level,
+							Section: fountain.Section{
+								Type:  PARENTHETICAL + dual_offset,
+								Text:  "(more)",
+								Level: section.Level,
+							},
 						})
 
 						local_t = template.types[CHARACTER + dual_offset]
@@ -262,9 +284,11 @@ func paginate(config *Config, data *Fountain) {
 							justify: local_t.justify,
 							page:    page_number,
 							is_raw:  true,
-							Type:    CHARACTER + dual_offset,
-							Text:    new_text,
-							Level:   section.Level,
+							Section: fountain.Section{
+								Type:  CHARACTER + dual_offset,
+								Text:  new_text,
+								Level: section.Level,
+							},
 						})
 
 						running_height += local_t.line_height
@@ -304,6 +328,7 @@ func paginate(config *Config, data *Fountain) {
 
 		case PAGE_BREAK:
 			find_header_or_footer(data, original_content[content_index:], 4)
+			next_scope = append(next_scope, find_word_scope(original_content[content_index:], 4)...)
 			new_page()
 
 		case WHITESPACE:
@@ -320,6 +345,7 @@ func paginate(config *Config, data *Fountain) {
 
 				if running_height > max_page_height {
 					find_header_or_footer(data, original_content[content_index:], 4)
+					next_scope = append(next_scope, find_word_scope(original_content[content_index:], 4)...)
 					new_page()
 				}
 			}
@@ -579,6 +605,7 @@ func break_section(data *Fountain, section *Section, width float64, para_indent
 		format.text_width    = rune_count(the_word)
 		format.space_width   = space_width
 		format.counter_reset = counter_reset
+		format.offset        = len(section.Text) - len(input)
 
 		if the_type == ESCAPE {
 			format.space_only = true
@@ -674,8 +701,13 @@ func break_section(data *Fountain, section *Section, width float64, para_indent
 
 			switch entry.leaf_type {
 			default:
+				before := entry.leaf_type
+
 				s := &state[entry.leaf_type]
 				s.live = inline_balance(entry, s.live)
+				if entry.leaf_type != before {
+					lint_emphasis(data, section, entry, before)
+				}
 				if entry.is_opening {
 					s.last = entry
 				}
@@ -692,6 +724,10 @@ func break_section(data *Fountain, section *Section, width float64, para_indent
 				b.live = x
 				i.live = x
 
+				if entry.leaf_type == NORMAL {
+					lint_emphasis(data, section, entry, BOLD | ITALIC)
+				}
+
 				if entry.is_opening {
 					b.last = entry
 					i.last = entry
@@ -700,7 +736,7 @@ func break_section(data *Fountain, section *Section, width float64, para_indent
 			case COUNTER, COUNTER_ALPHA:
 				word := homogenise(entry.text[1:])
 				switch word {
-				case "page", "scene", "wordcount":
+				case "page", "scene", "wordcount", "sectionwords", "scenewords", "dialoguewords", "narrationwords":
 					entry.text = fmt.Sprintf("%d", data.counter_lookup[word].value)
 
 				default:
@@ -774,7 +810,11 @@ func break_section(data *Fountain, section *Section, width float64, para_indent
 		// styling is unbalanced, so we revert it
 		for i := range state {
 			if state[i].live {
-				state[i].last.leaf_type = NORMAL
+				last := state[i].last
+				if last.leaf_type != NORMAL {
+					lint_emphasis(data, section, last, last.leaf_type)
+				}
+				last.leaf_type = NORMAL
 			}
 		}
 	}
@@ -0,0 +1,668 @@
+/*
+	Meander
+	A portable Fountain utility for production writing
+	Copyright (C) 2022-2023 Harley Denham
+*/
+
+package main
+
+import "os"
+import "strings"
+import "unicode"
+
+// where each element starts in an industry-layout script,
+// in characters (at ten to the inch) from the left edge of
+// the action.  anything between two of these is given to
+// whichever it's nearest.
+const (
+	TEXT_DIALOGUE      = 10 // 2.5"
+	TEXT_PARENTHETICAL = 16 // 3.1"
+	TEXT_CHARACTER     = 22 // 3.7"
+	TEXT_TRANSITION    = 45 // 6.0"
+
+	// the width of the action, and so the
+	// centre of the page, in characters
+	TEXT_WIDTH = 60
+)
+
+type Text_Line struct {
+	text   string
+	indent int
+	number string // a scene number, pulled off either side
+	page   int
+}
+
+// convert_text reads a screenplay that only survives as
+// plain text and works out what each line is from where
+// it sits on the page and how it's written.  the result
+// is built as a Final_Draft, so the same force-characters
+// are added wherever Fountain would guess differently.
+func convert_text(config *Config) {
+	byte_stream, err := os.ReadFile(config.source_file)
+	if err != nil {
+		eprintf("failed to load %q", config.source_file)
+		return
+	}
+
+	lines := text_lines(string(byte_stream))
+	base  := text_base_column(lines)
+
+	data := new(Final_Draft)
+
+	lines, data.Title = text_title_page(lines, base)
+	data.Content      = text_paragraphs(lines, base)
+
+	success := write_file(fix_path(config.output_file), []byte(final_draft_fountain(data)))
+	if !success {
+		eprintln("failed to write", config.output_file)
+	}
+}
+
+// text_lines splits the file into lines, with tabs expanded
+// and any scene numbers in the margins set aside
+func text_lines(input string) []Text_Line {
+	input = strings.ReplaceAll(input, "\r\n", "\n")
+	input = strings.ReplaceAll(input, "\r", "\n")
+
+	lines := make([]Text_Line, 0, 1024)
+
+	for page, text := range strings.Split(input, "\f") {
+		for _, raw := range strings.Split(text, "\n") {
+			raw = expand_tabs(strings.TrimRight(raw, " \t"))
+
+			trimmed := strings.TrimLeft(raw, " ")
+			line    := Text_Line{
+				text:   trimmed,
+				indent: rune_count(raw) - rune_count(trimmed),
+				page:   page,
+			}
+
+			// "12   INT. HOUSE - DAY   12"
+			fields := strings.Fields(trimmed)
+			if len(fields) > 1 && is_text_scene_number(fields[0]) {
+				rest := left_trim(trimmed[len(fields[0]):])
+
+				if fields[len(fields) - 1] == fields[0] {
+					rest = strings.TrimRight(rest[:len(rest) - len(fields[0])], " ")
+				}
+
+				if is_text_upper(rest) && (is_valid_scene(rest) || fields[len(fields) - 1] == fields[0]) {
+					line.number = fields[0]
+					line.indent = rune_count(raw) - rune_count(strings.TrimLeft(raw[len(raw) - len(trimmed) + len(fields[0]):], " "))
+					line.text   = rest
+				}
+			}
+
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
+
+// text_base_column finds the left edge of the action,
+// which is where the most lines too long to be dialogue
+// begin.  scene numbers and page furniture can sit to
+// the left of it, so the shortest indent won't do.
+func text_base_column(lines []Text_Line) int {
+	counts := make(map[int]int, 16)
+
+	for _, line := range lines {
+		if rune_count(line.text) > TEXT_WIDTH * 3 / 4 {
+			counts[line.indent] += 1
+		}
+	}
+
+	base := -1
+	for indent, count := range counts {
+		if base < 0 || count > counts[base] || (count == counts[base] && indent < base) {
+			base = indent
+		}
+	}
+
+	if base >= 0 {
+		return base
+	}
+
+	// a script with no long lines at all
+	for _, line := range lines {
+		if line.text != "" && (base < 0 || line.indent < base) {
+			base = line.indent
+		}
+	}
+
+	if base < 0 {
+		return 0
+	}
+	return base
+}
+
+// text_title_page takes the first page as a title page,
+// if there's a page break to mark where it ends and it
+// has no scenes or speeches on it
+func text_title_page(lines []Text_Line, base int) ([]Text_Line, []*XML_Paragraph) {
+	end := 0
+	for end < len(lines) && lines[end].page == 0 {
+		end += 1
+	}
+
+	if end == len(lines) {
+		return lines, nil
+	}
+
+	kinds := text_kinds(lines[:end], base)
+	for _, kind := range kinds {
+		if kind == "Scene Heading" || kind == "Character" || kind == "Dual" {
+			return lines, nil
+		}
+	}
+
+	title := make([]*XML_Paragraph, 0, 16)
+
+	for _, line := range lines[:end] {
+		if line.text == "" {
+			continue
+		}
+
+		paragraph := &XML_Paragraph{
+			Chunks: []*XML_Chunk{{Text: line.text}},
+		}
+
+		centre := line.indent - base + rune_count(line.text) / 2
+		if centre > TEXT_WIDTH / 2 - 5 && centre < TEXT_WIDTH / 2 + 5 {
+			paragraph.Alignment = "Center"
+		}
+
+		title = append(title, paragraph)
+	}
+
+	return lines[end:], title
+}
+
+// text_kinds guesses the Final Draft type of every line,
+// leaving blank lines and page furniture empty.  "More"
+// marks a speech that carries on over the page, and "Dual"
+// every line of a dual dialogue block, which is split into
+// its two speeches later.
+func text_kinds(lines []Text_Line, base int) []string {
+	kinds := make([]string, len(lines))
+
+	// the next line that isn't empty or page furniture,
+	// unless a blank line comes first
+	next_line := func(i int) *Text_Line {
+		for i += 1; i < len(lines); i++ {
+			if lines[i].text == "" {
+				return nil
+			}
+			if !is_text_furniture(&lines[i], base) {
+				return &lines[i]
+			}
+		}
+		return nil
+	}
+
+	is_alone := func(i int) bool {
+		return (i == 0 || lines[i - 1].text == "") && next_line(i) == nil
+	}
+
+	in_speech := false
+	in_dual   := false
+
+	for i := range lines {
+		line := &lines[i]
+
+		if line.text == "" {
+			in_speech = false
+			in_dual   = false
+			continue
+		}
+
+		if is_text_furniture(line, base) {
+			continue
+		}
+
+		if strings.EqualFold(line.text, "(more)") {
+			kinds[i]  = "More"
+			in_speech = false
+			continue
+		}
+
+		if in_dual {
+			kinds[i] = "Dual"
+			continue
+		}
+
+		column := line.indent - base
+		upper  := is_text_upper(line.text)
+
+		// anything in the speech columns under a
+		// character belongs to their speech
+		if in_speech && column > (TEXT_DIALOGUE / 2) && column < (TEXT_PARENTHETICAL + TEXT_CHARACTER) / 2 {
+			if line.text[0] == '(' || (i > 0 && kinds[i - 1] == "Parenthetical" && !strings.HasSuffix(lines[i - 1].text, ")")) {
+				kinds[i] = "Parenthetical"
+			} else {
+				kinds[i] = "Dialogue"
+			}
+			continue
+		}
+
+		in_speech = false
+
+		switch {
+		case text_dual_cue(line, base) >= 0 && next_line(i) != nil:
+			kinds[i] = "Dual"
+			in_dual  = true
+
+		case column >= (TEXT_PARENTHETICAL + TEXT_CHARACTER) / 2 - 2 && column < (TEXT_CHARACTER + TEXT_TRANSITION) / 2 && upper:
+			next := next_line(i)
+
+			if next != nil && next.indent - base > TEXT_DIALOGUE / 2 && next.indent - base < (TEXT_PARENTHETICAL + TEXT_CHARACTER) / 2 {
+				kinds[i]  = "Character"
+				in_speech = true
+			} else if strings.HasSuffix(line.text, ":") || is_valid_transition(line.text) {
+				kinds[i] = "Transition"
+			} else {
+				kinds[i] = "Centered"
+			}
+
+		case column >= (TEXT_CHARACTER + TEXT_TRANSITION) / 2 && upper:
+			kinds[i] = "Transition"
+
+		case upper && strings.HasSuffix(line.text, " TO:") && is_alone(i):
+			kinds[i] = "Transition"
+
+		case column <= TEXT_DIALOGUE / 2 && upper && is_alone(i) && (line.number != "" || is_valid_scene(line.text) || strings.Contains(line.text, " - ")):
+			kinds[i] = "Scene Heading"
+
+		default:
+			kinds[i] = "Action"
+		}
+	}
+
+	return kinds
+}
+
+// text_paragraphs joins lines back up into paragraphs,
+// undoing the line-wrapping and any speeches split over
+// a page break
+func text_paragraphs(lines []Text_Line, base int) []*XML_Paragraph {
+	kinds := text_kinds(lines, base)
+
+	// the widest line of each type shows how wide that
+	// type was set, so a line is a wrap if the first
+	// word of the next wouldn't have fit on the end of it
+	widths := make(map[string]int, 8)
+	for i, kind := range kinds {
+		if n := rune_count(lines[i].text); n > widths[kind] {
+			widths[kind] = n
+		}
+	}
+
+	content := make([]*XML_Paragraph, 0, len(lines) / 2)
+
+	var last *XML_Paragraph
+	last_line := ""
+
+	speaker    := "" // the current speaker
+	more       := "" // a speaker cut off by (MORE)
+	continuing := false
+
+	for i, kind := range kinds {
+		line := &lines[i]
+
+		switch kind {
+		case "":
+			if line.text == "" {
+				last = nil
+			}
+			continue
+		case "More":
+			more = speaker
+			last = nil
+			continue
+		case "Dual":
+			// the whole block is built from its first line
+			if i > 0 && kinds[i - 1] == "Dual" {
+				continue
+			}
+
+			end := i
+			for end < len(kinds) && kinds[end] == "Dual" {
+				end += 1
+			}
+
+			content = append(content, text_dual(lines[i:end], base))
+
+			speaker = ""
+			more    = ""
+			last    = nil
+			continue
+		}
+
+		text := line.text
+
+		if kind == "Character" {
+			text = strip_contd(text)
+
+			// the same speaker picking up where they
+			// left off on the next page
+			if more != "" && text == more {
+				more       = ""
+				continuing = true
+				last       = nil
+				continue
+			}
+
+			more    = ""
+			speaker = text
+		}
+
+		if continuing {
+			continuing = false
+
+			if kind == "Dialogue" && len(content) > 0 && content[len(content) - 1].Type == "Dialogue" {
+				last      = content[len(content) - 1]
+				last_line = ""
+			}
+		}
+
+		if kind != "Dialogue" && kind != "Parenthetical" && kind != "Character" {
+			more = ""
+		}
+
+		if last != nil && last.Type == text_type(kind) && last.Alignment == text_alignment(kind) {
+			chunk := last.Chunks[0]
+
+			first_word := text
+			if n := strings.IndexRune(text, ' '); n > -1 {
+				first_word = text[:n]
+			}
+
+			if last_line == "" || rune_count(last_line) + 1 + rune_count(first_word) > widths[kind] {
+				chunk.Text += " " + text
+			} else {
+				chunk.Text += "\n" + text
+			}
+
+			last_line = text
+			continue
+		}
+
+		last = &XML_Paragraph{
+			Type:      text_type(kind),
+			Alignment: text_alignment(kind),
+			Number:    line.number,
+			Chunks:    []*XML_Chunk{{Text: text}},
+		}
+		last_line = text
+
+		content = append(content, last)
+	}
+
+	return content
+}
+
+// text_dual_cue finds two character names side by side,
+// the first indented from the action and the second in
+// the right half of the page, and returns the column the
+// second starts at, or -1 if the line isn't one
+func text_dual_cue(line *Text_Line, base int) int {
+	if line.indent - base <= 0 {
+		return -1
+	}
+
+	gaps := text_gaps(line, 3)
+	if len(gaps) != 1 || gaps[0] - base < TEXT_WIDTH / 2 - 5 {
+		return -1
+	}
+
+	left, right := text_cut(line, gaps[0])
+
+	for _, name := range [...]string{left, right} {
+		if !is_text_upper(name) || strings.HasSuffix(name, ":") || strings.ContainsAny(name[len(name) - 1:], ".!?") {
+			return -1
+		}
+		if is_valid_scene(name) || is_valid_transition(name) {
+			return -1
+		}
+	}
+
+	return gaps[0]
+}
+
+// text_dual splits a dual dialogue block down the gap
+// between its two columns, which is taken to be halfway
+// between the two character names, and reads each side
+// as a speech of its own
+func text_dual(lines []Text_Line, base int) *XML_Paragraph {
+	cue := &lines[0]
+
+	start   := text_dual_cue(cue, base)
+	name, _ := text_cut(cue, start)
+	middle  := (cue.indent + rune_count(name) + start) / 2
+
+	left  := make([]string, 0, len(lines))
+	right := make([]string, 0, len(lines))
+
+	for i := range lines {
+		line := &lines[i]
+
+		if line.indent >= middle {
+			right = append(right, line.text)
+			continue
+		}
+
+		split := -1
+		for _, gap := range text_gaps(line, 2) {
+			if gap >= middle {
+				split = gap
+				break
+			}
+		}
+
+		if split < 0 {
+			left = append(left, line.text)
+			continue
+		}
+
+		a, b := text_cut(line, split)
+		left  = append(left,  a)
+		right = append(right, b)
+	}
+
+	dual := new(XML_Dual_Dialogue)
+	dual.Content = append(text_dual_speech(left), text_dual_speech(right)...)
+
+	return &XML_Paragraph{Dual: dual}
+}
+
+// text_dual_speech reads one column of a dual dialogue
+// block, which is the character's name and then their
+// speech, with any wrapped lines joined back up
+func text_dual_speech(lines []string) []*XML_Paragraph {
+	list := make([]*XML_Paragraph, 0, 4)
+
+	var last *XML_Paragraph
+
+	for _, text := range lines {
+		kind := "Dialogue"
+
+		switch {
+		case last == nil:
+			kind = "Character"
+			text = strip_contd(text)
+		case text[0] == '(':
+			kind = "Parenthetical"
+		case last.Type == "Parenthetical" && !strings.HasSuffix(last.Chunks[0].Text, ")"):
+			kind = "Parenthetical"
+		}
+
+		if last != nil && last.Type == kind && text[0] != '(' {
+			last.Chunks[0].Text += " " + text
+			continue
+		}
+
+		last = &XML_Paragraph{
+			Type:   kind,
+			Chunks: []*XML_Chunk{{Text: text}},
+		}
+
+		list = append(list, last)
+	}
+
+	return list
+}
+
+// text_gaps finds every run of at least n spaces inside
+// a line, returning the column that the text after each
+// one starts at
+func text_gaps(line *Text_Line, n int) []int {
+	gaps := make([]int, 0, 2)
+	run  := 0
+
+	for i, c := range []rune(line.text) {
+		if c == ' ' {
+			run += 1
+			continue
+		}
+		if run >= n {
+			gaps = append(gaps, line.indent + i)
+		}
+		run = 0
+	}
+
+	return gaps
+}
+
+// text_cut splits a line in two at a column
+func text_cut(line *Text_Line, column int) (string, string) {
+	text := []rune(line.text)
+	at   := column - line.indent
+
+	if at <= 0 {
+		return "", line.text
+	}
+	if at >= len(text) {
+		return line.text, ""
+	}
+
+	return strings.TrimRight(string(text[:at]), " "), strings.TrimLeft(string(text[at:]), " ")
+}
+
+func text_type(kind string) string {
+	if kind == "Centered" {
+		return "Action"
+	}
+	return kind
+}
+
+func text_alignment(kind string) string {
+	if kind == "Centered" {
+		return "Center"
+	}
+	return ""
+}
+
+// page numbers and the "continued" markers around
+// page breaks, which Fountain puts back by itself
+func is_text_furniture(line *Text_Line, base int) bool {
+	text := strings.ToUpper(line.text)
+
+	if text == "(CONTINUED)" || strings.HasPrefix(text, "CONTINUED:") || text == "CONTINUED" {
+		return true
+	}
+
+	if line.indent - base < TEXT_CHARACTER {
+		return false
+	}
+
+	// "12.", "Page 12", "- 12 -"
+	text = strings.TrimPrefix(text, "PAGE ")
+	text = strings.Trim(text, "-. ")
+
+	if text == "" {
+		return false
+	}
+
+	for i, c := range text {
+		if !unicode.IsDigit(c) && !(i > 0 && unicode.IsLetter(c)) {
+			return false
+		}
+	}
+	return true
+}
+
+func is_text_scene_number(text string) bool {
+	if text == "" || !unicode.IsDigit(rune(text[0])) {
+		return false
+	}
+	for _, c := range text {
+		if !unicode.IsDigit(c) && !unicode.IsUpper(c) && c != '.' && c != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// uppercase, ignoring anything in brackets such
+// as a lowercase (cont'd) after a name
+func is_text_upper(text string) bool {
+	if n := strings.IndexRune(text, '('); n > 0 {
+		text = text[:n]
+	}
+
+	has_letters := false
+	for _, c := range text {
+		if unicode.IsLower(c) {
+			return false
+		}
+		if unicode.IsLetter(c) {
+			has_letters = true
+		}
+	}
+	return has_letters
+}
+
+// strip_contd removes the (CONT'D) that's added
+// to a character name after an interruption
+func strip_contd(text string) string {
+	for {
+		start := strings.LastIndex(text, "(")
+		if start < 0 || !strings.HasSuffix(text, ")") {
+			return text
+		}
+
+		switch strings.ToUpper(strings.Trim(text[start:], "()")) {
+		case "CONT'D", "CONT’D", "CONTD", "CONT", "CONT.", "CONTINUED", "CONTINUING":
+			text = strings.TrimSpace(text[:start])
+		default:
+			return text
+		}
+	}
+}
+
+func expand_tabs(text string) string {
+	if !strings.ContainsRune(text, '\t') {
+		return text
+	}
+
+	buffer := strings.Builder{}
+	column := 0
+
+	for _, c := range text {
+		if c == '\t' {
+			for {
+				buffer.WriteRune(' ')
+				column += 1
+				if column % 8 == 0 {
+					break
+				}
+			}
+			continue
+		}
+		buffer.WriteRune(c)
+		column += 1
+	}
+
+	return buffer.String()
+}
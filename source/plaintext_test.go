@@ -0,0 +1,208 @@
+/*
+	Meander
+	A portable Fountain utility for production writing
+	Copyright (C) 2022-2023 Harley Denham
+*/
+
+package main
+
+import "strings"
+import "testing"
+
+// the lines of a short script in industry layout, with
+// the action starting at base; a leading "|" marks
+// where the action's left edge is, so that indents in
+// the tables below line up with what they'd look like,
+// and a form feed before it starts a new page
+func text_script(base int, lines ...string) string {
+	margin := strings.Repeat(" ", base)
+
+	for i, line := range lines {
+		if line == "" {
+			continue
+		}
+
+		page := ""
+		if line[0] == '\f' {
+			page = "\f"
+			line = line[1:]
+		}
+
+		lines[i] = page + margin + strings.TrimPrefix(line, "|")
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+const text_long = "Bob crosses the kitchen and reaches for the last biscuit in the tin."
+
+func TestTextBaseColumn(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		base  int
+	}{
+		{"no text",       "",                                                                                                              0},
+		{"flush left",    text_script(0, "|" + text_long, "|" + text_long),                                                               0},
+		{"indented",      text_script(15, "|" + text_long, "|" + text_long),                                                               15},
+		{"page numbers",  "                                                  12.\n\n" + text_script(10, "|" + text_long, "|" + text_long), 10},
+		{"scene numbers", "12   INT. HOUSE - DAY   12\n\n" + text_script(5, "|" + text_long),                                              5},
+		{"most common",   text_script(10, "|" + text_long, "|" + text_long, "|   " + text_long),                                           10},
+		{"short lines",   text_script(8, "|INT. HOUSE - DAY", "", "|          BOB", "|   Hello."),                                        8},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			base := text_base_column(text_lines(test.input))
+			if base != test.base {
+				t.Errorf("text_base_column() = %d, want %d", base, test.base)
+			}
+		})
+	}
+}
+
+func TestTextKinds(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		kinds []string // one for each line
+	}{
+		{
+			name:  "scene and action",
+			lines: []string{"|INT. HOUSE - DAY", "", "|" + text_long, "|Then he sits."},
+			kinds: []string{"Scene Heading", "", "Action", "Action"},
+		},
+		{
+			name:  "speech",
+			lines: []string{"|                      BOB", "|                (quietly)", "|          Hello there.", "|          How are you?"},
+			kinds: []string{"Character", "Parenthetical", "Dialogue", "Dialogue"},
+		},
+		{
+			name:  "parenthetical over two lines",
+			lines: []string{"|                      BOB", "|                (to Alice, who", "|                isn't listening)", "|          Hello."},
+			kinds: []string{"Character", "Parenthetical", "Parenthetical", "Dialogue"},
+		},
+		{
+			name:  "speech carried over",
+			lines: []string{"|                      BOB", "|          Hello.", "|                      (MORE)"},
+			kinds: []string{"Character", "Dialogue", "More"},
+		},
+		{
+			name:  "transitions",
+			lines: []string{"|                                             CUT TO:", "", "|SMASH TO:", "", "|                      DISSOLVE TO:"},
+			kinds: []string{"Transition", "", "Transition", "", "Transition"},
+		},
+		{
+			name:  "centred",
+			lines: []string{"|                      THE END"},
+			kinds: []string{"Centered"},
+		},
+		{
+			name:  "capitals in action",
+			lines: []string{"|THE DOOR EXPLODES.", "|Everyone ducks."},
+			kinds: []string{"Action", "Action"},
+		},
+		{
+			name:  "furniture",
+			lines: []string{"|                                                  12.", "|CONTINUED:", "|" + text_long},
+			kinds: []string{"", "", "Action"},
+		},
+		{
+			name:  "dual dialogue",
+			lines: []string{"|     BOB                              ALICE", "|That's mine.                     No, it's mine.", "|(smiles)                         (beat)"},
+			kinds: []string{"Dual", "Dual", "Dual"},
+		},
+		{
+			name:  "two names with nothing beneath",
+			lines: []string{"|     BOB                              ALICE"},
+			kinds: []string{"Action"},
+		},
+		{
+			name:  "capitals with a gap in action",
+			lines: []string{"|BOOM.                              THE DOOR GOES.", "|Everyone ducks."},
+			kinds: []string{"Action", "Action"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lines := text_lines(text_script(10, test.lines...))
+			kinds := text_kinds(lines, 10)
+
+			if strings.Join(kinds, ",") != strings.Join(test.kinds, ",") {
+				t.Errorf("text_kinds() = %q, want %q", kinds, test.kinds)
+			}
+		})
+	}
+}
+
+func TestTextFountain(t *testing.T) {
+	tests := []struct {
+		name   string
+		lines  []string
+		output string
+	}{
+		{
+			name: "wrapped lines",
+			lines: []string{
+				"|INT. KITCHEN - DAY",
+				"",
+				"|Bob and Alice stand at the counter, both reaching for the very last",
+				"|biscuit in the tin.",
+				"",
+				"|                      BOB",
+				"|          That one's mine, and you",
+				"|          know it.",
+			},
+			output: "INT. KITCHEN - DAY\n\nBob and Alice stand at the counter, both reaching for the very last biscuit in the tin.\n\nBOB\nThat one's mine, and you know it.\n\n/*\n\t[gender.unknown]\n\tBob\n*/\n",
+		},
+		{
+			name: "speech over a page break",
+			lines: []string{
+				"|" + text_long,
+				"",
+				"|                      BOB",
+				"|          That one's mine, and you",
+				"|                      (MORE)",
+				"\f|                      BOB (CONT'D)",
+				"|          know it.",
+			},
+			output: text_long + "\n\nBOB\nThat one's mine, and you know it.\n\n/*\n\t[gender.unknown]\n\tBob\n*/\n",
+		},
+		{
+			name: "dual dialogue",
+			lines: []string{
+				"|            BOB                              ALICE",
+				"|That's mine. I saw it             No, it's mine.",
+				"|first, fair and square.           (beat)",
+				"|(smiles)                          I'll fight you for",
+				"|Obviously.                        it.",
+			},
+			output: "BOB\nThat's mine. I saw it first, fair and square.\n(smiles)\nObviously.\n\nALICE ^\nNo, it's mine.\n(beat)\nI'll fight you for it.\n\n/*\n\t[gender.unknown]\n\tBob\n\tAlice\n*/\n",
+		},
+		{
+			name: "dual dialogue with one side longer",
+			lines: []string{
+				"|            BOB                              ALICE",
+				"|Mine.                             No, mine, and I",
+				"|                                  won't say it again.",
+			},
+			output: "BOB\nMine.\n\nALICE ^\nNo, mine, and I won't say it again.\n\n/*\n\t[gender.unknown]\n\tBob\n\tAlice\n*/\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lines := text_lines(text_script(0, test.lines...))
+			base  := text_base_column(lines)
+
+			data := new(Final_Draft)
+			data.Content = text_paragraphs(lines, base)
+
+			output := final_draft_fountain(data)
+			if output != test.output {
+				t.Errorf("got\n%q\nwant\n%q", output, test.output)
+			}
+		})
+	}
+}
@@ -35,44 +35,19 @@ func set_font(doc *lib.GoPdf, style Leaf_Type) {
 }
 
 func command_render(config *Config) {
-	text, success := merge(config.source_file)
+	data, success := parse_file(config)
 	if !success {
 		return
 	}
 
-	data := init_data(config)
-
-	syntax_parser(config, data, text)
 	vet_template(data.template)
 	paginate(config, data)
 
-	if config.starred_show && len(data.config.starred_target) == 0 && len(data.Title.Revision) > 0 {
-		data.config.starred_target = strings.ToLower(data.Title.Revision)
+	if !select_starred(config, data) {
+		return
 	}
-
-	if config.starred_only {
-		page_count  := data.Content[len(data.Content) - 1].page
-		valid_pages := make([]bool, page_count + 1)
-
-		has_any := false
-
-		for i := range data.Content {
-			section := &data.Content[i]
-			if len(section.Revision) > 0 && strings.Contains(data.config.starred_target, section.Revision) {
-				valid_pages[section.page] = true
-				has_any = true
-			}
-		}
-
-		if !has_any {
-			eprintln("--revision-only: there are no revision tags in this document that match the input — PDF would be blank")
-			return
-		}
-
-		for i := range data.Content {
-			section := &data.Content[i]
-			section.skip = !valid_pages[section.page]
-		}
+	if !highlight_characters(config, data) {
+		return
 	}
 
 	doc := new(lib.GoPdf)
@@ -100,11 +75,65 @@ func command_render(config *Config) {
 	}
 }
 
+// select_starred works out which revisions get stars and,
+// for --stars-only, skips every page without one.  it returns
+// false if that would leave nothing to output.
+func select_starred(config *Config, data *Fountain) bool {
+	if config.starred_show && len(data.config.starred_target) == 0 && len(data.Title.Revision) > 0 {
+		data.config.starred_target = strings.ToLower(data.Title.Revision)
+	}
+
+	if config.starred_only {
+		page_count  := data.Content[len(data.Content) - 1].page
+		valid_pages := make([]bool, page_count + 1)
+
+		has_any := false
+
+		for i := range data.Content {
+			section := &data.Content[i]
+			if len(section.Revision) > 0 && strings.Contains(data.config.starred_target, section.Revision) {
+				valid_pages[section.page] = true
+				has_any = true
+			}
+		}
+
+		if !has_any {
+			eprintln("--revision-only: there are no revision tags in this document that match the input — output would be blank")
+			return false
+		}
+
+		skip_pages(data, valid_pages)
+	}
+
+	return true
+}
+
+// skip_pages leaves every page that isn't marked
+// valid out of the output, keeping their numbers
+func skip_pages(data *Fountain, valid_pages []bool) {
+	for i := range data.Content {
+		section := &data.Content[i]
+		section.skip = !valid_pages[section.page]
+	}
+}
+
 func render_title(config *Config, data *Fountain, doc *lib.GoPdf) {
-	if !data.Title.has_any || data.config.starred_only {
+	if !data.Title.HasAny || data.config.starred_only {
 		return
 	}
 
+	doc.AddPage()
+
+	for _, section := range title_sections(data) {
+		draw_section(doc, data, section)
+	}
+}
+
+// title_sections lays out each entry of the title page
+// as a section, ready to be drawn
+func title_sections(data *Fountain) []*Section {
+	output := make([]*Section, 0, 10)
+
 	const LINE_HEIGHT = LINE_HEIGHT * 1.5
 
 	const WIDTH  = INCH * 3 // bottom corners
@@ -120,8 +149,6 @@ func render_title(config *Config, data *Fountain, doc *lib.GoPdf) {
 		title_width = WIDTH
 	}
 
-	doc.AddPage()
-
 	// Title    Credit    Author    Source
 	{
 		title  := quick_section(data, data.Title.Title,  align, LINE_HEIGHT, title_width)
@@ -132,28 +159,28 @@ func render_title(config *Config, data *Fountain, doc *lib.GoPdf) {
 		if data.Title.Title != "" {
 			title.pos_x = start_x
 			title.pos_y = start_y
-			draw_section(doc, data, title)
+			output = append(output, title)
 			start_y += title.total_height + LINE_HEIGHT * 4
 		}
 
 		if data.Title.Credit != "" {
 			credit.pos_x = start_x
 			credit.pos_y = start_y
-			draw_section(doc, data, credit)
+			output = append(output, credit)
 			start_y += credit.total_height + LINE_HEIGHT * 2
 		}
 
 		if data.Title.Author != "" {
 			author.pos_x = start_x
 			author.pos_y = start_y
-			draw_section(doc, data, author)
+			output = append(output, author)
 			start_y += author.total_height + LINE_HEIGHT * 4
 		}
 
 		if data.Title.Source != "" {
 			source.pos_x = start_x
 			source.pos_y = start_y
-			draw_section(doc, data, source)
+			output = append(output, source)
 		}
 	}
 
@@ -170,21 +197,21 @@ func render_title(config *Config, data *Fountain, doc *lib.GoPdf) {
 		if data.Title.Notes != "" {
 			note.pos_x = start_x
 			note.pos_y = start_y
-			draw_section(doc, data, note)
+			output = append(output, note)
 			start_y += note.total_height
 		}
 
 		if data.Title.Contact != "" {
 			cont.pos_x = start_x
 			cont.pos_y = start_y
-			draw_section(doc, data, cont)
+			output = append(output, cont)
 			start_y += cont.total_height
 		}
 
 		if data.Title.Copyright != "" {
 			copy.pos_x = start_x
 			copy.pos_y = start_y
-			draw_section(doc, data, copy)
+			output = append(output, copy)
 		}
 	}
 
@@ -201,23 +228,25 @@ func render_title(config *Config, data *Fountain, doc *lib.GoPdf) {
 		if data.Title.Revision != "" {
 			revs.pos_x = start_x
 			revs.pos_y = start_y
-			draw_section(doc, data, revs)
+			output = append(output, revs)
 			start_y += revs.total_height + LINE_HEIGHT
 		}
 
 		if data.Title.DraftDate != "" {
 			drft.pos_x = start_x
 			drft.pos_y = start_y
-			draw_section(doc, data, drft)
+			output = append(output, drft)
 			start_y += drft.total_height + LINE_HEIGHT
 		}
 
 		if data.Title.Info != "" {
 			info.pos_x = start_x
 			info.pos_y = start_y
-			draw_section(doc, data, info)
+			output = append(output, info)
 		}
 	}
+
+	return output
 }
 
 func render_gender(config *Config, data *Fountain, doc *lib.GoPdf) {
@@ -244,11 +273,12 @@ func render_gender(config *Config, data *Fountain, doc *lib.GoPdf) {
 
 	start_y += LINE_HEIGHT * 2
 
-	render_gender_data(data, doc, crunch_chars_by_gender(data), GENDER_CHARS_BY_GENDER, &start_y)
-	start_y += LINE_HEIGHT
-	render_gender_data(data, doc, crunch_lines_by_gender(data), GENDER_LINES_BY_GENDER, &start_y)
-	start_y += LINE_HEIGHT
-	render_gender_data(data, doc, crunch_chars_by_lines(data),  GENDER_CHARS_BY_LINES,  &start_y)
+	for i, table := range crunch_gender(data) {
+		if i > 0 {
+			start_y += LINE_HEIGHT
+		}
+		render_gender_data(data, doc, table.set, table.title, &start_y)
+	}
 }
 
 func render_toc(config *Config, data *Fountain, doc *lib.GoPdf) {
@@ -337,6 +367,19 @@ func render_content(config *Config, data *Fountain, doc *lib.GoPdf) {
 
 	page_number := 0
 
+	// runs of struck sections are crossed through
+	// as one block once they come to an end
+	striking      := false
+	strike_top    := 0.0
+	strike_bottom := 0.0
+
+	finish_strike := func() {
+		if striking {
+			draw_strike(doc, data, strike_top, strike_bottom)
+			striking = false
+		}
+	}
+
 	for i := range data.Content {
 		section := &data.Content[i]
 
@@ -345,6 +388,8 @@ func render_content(config *Config, data *Fountain, doc *lib.GoPdf) {
 		}
 
 		if section.page > page_number {
+			finish_strike()
+
 			page_number = section.page
 			doc.AddPage()
 
@@ -353,6 +398,19 @@ func render_content(config *Config, data *Fountain, doc *lib.GoPdf) {
 			}
 		}
 
+		if section.Type > is_printable {
+			if section.struck {
+				top, bottom := section_extent(data, section)
+				if !striking {
+					striking   = true
+					strike_top = top
+				}
+				strike_bottom = bottom
+			} else {
+				finish_strike()
+			}
+		}
+
 		if section.Type == SCENE && config.scenes != SCENE_REMOVE {
 			text_width := float64(rune_count(section.SceneNumber)) * CHAR_WIDTH
 			right_x    := data.template.margin_right - text_width
@@ -375,6 +433,39 @@ func render_content(config *Config, data *Fountain, doc *lib.GoPdf) {
 
 		draw_section(doc, data, section)
 	}
+
+	finish_strike()
+}
+
+// section_extent gives the top and bottom of a section
+// as it's laid out on the page
+func section_extent(data *Fountain, section *Section) (float64, float64) {
+	line_height := section.line_height
+	if line_height == 0 {
+		line_height = data.template.line_height
+	}
+
+	lines := len(section.lines)
+	if lines == 0 {
+		lines = 1
+	}
+
+	top := section.pos_y - PICA + 2
+	return top, top + line_height * float64(lines)
+}
+
+// draw_strike crosses through a block of the page,
+// as for the parts of a page outside the chosen sides
+func draw_strike(doc *lib.GoPdf, data *Fountain, top, bottom float64) {
+	left  := data.template.margin_left - INCH / 4
+	right := data.template.margin_right
+
+	set_color(doc, data.template.text_color)
+	doc.SetStrokeColor(data.template.text_color.R, data.template.text_color.G, data.template.text_color.B)
+	doc.SetLineWidth(1)
+
+	doc.Line(left, top, right, bottom)
+	doc.Line(left, bottom, right, top)
 }
 
 func draw_section(doc *lib.GoPdf, data *Fountain, section *Section) {
@@ -399,6 +490,23 @@ func draw_section(doc *lib.GoPdf, data *Fountain, section *Section) {
 			pos_x -= CHAR_WIDTH * float64(section.longest_line)
 		}
 
+		// highlighted raw text goes through draw_line,
+		// which knows how to paint behind it
+		if section.highlight_color != nil {
+			length := rune_count(section.Text)
+
+			line := Line{
+				length:          length,
+				leaves:          []Leaf{{NORMAL, false, section.Text}},
+				highlight:       []int{0, length},
+				highlight_color: section.highlight_color,
+			}
+
+			draw_line(doc, data.template, &line, pos_x, section.pos_y)
+			draw_star(doc, data, section, section.pos_y)
+			return
+		}
+
 		doc.SetXY(pos_x, section.pos_y)
 		doc.Text(section.Text)
 		draw_star(doc, data, section, section.pos_y)
@@ -435,7 +543,11 @@ func draw_line(doc *lib.GoPdf, template *Template, line *Line, pos_x, pos_y floa
 	doc.SetXY(pos_x, pos_y)
 
 	if len(line.highlight) > 0 {
-		set_color(doc, template.highlight_color)
+		if line.highlight_color != nil {
+			set_color(doc, *line.highlight_color)
+		} else {
+			set_color(doc, template.highlight_color)
+		}
 
 		draw_range_item(line.highlight, func(a, b float64) {
 			y := pos_y - PICA + 2
@@ -496,6 +608,16 @@ func render_gender_data(data *Fountain, doc *lib.GoPdf, data_set *Analytics_Set,
 	data_total   := float64(data_set.total_value)
 	data_largest := float64(data_set.largest_value)
 
+	// there's no longer room for all of the tables
+	// on one page, so each one that won't start on
+	// this page with a few rows goes to the next
+	max_y := data.template.paper.H - data.template.margin_bottom
+
+	if *start_y + LINE_HEIGHT * 4 > max_y {
+		doc.AddPage()
+		*start_y = data.template.margin_top
+	}
+
 	{
 		t := Line{leaves:[]Leaf{{ITALIC, false, title}}}
 		draw_line(doc, data.template, &t, data.template.margin_left, *start_y)
@@ -510,6 +632,11 @@ func render_gender_data(data *Fountain, doc *lib.GoPdf, data_set *Analytics_Set,
 			continue
 		}
 
+		if *start_y > max_y {
+			doc.AddPage()
+			*start_y = data.template.margin_top
+		}
+
 		running_x := data.template.margin_left
 
 		doc.SetXY(running_x, *start_y)
@@ -0,0 +1,170 @@
+/*
+	Meander
+	A portable Fountain utility for production writing
+	Copyright (C) 2022-2023 Harley Denham
+*/
+
+package main
+
+import "os"
+import "path/filepath"
+import "encoding/csv"
+
+import lib "github.com/signintech/gopdf"
+
+const CSV_EXT = ".csv"
+
+// Report is a plain PDF for the production reports: a
+// title, then lines of text working down the page, with
+// a new page whenever the next block won't fit
+type Report struct {
+	doc  *lib.GoPdf
+	data *Fountain
+
+	left   float64
+	right  float64
+	top    float64
+	bottom float64
+
+	y float64
+}
+
+// wants_csv reports whether a report should be written as
+// CSV instead of PDF, from --output-format or the output
+// file's extension
+func wants_csv(config *Config) bool {
+	if config.output_format != "" {
+		return config.output_format == CSV_EXT
+	}
+	return filepath.Ext(config.output_file) == CSV_EXT
+}
+
+// report_ext is the extension for a report's
+// default output file
+func report_ext(config *Config) string {
+	if config.output_format == CSV_EXT {
+		return CSV_EXT
+	}
+	return PDF_EXT
+}
+
+// valid_report_format checks that --output-format
+// is one that reports can be written as
+func valid_report_format(config *Config) bool {
+	switch config.output_format {
+	case "", PDF_EXT, CSV_EXT:
+		return true
+	}
+	eprintln("error: reports can only be written as pdf or csv")
+	return false
+}
+
+func write_csv(path string, rows [][]string) bool {
+	file, err := os.Create(fix_path(path))
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.WriteAll(rows)
+
+	return writer.Error() == nil
+}
+
+func new_report(config *Config, data *Fountain, title string, landscape bool) *Report {
+	paper := config.paper_size
+	if landscape && paper.W < paper.H {
+		paper.W, paper.H = paper.H, paper.W
+	}
+
+	doc := new(lib.GoPdf)
+
+	doc.Start(lib.Config{
+		PageSize: paper,
+	})
+	doc.SetInfo(lib.PdfInfo{
+		Title:        clean_string(data.Title.Title + " " + title),
+		Author:       clean_string(data.Title.Author),
+		Creator:      MEANDER,
+		CreationDate: now(),
+	})
+
+	register_fonts(doc)
+	set_font(doc, NO_TYPE)
+
+	r := &Report{
+		doc:    doc,
+		data:   data,
+		left:   INCH * 0.75,
+		right:  paper.W - INCH * 0.75,
+		top:    INCH * 0.75,
+		bottom: paper.H - INCH * 0.75,
+	}
+
+	doc.AddPage()
+	r.y = r.top
+
+	heading := title
+	if data.Title.Title != "" {
+		heading = clean_string(data.Title.Title) + " — " + title
+	}
+
+	t := Line{
+		length: rune_count(heading),
+		leaves: []Leaf{{NORMAL, false, heading}},
+	}
+	line_override(&t, UNDERLINE)
+	draw_line(doc, data.template, &t, r.left, r.y)
+
+	r.y += LINE_HEIGHT * 2
+
+	return r
+}
+
+// report_fits starts a new page if a block of the
+// given height won't fit on this one, and reports
+// whether it did
+func report_fits(r *Report, height float64) bool {
+	if r.y + height <= r.bottom {
+		return true
+	}
+	r.doc.AddPage()
+	r.y = r.top
+	return false
+}
+
+func report_text(r *Report, x float64, text string, style Leaf_Type) {
+	set_color(r.doc, r.data.template.text_color)
+	set_font(r.doc, style)
+	r.doc.SetXY(x, r.y)
+	r.doc.Text(text)
+	set_font(r.doc, NO_TYPE)
+}
+
+// report_wrap breaks text to fit a width, ready to
+// be drawn at x on the current line
+func report_wrap(r *Report, text string, x, width float64) *Section {
+	section := quick_section(r.data, text, LEFT, LINE_HEIGHT, width)
+	section.pos_x = x
+	section.pos_y = r.y
+	return section
+}
+
+func report_lines(section *Section) int {
+	if n := len(section.lines); n > 0 {
+		return n
+	}
+	return 1
+}
+
+func report_rule(r *Report) {
+	r.doc.SetLineWidth(0.5)
+	r.doc.Line(r.left, r.y + PICA / 2, r.right, r.y + PICA / 2)
+}
+
+func save_report(r *Report, path string) {
+	if err := r.doc.WritePdf(fix_path(path)); err != nil {
+		eprintln("error saving", path)
+	}
+}
@@ -0,0 +1,262 @@
+/*
+	Meander
+	A portable Fountain utility for production writing
+	Copyright (C) 2022-2023 Harley Denham
+*/
+
+package main
+
+import "fmt"
+import "math"
+import "strings"
+
+// Runtime is the estimated running time of a script,
+// scene by scene and section by section
+type Runtime struct {
+	total   float64
+	eighths int
+
+	scenes []float64 // seconds, in the same order as the scenes
+
+	// every section with a scene in it, in outline order,
+	// with totals that include the sections inside them
+	order    []*Heading
+	sections map[*Heading]*Section_Time
+}
+
+type Section_Time struct {
+	seconds float64
+	eighths int
+}
+
+func command_runtime(config *Config) {
+	data, success := parse_file(config)
+	if !success {
+		return
+	}
+
+	scenes := collect_scenes(config, data)
+
+	if len(scenes) == 0 {
+		eprintf("runtime: %q has no scenes", config.source_file)
+		return
+	}
+
+	print_runtime(data, scenes, time_script(data.template, scenes))
+}
+
+// scene_seconds estimates how long a scene runs on screen.
+// the page-a-minute rule knows about pacing and white space
+// but not how dense the writing is, and reading speeds know
+// the opposite, so the estimate sits halfway between them.
+func scene_seconds(template *Template, scene *Scene) float64 {
+	by_page  := float64(scene.eighths) / 8 * 60
+	by_words := float64(scene.dialogue) / template.dialogue_wpm * 60 + float64(scene.action) / template.action_wpm * 60
+
+	return (by_page + by_words) / 2
+}
+
+func time_script(template *Template, scenes []*Scene) *Runtime {
+	r := &Runtime{
+		scenes:   make([]float64, len(scenes)),
+		sections: make(map[*Heading]*Section_Time, 16),
+	}
+
+	for i, scene := range scenes {
+		seconds := scene_seconds(template, scene)
+
+		r.scenes[i] = seconds
+		r.total    += seconds
+		r.eighths  += scene.eighths
+
+		for _, h := range heading_chain(scene.section) {
+			t, ok := r.sections[h]
+			if !ok {
+				t = &Section_Time{}
+				r.sections[h] = t
+				r.order = append(r.order, h)
+			}
+			t.seconds += seconds
+			t.eighths += scene.eighths
+		}
+	}
+
+	return r
+}
+
+// heading_chain lists a section and those
+// it falls under, outermost first
+func heading_chain(h *Heading) []*Heading {
+	chain := make([]*Heading, 0, 3)
+	for ; h != nil; h = h.parent {
+		chain = append([]*Heading{h}, chain...)
+	}
+	return chain
+}
+
+// format_duration writes a number of seconds
+// as a clock, such as "1:32:05" or "2:41"
+func format_duration(seconds float64) string {
+	n := int(math.Round(seconds))
+
+	if n >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", n / 3600, n / 60 % 60, n % 60)
+	}
+	return fmt.Sprintf("%d:%02d", n / 60, n % 60)
+}
+
+func print_runtime(data *Fountain, scenes []*Scene, r *Runtime) {
+	const max_name = 48
+
+	// each line of the outline: a section heading,
+	// with its children and scenes indented beneath
+	type Row struct {
+		name    string
+		eighths int
+		seconds float64
+		is_head bool
+	}
+
+	rows := make([]Row, 0, len(scenes) + len(r.order))
+	seen := make(map[*Heading]bool, len(r.order))
+
+	for i, scene := range scenes {
+		chain := heading_chain(scene.section)
+
+		for depth, h := range chain {
+			if seen[h] {
+				continue
+			}
+			seen[h] = true
+
+			t := r.sections[h]
+			rows = append(rows, Row{
+				name:    strings.Repeat("  ", depth) + h.title,
+				eighths: t.eighths,
+				seconds: t.seconds,
+				is_head: true,
+			})
+		}
+
+		name := scene.heading
+		if scene.number != "" {
+			name = scene.number + "  " + name
+		}
+
+		rows = append(rows, Row{
+			name:    strings.Repeat("  ", len(chain)) + name,
+			eighths: scene.eighths,
+			seconds: r.scenes[i],
+		})
+	}
+
+	longest := rune_count(RUNTIME_TOTAL)
+	for i := range rows {
+		rows[i].name = truncate(rows[i].name, max_name)
+		if n := rune_count(rows[i].name); n > longest {
+			longest = n
+		}
+	}
+
+	println_color("\n   ", clean_string(data.Title.Title), RUNTIME_HEADING)
+
+	print("\n    ")
+	println(fmt.Sprintf(RUNTIME_RATES, data.template.dialogue_wpm, data.template.action_wpm))
+
+	print("\n    ")
+	print_padded(BREAKDOWN_SCENE,  longest)
+	print_padded(BREAKDOWN_LENGTH, 8)
+	println(RUNTIME_COLUMN)
+
+	print("    ")
+	print_dashes(longest + 20)
+
+	for _, row := range rows {
+		print("    ")
+		if row.is_head && running_in_term {
+			print(ANSI_COLOR)
+		}
+		print_padded(row.name, longest)
+		print_padded(format_eighths(row.eighths), 8)
+		println(format_duration(row.seconds))
+		if row.is_head && running_in_term {
+			print(ANSI_RESET)
+		}
+	}
+
+	print("    ")
+	print_dashes(longest + 20)
+
+	print("    ")
+	print_padded(RUNTIME_TOTAL, longest)
+	print_padded(format_eighths(r.eighths), 8)
+	println(format_duration(r.total))
+
+	print("\n")
+}
+
+// Data_Runtime is the runtime estimate
+// as it's given in the data output
+type Data_Runtime struct {
+	DialogueWPM float64       `json:"dialogue_wpm"`
+	ActionWPM   float64       `json:"action_wpm"`
+	Eighths     int           `json:"eighths"`
+	Seconds     float64       `json:"seconds"`
+	Sections    []Data_Timing `json:"sections,omitempty"`
+	Scenes      []Data_Timing `json:"scenes,omitempty"`
+}
+
+type Data_Timing struct {
+	Text        string  `json:"text"`
+	SceneNumber string  `json:"scene_number,omitempty"`
+	Level       int     `json:"level,omitempty"`
+	Section     string  `json:"section,omitempty"`
+	Eighths     int     `json:"eighths"`
+	Seconds     float64 `json:"seconds"`
+}
+
+func runtime_data(data *Fountain, scenes []*Scene) *Data_Runtime {
+	if len(scenes) == 0 {
+		return nil
+	}
+
+	r := time_script(data.template, scenes)
+
+	output := &Data_Runtime{
+		DialogueWPM: data.template.dialogue_wpm,
+		ActionWPM:   data.template.action_wpm,
+		Eighths:     r.eighths,
+		Seconds:     round_seconds(r.total),
+		Sections:    make([]Data_Timing, 0, len(r.order)),
+		Scenes:      make([]Data_Timing, 0, len(scenes)),
+	}
+
+	for _, h := range r.order {
+		t := r.sections[h]
+		output.Sections = append(output.Sections, Data_Timing{
+			Text:    h.title,
+			Level:   h.level,
+			Eighths: t.eighths,
+			Seconds: round_seconds(t.seconds),
+		})
+	}
+
+	for i, scene := range scenes {
+		entry := Data_Timing{
+			Text:        scene.heading,
+			SceneNumber: scene.number,
+			Eighths:     scene.eighths,
+			Seconds:     round_seconds(r.scenes[i]),
+		}
+		if scene.section != nil {
+			entry.Section = scene.section.title
+		}
+		output.Scenes = append(output.Scenes, entry)
+	}
+
+	return output
+}
+
+func round_seconds(x float64) float64 {
+	return math.Round(x * 10) / 10
+}
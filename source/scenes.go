@@ -0,0 +1,501 @@
+/*
+	Meander
+	A portable Fountain utility for production writing
+	Copyright (C) 2022-2023 Harley Denham
+*/
+
+package main
+
+import "fmt"
+import "math"
+import "strconv"
+import "strings"
+import "unicode"
+
+import "github.com/lichendust/meander/fountain"
+
+// Scene is everything the production reports need to know
+// about one scene, gathered from the script and its layout
+type Scene struct {
+	number  string
+	heading string
+
+	setting  string // INT, EXT, INT/EXT or EXT/INT
+	location string
+	time     string
+	night    bool // worked out in script order, for the strip colours
+
+	page    int // the page the heading is on
+	eighths int // rounded, never less than one
+
+	cast      []int    // indices into Characters of those who speak, in order of appearance
+	speaking  []string // their names
+	mentioned []string // names in caps in the action, who don't speak
+	silent    []int    // indices of those mentioned who are in Characters
+	synopsis  string
+
+	dialogue int // words of dialogue and lyrics
+	action   int // words of action
+
+	section *Heading // the innermost section it falls under
+}
+
+// Heading is a # section heading,
+// as a step in a scene's outline
+type Heading struct {
+	title  string
+	level  int
+	parent *Heading
+}
+
+// collect_scenes reads the scenes out of the script and
+// then paginates it to find where each one falls.  like
+// paginate, it leaves the laid-out script in data.Content.
+func collect_scenes(config *Config, data *Fountain) []*Scene {
+	prepare_export(data)
+
+	scenes := read_scenes(data)
+
+	vet_template(data.template)
+	paginate(config, data)
+
+	measure_scenes(data, scenes)
+
+	return scenes
+}
+
+// number_scenes settles the reports that pick scenes out by
+// number on a single numbering.  a script without any scene
+// numbers is numbered in order, as --scene generate would,
+// but one that numbers only some of its scenes is an error,
+// because a scene's position could clash with a forced number
+func number_scenes(config *Config, data *Fountain) bool {
+	if config.scenes == SCENE_GENERATE {
+		return true
+	}
+
+	var missing *Section
+
+	count    := 0
+	numbered := 0
+
+	for i := range data.Content {
+		section := &data.Content[i]
+		if section.Type != SCENE {
+			continue
+		}
+
+		count += 1
+
+		if section.SceneNumber != "" {
+			numbered += 1
+		} else if missing == nil {
+			missing = section
+		}
+	}
+
+	if numbered == 0 {
+		n := 0
+		for i := range data.Content {
+			if data.Content[i].Type == SCENE {
+				n += 1
+				data.Content[i].SceneNumber = strconv.Itoa(n)
+			}
+		}
+		return true
+	}
+
+	if numbered < count {
+		eprintln(apply_color(fmt.Sprintf("error: %q has no scene number, but other scenes do\n\nnumber every scene, or use $1--scene generate$0", missing.Text)))
+		return false
+	}
+
+	return true
+}
+
+// repeated_numbers lists any scene
+// numbers used more than once
+func repeated_numbers(scenes []*Scene) []string {
+	seen     := make(map[string]int, len(scenes))
+	repeated := make([]string, 0, 4)
+
+	for _, scene := range scenes {
+		key := strings.ToUpper(scene.number)
+
+		seen[key] += 1
+		if seen[key] == 2 {
+			repeated = append(repeated, scene.number)
+		}
+	}
+
+	return repeated
+}
+
+// read_scenes does the first half of collect_scenes,
+// gathering everything it can from the script before
+// it's laid out
+func read_scenes(data *Fountain) []*Scene {
+	scenes := make([]*Scene, 0, 64)
+
+	var scene   *Scene
+	var heading *Heading
+
+	last_night := false
+
+	for i := range data.Content {
+		section := &data.Content[i]
+
+		if section.Type == SECTION {
+			level := section.Level
+			if level < 1 {
+				level = 1
+			}
+
+			parent := heading
+			for parent != nil && parent.level >= level {
+				parent = parent.parent
+			}
+
+			heading = &Heading{
+				title:  section.Text,
+				level:  level,
+				parent: parent,
+			}
+			continue
+		}
+
+		if section.Type == SCENE {
+			setting, location, time := fountain.SplitScene(section.Text)
+
+			scene = &Scene{
+				number:   section.SceneNumber,
+				heading:  section.Text,
+				setting:  setting,
+				location: location,
+				time:     time,
+				night:    is_night(time, last_night),
+				section:  heading,
+			}
+			scenes = append(scenes, scene)
+
+			last_night = scene.night
+			continue
+		}
+
+		if scene == nil {
+			continue
+		}
+
+		switch section.Type {
+		case DIALOGUE, DUAL_DIALOGUE, LYRIC, DUAL_LYRIC:
+			scene.dialogue += count_words(section.Text)
+
+		case ACTION, CENTERED:
+			scene.action += count_words(section.Text)
+		}
+
+		switch section.Type {
+		case CHARACTER, DUAL_CHARACTER:
+			if i, ok := find_character(data, section.Text); ok && !has_int(scene.cast, i) {
+				scene.cast     = append(scene.cast, i)
+				scene.speaking = append(scene.speaking, data.Characters[i].Name)
+			}
+
+		case ACTION:
+			for _, name := range caps_names(plain_text(data, section.Text)) {
+				if i, ok := find_character(data, name); ok {
+					name = data.Characters[i].Name
+					if !has_int(scene.silent, i) {
+						scene.silent = append(scene.silent, i)
+					}
+				} else {
+					name = title_case(strings.ToLower(name))
+				}
+				scene.mentioned = append_unique(scene.mentioned, name)
+			}
+
+		case SYNOPSIS:
+			if scene.synopsis != "" {
+				scene.synopsis += " "
+			}
+			scene.synopsis += section.Text
+		}
+	}
+
+	// anyone who speaks anywhere in the scene isn't
+	// counted as a mention, even if they speak later
+	for _, scene := range scenes {
+		mentioned := scene.mentioned[:0]
+		for _, name := range scene.mentioned {
+			if !has_string(scene.speaking, name) {
+				mentioned = append(mentioned, name)
+			}
+		}
+		scene.mentioned = mentioned
+
+		silent := scene.silent[:0]
+		for _, i := range scene.silent {
+			if !has_int(scene.cast, i) {
+				silent = append(silent, i)
+			}
+		}
+		scene.silent = silent
+	}
+
+	return scenes
+}
+
+// measure_scenes finds the page and length of each scene in
+// the paginated content.  a scene's length on each page runs
+// from the top of its first element on that page to the
+// bottom of its last, and they're added up in eighths of
+// the page body.
+func measure_scenes(data *Fountain, scenes []*Scene) {
+	template := data.template
+
+	eighth := (template.paper.H - template.margin_bottom - template.margin_top) / 8
+
+	index := -1
+
+	page   := 0
+	top    := 0.0
+	bottom := 0.0
+	height := 0.0
+
+	finish := func() {
+		if index < 0 {
+			return
+		}
+		height += bottom - top
+
+		n := int(math.Round(height / eighth))
+		if n < 1 {
+			n = 1
+		}
+		scenes[index].eighths = n
+	}
+
+	for i := range data.Content {
+		section := &data.Content[i]
+
+		if section.Type < is_printable {
+			continue
+		}
+
+		if section.Type == SCENE {
+			finish()
+
+			index += 1
+			if index >= len(scenes) {
+				return
+			}
+
+			// the numbers are only final after pagination,
+			// which generates them for --scene generate
+			scenes[index].number = section.SceneNumber
+			scenes[index].page   = section.page
+
+			page   = section.page
+			top    = section.pos_y
+			bottom = section.pos_y
+			height = 0
+		}
+
+		if index < 0 {
+			continue
+		}
+
+		if section.page != page {
+			height += bottom - top
+
+			page   = section.page
+			top    = section.pos_y
+			bottom = section.pos_y
+		}
+
+		line_height := section.line_height
+		if line_height == 0 {
+			line_height = template.line_height
+		}
+
+		lines := len(section.lines)
+		if lines == 0 {
+			lines = 1
+		}
+
+		if section.pos_y < top {
+			top = section.pos_y
+		}
+		if y := section.pos_y + line_height * float64(lines); y > bottom {
+			bottom = y
+		}
+	}
+
+	finish()
+}
+
+// find_character looks a character cue up in the list of
+// characters, ignoring any extension such as (V.O.), and
+// returns its index
+func find_character(data *Fountain, text string) (int, bool) {
+	name := text
+
+	if i := strings.IndexRune(name, '('); i >= 0 {
+		name = name[:i]
+	}
+
+	return data.document.FindCharacter(strings.TrimSpace(name))
+}
+
+// caps_names finds the runs of capitalised words in a line
+// of action, which is how a script introduces its characters.
+// a run is broken by punctuation, and anything shouted
+// (ending in !) is taken to be a sound, not a name.
+func caps_names(text string) []string {
+	names := make([]string, 0, 4)
+	run   := make([]string, 0, 4)
+
+	flush := func(shouted bool) {
+		if len(run) == 0 {
+			return
+		}
+		name := strings.Join(run, " ")
+		run = run[:0]
+
+		name = strings.TrimSuffix(name, "'S")
+		name = strings.TrimSuffix(name, "’S")
+
+		if shouted || count_letters(name) < 2 || is_caps_ignored(name) {
+			return
+		}
+		names = append(names, name)
+	}
+
+	for _, word := range strings.Fields(text) {
+		trimmed := strings.TrimLeft(word, "\"'(“‘")
+
+		// a possessive ends the name, as in "ALICE's"
+		possessive := false
+		for _, suffix := range [...]string{"'s", "’s"} {
+			if i := strings.LastIndex(trimmed, suffix); i > 0 {
+				trimmed, possessive = trimmed[:i], true
+				break
+			}
+		}
+
+		if !is_caps_word(trimmed) {
+			flush(false)
+			continue
+		}
+
+		clean := strings.TrimRight(trimmed, ".,;:!?\"')”’-—")
+
+		// but not the full stop of a title, as in "MRS. HUDSON"
+		if is_caps_title(trimmed) {
+			clean = trimmed
+		}
+
+		// a word that starts with an opening quote
+		// or bracket starts a new run
+		if len(trimmed) != len(word) {
+			flush(false)
+		}
+
+		// a lone capital starting a run is just
+		// "A" or "I", as in "A WAITER arrives"
+		if clean != "" && (len(run) > 0 || count_letters(clean) > 1) {
+			run = append(run, clean)
+		}
+
+		if len(clean) != len(trimmed) {
+			flush(strings.ContainsRune(trimmed[len(clean):], '!'))
+		} else if possessive {
+			flush(false)
+		}
+	}
+
+	flush(false)
+
+	return names
+}
+
+func is_caps_word(word string) bool {
+	has_letter := false
+	for _, c := range word {
+		if unicode.IsLower(c) {
+			return false
+		}
+		if unicode.IsLetter(c) {
+			has_letter = true
+		}
+	}
+	return has_letter
+}
+
+func count_letters(text string) int {
+	n := 0
+	for _, c := range text {
+		if unicode.IsLetter(c) {
+			n += 1
+		}
+	}
+	return n
+}
+
+func is_caps_title(word string) bool {
+	for _, title := range CAPS_TITLES {
+		if word == title {
+			return true
+		}
+	}
+	return false
+}
+
+func is_caps_ignored(name string) bool {
+	for _, word := range CAPS_IGNORED {
+		if name == word {
+			return true
+		}
+	}
+	return false
+}
+
+func append_unique(list []string, text string) []string {
+	if has_string(list, text) {
+		return list
+	}
+	return append(list, text)
+}
+
+func has_int(list []int, n int) bool {
+	for _, x := range list {
+		if x == n {
+			return true
+		}
+	}
+	return false
+}
+
+func has_string(list []string, text string) bool {
+	for _, x := range list {
+		if x == text {
+			return true
+		}
+	}
+	return false
+}
+
+// format_eighths writes a length in eighths the way
+// a production office would, such as "1 3/8"
+func format_eighths(n int) string {
+	pages  := n / 8
+	eighth := n % 8
+
+	switch {
+	case pages == 0:
+		return fmt.Sprintf("%d/8", eighth)
+	case eighth == 0:
+		return fmt.Sprintf("%d", pages)
+	}
+	return fmt.Sprintf("%d %d/8", pages, eighth)
+}
@@ -0,0 +1,505 @@
+/*
+	Meander
+	A portable Fountain utility for production writing
+	Copyright (C) 2022-2023 Harley Denham
+*/
+
+package main
+
+import "os"
+import "fmt"
+import "strings"
+import "unicode"
+import "strconv"
+import "encoding/xml"
+import "path/filepath"
+
+const SCRIVENER_EXT = ".scriv"
+
+// we only need the shape of the binder: the tree of
+// documents and folders, their titles and whether
+// they're part of the compiled draft
+type Scrivener_Project struct {
+	XMLName xml.Name          `xml:"ScrivenerProject"`
+	Binder  []*Scrivener_Item `xml:"Binder>BinderItem"`
+}
+
+type Scrivener_Item struct {
+	UUID     string            `xml:"UUID,attr"` // Scrivener 3
+	ID       string            `xml:"ID,attr"`   // Scrivener 2
+	Type     string            `xml:"Type,attr"`
+	Title    string            `xml:"Title"`
+	Compile  string            `xml:"MetaData>IncludeInCompile"`
+	Children []*Scrivener_Item `xml:"Children>BinderItem"`
+}
+
+// convert_scrivener writes every document in the draft
+// folder to its own Fountain file, in a folder named
+// after the project, and a root file that includes them
+// in binder order.  folders become sections, and anything
+// left out of compile is included inside a boneyard so
+// it's easy to bring back.
+func convert_scrivener(config *Config) {
+	matches, _ := filepath.Glob(filepath.Join(config.source_file, "*.scrivx"))
+	if len(matches) == 0 {
+		eprintf("convert: %q has no .scrivx binder", config.source_file)
+		return
+	}
+
+	byte_stream, err := os.ReadFile(matches[0])
+	if err != nil {
+		eprintf("failed to load %q", matches[0])
+		return
+	}
+
+	project := new(Scrivener_Project)
+
+	err = xml.Unmarshal(byte_stream, project)
+	if err != nil {
+		eprintf("failed to load %q", matches[0])
+		return
+	}
+
+	var draft *Scrivener_Item
+	for _, item := range project.Binder {
+		if item.Type == "DraftFolder" {
+			draft = item
+			break
+		}
+	}
+
+	if draft == nil {
+		eprintf("convert: %q has no draft folder", config.source_file)
+		return
+	}
+
+	root_file := fix_path(config.output_file)
+	folder    := rewrite_ext(filepath.Base(root_file), "")
+
+	state := &Scrivener_Import{
+		source: config.source_file,
+		folder: folder,
+		target: filepath.Join(filepath.Dir(root_file), folder),
+	}
+
+	if err := os.MkdirAll(state.target, os.ModePerm); err != nil {
+		eprintln("failed to create", state.target)
+		return
+	}
+
+	state.root.WriteString("title: ")
+	state.root.WriteString(rewrite_ext(filepath.Base(config.source_file), ""))
+	state.root.WriteRune('\n')
+
+	for _, item := range draft.Children {
+		state.write_item(item, 1, true)
+	}
+
+	state.root.WriteRune('\n')
+
+	if !write_file(root_file, []byte(state.root.String())) {
+		eprintln("failed to write", config.output_file)
+	}
+}
+
+type Scrivener_Import struct {
+	source string // the .scriv folder
+	folder string // the documents' folder, relative to the root file
+	target string // ...and absolute
+
+	root  strings.Builder
+	count int
+}
+
+func (state *Scrivener_Import) write_item(item *Scrivener_Item, depth int, compile bool) {
+	compile = compile && item.Compile != "No"
+
+	title := strings.TrimSpace(item.Title)
+	if title == "" {
+		title = "Untitled"
+	}
+
+	// Fountain only has three levels of section
+	if strings.HasSuffix(item.Type, "Folder") {
+		level := depth
+		if level > 3 {
+			level = 3
+		}
+
+		state.root.WriteString("\n")
+		state.root.WriteString(strings.Repeat("#", level))
+		state.root.WriteRune(' ')
+		state.root.WriteString(title)
+		state.root.WriteRune('\n')
+	}
+
+	// folders can have text of their own too
+	if text, ok := state.read_document(item); ok && strings.TrimSpace(text) != "" {
+		state.count += 1
+
+		name := fmt.Sprintf("%03d_%s%s", state.count, file_slug(title), FOUNTAIN_EXT)
+
+		if write_file(filepath.Join(state.target, name), []byte(text)) {
+			include := "include: " + filepath.ToSlash(filepath.Join(state.folder, name))
+
+			state.root.WriteRune('\n')
+			if compile {
+				state.root.WriteString(include)
+			} else {
+				state.root.WriteString("/* " + include + " */")
+			}
+			state.root.WriteRune('\n')
+		} else {
+			eprintln("failed to write", name)
+		}
+	}
+
+	for _, child := range item.Children {
+		state.write_item(child, depth + 1, compile)
+	}
+}
+
+// read_document finds a binder item's text, which lives
+// in a different place in each version of Scrivener
+func (state *Scrivener_Import) read_document(item *Scrivener_Item) (string, bool) {
+	paths := make([]string, 0, 3)
+
+	if item.UUID != "" {
+		paths = append(paths, filepath.Join(state.source, "Files", "Data", item.UUID, "content.rtf"))
+		paths = append(paths, filepath.Join(state.source, "Files", "Data", item.UUID, "content.txt"))
+	}
+	if item.ID != "" {
+		paths = append(paths, filepath.Join(state.source, "Files", "Docs", item.ID + ".rtf"))
+	}
+
+	for _, path := range paths {
+		byte_stream, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if filepath.Ext(path) == ".rtf" {
+			return rtf_fountain(byte_stream), true
+		}
+		return string(byte_stream), true
+	}
+
+	return "", false
+}
+
+// lowercase letters and numbers, with
+// underscores for everything else
+func file_slug(title string) string {
+	buffer := strings.Builder{}
+	gap    := false
+
+	for _, c := range strings.ToLower(title) {
+		if unicode.IsLetter(c) || unicode.IsNumber(c) {
+			if gap && buffer.Len() > 0 {
+				buffer.WriteRune('_')
+			}
+			buffer.WriteRune(c)
+			gap = false
+			continue
+		}
+		gap = true
+	}
+
+	if buffer.Len() == 0 {
+		return "untitled"
+	}
+	return buffer.String()
+}
+
+// rtf_fountain reads the text out of an RTF document
+// and writes its bold, italic, underline and strikeout
+// as Fountain markup.
+//
+// people writing Fountain in Scrivener type blank lines
+// between paragraphs themselves, but prose usually has
+// none, so a document without any empty paragraphs has
+// them added to keep each paragraph separate.
+func rtf_fountain(input []byte) string {
+	paragraphs := rtf_paragraphs(string(input))
+
+	is_empty := func(chunks []*XML_Chunk) bool {
+		return strings.TrimSpace(chunks_text(chunks)) == ""
+	}
+
+	// empty paragraphs at either end don't count
+	for len(paragraphs) > 0 && is_empty(paragraphs[0]) {
+		paragraphs = paragraphs[1:]
+	}
+	for len(paragraphs) > 0 && is_empty(paragraphs[len(paragraphs) - 1]) {
+		paragraphs = paragraphs[:len(paragraphs) - 1]
+	}
+
+	has_empty := false
+	for _, chunks := range paragraphs {
+		if is_empty(chunks) {
+			has_empty = true
+			break
+		}
+	}
+
+	lines := make([]string, 0, len(paragraphs))
+	for _, chunks := range paragraphs {
+		lines = append(lines, write_chunks(chunks, false))
+	}
+
+	text := strings.Join(lines, "\n")
+	if !has_empty {
+		text = strings.Join(lines, "\n\n")
+	}
+
+	return strings.TrimSpace(text) + "\n"
+}
+
+// RTF groups we don't want any text from
+var rtf_skipped = map[string]bool{
+	"fonttbl":           true,
+	"colortbl":          true,
+	"expandedcolortbl":  true,
+	"stylesheet":        true,
+	"listtable":         true,
+	"listoverridetable": true,
+	"info":              true,
+	"pict":              true,
+	"header":            true,
+	"footer":            true,
+	"footnote":          true,
+	"fldinst":           true,
+	"themedata":         true,
+	"datastore":         true,
+	"latentstyles":      true,
+	"generator":         true,
+	"object":            true,
+}
+
+var rtf_symbols = map[string]string{
+	"tab":       "\t",
+	"emdash":    "—",
+	"endash":    "–",
+	"lquote":    "‘",
+	"rquote":    "’",
+	"ldblquote": "“",
+	"rdblquote": "”",
+	"bullet":    "•",
+}
+
+type rtf_state struct {
+	skip  bool
+	style Leaf_Type
+	uc    int // characters to skip after a \u
+}
+
+// rtf_paragraphs splits an RTF document into paragraphs
+// of styled chunks, ready for write_chunks
+func rtf_paragraphs(input string) [][]*XML_Chunk {
+	paragraphs := make([][]*XML_Chunk, 0, 64)
+	paragraph  := make([]*XML_Chunk, 0, 8)
+
+	stack := make([]rtf_state, 0, 16)
+	state := rtf_state{uc: 1}
+
+	text := strings.Builder{}
+
+	// close off the text so far as a chunk
+	// in the current style
+	flush := func() {
+		if text.Len() == 0 {
+			return
+		}
+
+		styles := make([]string, 0, 4)
+
+		if state.style & BOLD      != 0 { styles = append(styles, "Bold") }
+		if state.style & ITALIC    != 0 { styles = append(styles, "Italic") }
+		if state.style & UNDERLINE != 0 { styles = append(styles, "Underline") }
+		if state.style & STRIKEOUT != 0 { styles = append(styles, "Strikeout") }
+
+		paragraph = append(paragraph, &XML_Chunk{
+			Style: strings.Join(styles, "+"),
+			Text:  text.String(),
+		})
+		text.Reset()
+	}
+
+	write := func(s string) {
+		if !state.skip {
+			text.WriteString(s)
+		}
+	}
+
+	set_style := func(style Leaf_Type, on bool) {
+		flush()
+		if on {
+			state.style |= style
+		} else {
+			state.style &^= style
+		}
+	}
+
+	skip_chars := 0 // replacement characters left to skip
+
+	for i := 0; i < len(input); i++ {
+		c := input[i]
+
+		switch c {
+		case '{':
+			flush()
+			stack = append(stack, state)
+			continue
+
+		case '}':
+			flush()
+			if len(stack) > 0 {
+				state = stack[len(stack) - 1]
+				stack = stack[:len(stack) - 1]
+			}
+			continue
+
+		case '\r', '\n':
+			continue
+
+		case '\\':
+		default:
+			if skip_chars > 0 {
+				skip_chars -= 1
+				continue
+			}
+			write(string(c))
+			continue
+		}
+
+		// control symbols
+		if i + 1 >= len(input) {
+			break
+		}
+
+		next := input[i + 1]
+
+		if !is_ascii_letter(next) {
+			i += 1
+
+			switch next {
+			case '\\', '{', '}':
+				if skip_chars > 0 {
+					skip_chars -= 1
+					continue
+				}
+				write(string(next))
+			case '~':
+				write(" ")
+			case '_':
+				write("-")
+			case '*':
+				state.skip = true
+			case '\'':
+				if i + 2 < len(input) {
+					if skip_chars > 0 {
+						skip_chars -= 1
+					} else if b, err := strconv.ParseUint(input[i + 1:i + 3], 16, 8); err == nil {
+						write(string(cp1252_rune(byte(b))))
+					}
+					i += 2
+				}
+			case '\r', '\n':
+				if !state.skip {
+					flush()
+					paragraphs = append(paragraphs, paragraph)
+					paragraph  = make([]*XML_Chunk, 0, 8)
+				}
+			}
+			continue
+		}
+
+		// control words, with an optional number
+		// and a single space as the delimiter
+		start := i + 1
+		end   := start
+		for end < len(input) && is_ascii_letter(input[end]) {
+			end += 1
+		}
+		word := input[start:end]
+
+		number_start := end
+		if end < len(input) && input[end] == '-' {
+			end += 1
+		}
+		for end < len(input) && input[end] >= '0' && input[end] <= '9' {
+			end += 1
+		}
+
+		has_number := end > number_start
+		number, _  := strconv.Atoi(input[number_start:end])
+
+		if end < len(input) && input[end] == ' ' {
+			end += 1
+		}
+		i = end - 1
+
+		on := !has_number || number != 0
+
+		if rtf_skipped[word] {
+			state.skip = true
+			continue
+		}
+		if symbol, ok := rtf_symbols[word]; ok {
+			write(symbol)
+			continue
+		}
+
+		switch word {
+		case "par", "sect":
+			if !state.skip {
+				flush()
+				paragraphs = append(paragraphs, paragraph)
+				paragraph  = make([]*XML_Chunk, 0, 8)
+			}
+		case "line":
+			write("\n")
+		case "u":
+			if number < 0 {
+				number += 65536
+			}
+			write(string(rune(number)))
+			skip_chars = state.uc
+		case "uc":
+			state.uc = number
+		case "b":
+			set_style(BOLD, on)
+		case "i":
+			set_style(ITALIC, on)
+		case "ul":
+			set_style(UNDERLINE, on)
+		case "ulnone":
+			set_style(UNDERLINE, false)
+		case "strike":
+			set_style(STRIKEOUT, on)
+		case "plain":
+			set_style(BOLD | ITALIC | UNDERLINE | STRIKEOUT, false)
+		}
+	}
+
+	flush()
+	if len(paragraph) > 0 {
+		paragraphs = append(paragraphs, paragraph)
+	}
+
+	return paragraphs
+}
+
+func is_ascii_letter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// RTF's \'hh escapes are Windows-1252, which is Latin-1
+// apart from the printable characters in 0x80-0x9f
+func cp1252_rune(b byte) rune {
+	const table = "€\u0081‚ƒ„…†‡ˆ‰Š‹Œ\u008dŽ\u008f\u0090‘’“”•–—˜™š›œ\u009džŸ"
+
+	if b >= 0x80 && b <= 0x9f {
+		return []rune(table)[b - 0x80]
+	}
+	return rune(b)
+}
@@ -0,0 +1,163 @@
+/*
+	Meander
+	A portable Fountain utility for production writing
+	Copyright (C) 2022-2023 Harley Denham
+*/
+
+package main
+
+import "strings"
+import "testing"
+
+// describe_chunks writes out each paragraph's chunks as
+// "Style:text", or just the text if it has no style,
+// separated by bars
+func describe_chunks(paragraphs [][]*XML_Chunk) []string {
+	list := make([]string, 0, len(paragraphs))
+
+	for _, chunks := range paragraphs {
+		parts := make([]string, 0, len(chunks))
+		for _, chunk := range chunks {
+			if chunk.Style == "" {
+				parts = append(parts, chunk.Text)
+			} else {
+				parts = append(parts, chunk.Style + ":" + chunk.Text)
+			}
+		}
+		list = append(list, strings.Join(parts, "|"))
+	}
+
+	return list
+}
+
+func TestRTFParagraphs(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		output []string
+	}{
+		{
+			name:   "paragraphs",
+			input:  `{\rtf1\ansi One.\par Two.\par}`,
+			output: []string{"One.", "Two."},
+		},
+		{
+			name:   "skipped groups",
+			input:  `{\rtf1{\fonttbl{\f0 Courier;}}{\colortbl;\red0\green0\blue0;}{\*\generator Scrivener;}{\info{\title Hidden}}Kept.\par}`,
+			output: []string{"Kept."},
+		},
+		{
+			name:   "unknown destination",
+			input:  `{\rtf1{\*\unheard of}Kept.\par}`,
+			output: []string{"Kept."},
+		},
+		{
+			name:   "styles",
+			input:  `{\rtf1 Plain \b bold\b0  and {\i italic} and \ul under\ulnone  and \strike struck\strike0 .\par}`,
+			output: []string{"Plain |Bold:bold| and |Italic:italic| and |Underline:under| and |Strikeout:struck|."},
+		},
+		{
+			name:   "styles together",
+			input:  `{\rtf1\b\i both\plain  neither\par}`,
+			output: []string{"Bold+Italic:both| neither"},
+		},
+		{
+			name:   "group restores style",
+			input:  `{\rtf1{\b bold }plain\par}`,
+			output: []string{"Bold:bold |plain"},
+		},
+		{
+			name:   "escaped characters",
+			input:  `{\rtf1 a\\b \{c\} d\~e f\_g\par}`,
+			output: []string{"a\\b {c} d\u00a0e f-g"},
+		},
+		{
+			name:   "hex escapes",
+			input:  `{\rtf1 caf\'e9 \'93quoted\'94\par}`,
+			output: []string{"café “quoted”"},
+		},
+		{
+			name:   "unicode with a fallback",
+			input:  `{\rtf1 \u8212 ? dash \uc2\u8364 ?? euro\par}`,
+			output: []string{"— dash € euro"},
+		},
+		{
+			name:   "negative unicode",
+			input:  `{\rtf1 \u-3913 ?\par}`,
+			output: []string{"\uf0b7"},
+		},
+		{
+			name:   "symbols",
+			input:  `{\rtf1\ldblquote Hi\rdblquote \emdash\tab x\par}`,
+			output: []string{"“Hi”—\tx"},
+		},
+		{
+			name:   "line breaks",
+			input:  "{\\rtf1 one\\line two\\\nthree\\par}",
+			output: []string{"one\ntwo", "three"},
+		},
+		{
+			name:   "no final par",
+			input:  `{\rtf1 One.\par Two.}`,
+			output: []string{"One.", "Two."},
+		},
+		{
+			name:   "newlines are ignored",
+			input:  "{\\rtf1 one\r\ntwo\\par}",
+			output: []string{"onetwo"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output := describe_chunks(rtf_paragraphs(test.input))
+
+			if strings.Join(output, "\n---\n") != strings.Join(test.output, "\n---\n") {
+				t.Errorf("rtf_paragraphs(%q) =\n%q\nwant\n%q", test.input, output, test.output)
+			}
+		})
+	}
+}
+
+func TestRTFFountain(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		output string
+	}{
+		{
+			name:   "prose is given blank lines",
+			input:  `{\rtf1 One.\par Two.\par}`,
+			output: "One.\n\nTwo.\n",
+		},
+		{
+			name:   "blank lines are kept",
+			input:  `{\rtf1 INT. HOUSE - DAY\par\par BOB\par Hello.\par}`,
+			output: "INT. HOUSE - DAY\n\nBOB\nHello.\n",
+		},
+		{
+			name:   "empty ends don't count",
+			input:  `{\rtf1\par One.\par Two.\par\par}`,
+			output: "One.\n\nTwo.\n",
+		},
+		{
+			name:   "markup",
+			input:  `{\rtf1 Some \b bold\b0 , \i italic\i0 , \ul underlined\ul0  and \strike struck\strike0  words.\par}`,
+			output: "Some **bold**, *italic*, _underlined_ and ~~struck~~ words.\n",
+		},
+		{
+			name:   "spaces stay outside markup",
+			input:  `{\rtf1 a\b  bold \b0 b\par}`,
+			output: "a **bold** b\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output := rtf_fountain([]byte(test.input))
+			if output != test.output {
+				t.Errorf("rtf_fountain(%q) =\n%q\nwant\n%q", test.input, output, test.output)
+			}
+		})
+	}
+}
@@ -0,0 +1,287 @@
+/*
+	Meander
+	A portable Fountain utility for production writing
+	Copyright (C) 2022-2023 Harley Denham
+*/
+
+package main
+
+import "fmt"
+import "strconv"
+import "strings"
+
+import lib "github.com/signintech/gopdf"
+
+// Side is one scene picked for the sides,
+// as it's listed on the cover sheet
+type Side struct {
+	number  string
+	heading string
+
+	first_page int
+	last_page  int
+}
+
+func command_sides(config *Config) {
+	if config.sides_list == "" {
+		eprintln(apply_color("error: sides needs a list of scenes, such as 12,14-16\n\nsee $1meander help sides$0 for full usage"))
+		return
+	}
+
+	data, success := parse_file(config)
+	if !success {
+		return
+	}
+
+	if !number_scenes(config, data) {
+		return
+	}
+
+	vet_template(data.template)
+	paginate(config, data)
+
+	sides, success := select_sides(config, data)
+	if !success {
+		return
+	}
+	if !highlight_characters(config, data) {
+		return
+	}
+
+	doc := new(lib.GoPdf)
+
+	doc.Start(lib.Config{
+		PageSize: config.paper_size,
+	})
+	doc.SetInfo(lib.PdfInfo{
+		Title:        clean_string(data.Title.Title + " " + SIDES_HEADING),
+		Author:       clean_string(data.Title.Author),
+		Creator:      MEANDER,
+		CreationDate: now(),
+	})
+
+	register_fonts(doc)
+	set_font(doc, NO_TYPE)
+
+	render_sides_cover(data, doc, sides)
+	render_content(config, data, doc)
+
+	if err := doc.WritePdf(fix_path(config.output_file)); err != nil {
+		eprintln("error saving", config.output_file)
+	}
+}
+
+// select_sides is the scene-by-scene version of --stars-only:
+// it skips every page without one of the chosen scenes on it,
+// then strikes out anything else left on the pages that remain
+func select_sides(config *Config, data *Fountain) ([]*Side, bool) {
+	// the number of each scene, as it's printed
+	numbers := make([]string, 0, 64)
+
+	for _, section := range data.Content {
+		if section.Type == SCENE {
+			numbers = append(numbers, section.SceneNumber)
+		}
+	}
+
+	if len(numbers) == 0 {
+		eprintf("sides: %q has no scenes", config.source_file)
+		return nil, false
+	}
+
+	picked, success := parse_scene_list(config.sides_list, numbers)
+	if !success {
+		return nil, false
+	}
+
+	page_count  := data.Content[len(data.Content) - 1].page
+	valid_pages := make([]bool, page_count + 1)
+
+	sides := make([]*Side, 0, len(numbers))
+
+	var side *Side
+	index := -1
+
+	for i := range data.Content {
+		section := &data.Content[i]
+
+		if section.Type == SCENE {
+			index += 1
+			side = nil
+
+			if picked[index] {
+				side = &Side{
+					number:     numbers[index],
+					heading:    section.Text,
+					first_page: section.page,
+				}
+				sides = append(sides, side)
+			}
+		}
+
+		if section.Type < is_printable {
+			continue
+		}
+
+		if side == nil {
+			section.struck = true
+			continue
+		}
+
+		side.last_page = section.page
+		valid_pages[section.page] = true
+	}
+
+	skip_pages(data, valid_pages)
+
+	return sides, true
+}
+
+// parse_scene_list reads a list of scenes such as "12,14-16",
+// where a range covers every scene from the first number to
+// the second in script order, so 14-16 takes in 15A; scene
+// numbers with hyphens in them, like 12-A, still work
+func parse_scene_list(text string, numbers []string) ([]bool, bool) {
+	picked := make([]bool, len(numbers))
+
+	has_number := func(number string) bool {
+		number = strings.TrimSpace(number)
+		for _, n := range numbers {
+			if strings.EqualFold(n, number) {
+				return true
+			}
+		}
+		return false
+	}
+
+	find := func(number string) (int, bool) {
+		number = strings.TrimSpace(number)
+
+		found := -1
+		for i, n := range numbers {
+			if !strings.EqualFold(n, number) {
+				continue
+			}
+			if found >= 0 {
+				eprintf("sides: more than one scene is numbered %q", number)
+				return 0, false
+			}
+			found = i
+		}
+
+		if found < 0 {
+			eprintf("sides: there's no scene numbered %q", number)
+			return 0, false
+		}
+		return found, true
+	}
+
+	for _, item := range strings.Split(text, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+
+		// numbers like 12-A have hyphens of their own,
+		// so an exact match comes first, then whichever
+		// hyphen falls between two scene numbers
+		from, to := item, item
+		if !has_number(item) {
+			split := false
+			for i := range item {
+				if item[i] == '-' && has_number(item[:i]) && has_number(item[i + 1:]) {
+					from, to = item[:i], item[i + 1:]
+					split = true
+					break
+				}
+			}
+			if !split {
+				// only worth splitting for the error if
+				// one side is a real number
+				if a, b, ok := strings.Cut(item, "-"); ok && (has_number(a) || has_number(b)) {
+					from, to = a, b
+				}
+			}
+		}
+
+		a, ok := find(from)
+		if !ok {
+			return nil, false
+		}
+		b, ok := find(to)
+		if !ok {
+			return nil, false
+		}
+
+		if a > b {
+			eprintf("sides: scene %q comes after %q", strings.TrimSpace(from), strings.TrimSpace(to))
+			return nil, false
+		}
+
+		for i := a; i <= b; i += 1 {
+			picked[i] = true
+		}
+	}
+
+	return picked, true
+}
+
+// render_sides_cover lists the scenes in the
+// sides, with the pages they can be found on
+func render_sides_cover(data *Fountain, doc *lib.GoPdf, sides []*Side) {
+	template := data.template
+
+	doc.AddPage()
+
+	left  := template.margin_left
+	right := template.margin_right
+	y     := template.margin_top
+
+	text := func(x float64, text string, style Leaf_Type) {
+		set_color(doc, template.text_color)
+		set_font(doc, style)
+		doc.SetXY(x, y)
+		doc.Text(text)
+		set_font(doc, NO_TYPE)
+	}
+
+	if data.Title.Title != "" {
+		text(left, clean_string(data.Title.Title), BOLD)
+		y += LINE_HEIGHT
+	}
+	text(left, SIDES_HEADING, NORMAL)
+	y += LINE_HEIGHT * 3
+
+	number_width := rune_count(BREAKDOWN_SCENE)
+	for _, side := range sides {
+		if n := rune_count(side.number); n > number_width {
+			number_width = n
+		}
+	}
+
+	col_heading := left + float64(number_width + 2) * CHAR_WIDTH
+	col_pages   := right - 8 * CHAR_WIDTH
+
+	heading_width := int((col_pages - col_heading) / CHAR_WIDTH) - 2
+
+	text(left,        BREAKDOWN_SCENE, BOLD)
+	text(col_heading, SIDES_SCENE,     BOLD)
+	text(col_pages,   SIDES_PAGES,     BOLD)
+	y += LINE_HEIGHT * 2
+
+	for _, side := range sides {
+		if y > template.paper.H - template.margin_bottom {
+			doc.AddPage()
+			y = template.margin_top
+		}
+
+		pages := strconv.Itoa(side.first_page)
+		if side.last_page > side.first_page {
+			pages = fmt.Sprintf("%d-%d", side.first_page, side.last_page)
+		}
+
+		text(left,        side.number,                           BOLD)
+		text(col_heading, truncate(side.heading, heading_width), NORMAL)
+		text(col_pages,   pages,                                 NORMAL)
+		y += LINE_HEIGHT
+	}
+}
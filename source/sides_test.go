@@ -0,0 +1,59 @@
+/*
+	Meander
+	A portable Fountain utility for production writing
+	Copyright (C) 2022-2023 Harley Denham
+*/
+
+package main
+
+import "testing"
+
+func TestParseSceneList(t *testing.T) {
+	numbers := []string{"1", "2", "12-A", "12-B", "13", "14"}
+
+	tests := []struct {
+		input  string
+		picked string // an x for each scene picked, or "" for an error
+	}{
+		{"1",          "x....."},
+		{"1,13",       "x...x."},
+		{"1-2",        "xx...."},
+		{"12-A",       "..x..."},
+		{"12-a",       "..x..."},
+		{"12-A-13",    "..xxx."},
+		{"2-12-B",     ".xxx.."},
+		{"12-A-12-B",  "..xx.."},
+		{" 13 - 14 ",  "....xx"},
+		{"14-1",       ""},
+		{"12-C",       ""},
+		{"1-99",       ""},
+		{"99",         ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			picked, ok := parse_scene_list(test.input, numbers)
+
+			if test.picked == "" {
+				if ok {
+					t.Errorf("expected an error")
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("unexpected error")
+			}
+
+			output := make([]byte, len(picked))
+			for i, x := range picked {
+				output[i] = '.'
+				if x {
+					output[i] = 'x'
+				}
+			}
+			if string(output) != test.picked {
+				t.Errorf("parse_scene_list(%q) = %s, want %s", test.input, output, test.picked)
+			}
+		})
+	}
+}
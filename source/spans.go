@@ -0,0 +1,239 @@
+/*
+	Meander
+	A portable Fountain utility for production writing
+	Copyright (C) 2022-2023 Harley Denham
+*/
+
+package main
+
+import "fmt"
+import "math"
+import "strings"
+
+// a run of text with a single style, used by the exporters
+// that don't care about pages or line lengths; style is any
+// combination of BOLD, ITALIC, NOTE, UNDERLINE, STRIKEOUT
+// and HIGHLIGHT
+type Span struct {
+	text  string
+	style Leaf_Type
+}
+
+const span_styles = BOLD | ITALIC | NOTE
+
+// section_spans runs a section through the line breaker with
+// an unreachable width, so the only breaks left are the ones
+// in the text, and returns each line as a list of spans.
+// the section itself is left untouched.
+func section_spans(data *Fountain, section *Section) [][]Span {
+	the_copy := *section
+
+	lines := break_section(data, &the_copy, math.MaxInt32, 0, false)
+
+	if lines == nil {
+		return [][]Span{{{text: section.Text, style: NORMAL}}}
+	}
+
+	output := make([][]Span, 0, len(lines))
+
+	for i := range lines {
+		output = append(output, line_spans(&lines[i]))
+	}
+
+	return output
+}
+
+// line_spans turns a single broken line into spans
+func line_spans(line *Line) []Span {
+	spans := make([]Span, 0, len(line.leaves))
+
+	position := 0
+
+	for _, leaf := range line.leaves {
+		text := leaf.text
+
+		for len(text) > 0 {
+			length := rune_count(text)
+
+			// find the next place the underline, strikeout
+			// or highlight changes within this leaf
+			style := leaf.leaf_type & span_styles
+			split := length
+
+			for _, r := range [...]struct{
+				ranges []int
+				style  Leaf_Type
+			}{
+				{line.underline, UNDERLINE},
+				{line.strikeout, STRIKEOUT},
+				{line.highlight, HIGHLIGHT},
+			} {
+				inside, next := in_range(r.ranges, position)
+				if inside {
+					style |= r.style
+				}
+				if next > position && next - position < split {
+					split = next - position
+				}
+			}
+
+			head, tail := split_runes(text, split)
+
+			// the spaces before a word come with its style,
+			// so the first word of a bold or italic run
+			// would otherwise start with a styled space.
+			// styles already running carry on over them.
+			if fresh := style & span_styles &^ last_style(spans); fresh != 0 {
+				trimmed := strings.TrimLeft(head, " ")
+				spans = append_span(spans, Span{head[:len(head) - len(trimmed)], style &^ fresh})
+				head = trimmed
+			}
+
+			spans = append_span(spans, Span{head, style})
+
+			position += split
+			text = tail
+		}
+	}
+
+	return spans
+}
+
+// span_style_names lists a span's styles by the names
+// the data command uses for them
+func span_style_names(style Leaf_Type) []string {
+	names := make([]string, 0, 2)
+
+	for _, s := range [...]struct{
+		style Leaf_Type
+		name  string
+	}{
+		{BOLD,      "bold"},
+		{ITALIC,    "italic"},
+		{UNDERLINE, "underline"},
+		{STRIKEOUT, "strikeout"},
+		{HIGHLIGHT, "highlight"},
+		{NOTE,      "note"},
+	} {
+		if style & s.style != 0 {
+			names = append(names, s.name)
+		}
+	}
+
+	if len(names) == 0 {
+		return nil
+	}
+	return names
+}
+
+// text_spans is section_spans for a loose piece of text,
+// such as a title page entry
+func text_spans(data *Fountain, text string) [][]Span {
+	section := Section{}
+	section.Text = text
+	return section_spans(data, &section)
+}
+
+// ranges are start/end pairs of rune offsets, as built
+// by break_section; an unpaired start runs to the end
+// of the line.  returns whether the position is inside
+// one and where the next boundary after it is, or -1
+func in_range(ranges []int, position int) (bool, int) {
+	for i := 0; i < len(ranges); i += 2 {
+		start := ranges[i]
+		end   := math.MaxInt32
+		if i + 1 < len(ranges) {
+			end = ranges[i + 1]
+		}
+
+		if position < start {
+			return false, start
+		}
+		if position < end {
+			return true, end
+		}
+	}
+	return false, -1
+}
+
+func split_runes(text string, n int) (string, string) {
+	for i := range text {
+		if n == 0 {
+			return text[:i], text[i:]
+		}
+		n -= 1
+	}
+	return text, ""
+}
+
+func last_style(spans []Span) Leaf_Type {
+	if n := len(spans) - 1; n >= 0 {
+		return spans[n].style
+	}
+	return NORMAL
+}
+
+// adjacent spans of the same style are joined
+func append_span(spans []Span, span Span) []Span {
+	if span.text == "" {
+		return spans
+	}
+	if n := len(spans) - 1; n >= 0 && spans[n].style == span.style {
+		spans[n].text += span.text
+		return spans
+	}
+	return append(spans, span)
+}
+
+// spans_text joins the plain text of a list of spans
+func spans_text(spans []Span) string {
+	buffer := strings.Builder{}
+	for _, s := range spans {
+		buffer.WriteString(s.text)
+	}
+	return buffer.String()
+}
+
+// plain_text strips the markup from a piece of text,
+// joining its lines with spaces
+func plain_text(data *Fountain, text string) string {
+	if text == "" {
+		return ""
+	}
+	lines := make([]string, 0, 4)
+	for _, line := range text_spans(data, text) {
+		lines = append(lines, spans_text(line))
+	}
+	return strings.Join(lines, " ")
+}
+
+// is_manuscript reports whether the script can be exported
+// to one of the formats meant only for prose
+func is_manuscript(data *Fountain, format string) bool {
+	if data.template.kind == MANUSCRIPT || data.template.kind == MANUSCRIPT_COMPACT {
+		return true
+	}
+	eprintln(apply_color("error: only manuscripts can be exported as " + format + " — try $1--format manuscript$0"))
+	return false
+}
+
+// prepare_export readies the counters the line breaker
+// expects, as paginate would
+func prepare_export(data *Fountain) {
+	data.counter_lookup["page"]  = &Counter{value: 1}
+	data.counter_lookup["scene"] = &Counter{value: 0}
+}
+
+// export_scene_number applies the --scene flag to a
+// scene heading outside of pagination
+func export_scene_number(data *Fountain, section *Section) string {
+	switch data.config.scenes {
+	case SCENE_REMOVE:
+		return ""
+	case SCENE_GENERATE:
+		counter := data.counter_lookup["scene"]
+		counter.value += 1
+		return fmt.Sprintf("%d", counter.value)
+	}
+	return section.SceneNumber
+}
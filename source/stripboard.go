@@ -0,0 +1,336 @@
+/*
+	Meander
+	A portable Fountain utility for production writing
+	Copyright (C) 2022-2023 Harley Denham
+*/
+
+package main
+
+import "os"
+import "fmt"
+import "strings"
+import "strconv"
+
+// one row of the stripboard: either a scene
+// or a banner, such as the end of a day
+type Strip struct {
+	scene  *Scene
+	banner string
+}
+
+func command_stripboard(config *Config) {
+	data, success := parse_file(config)
+	if !success {
+		return
+	}
+
+	if !number_scenes(config, data) {
+		return
+	}
+
+	scenes := collect_scenes(config, data)
+
+	if len(scenes) == 0 {
+		eprintf("stripboard: %q has no scenes", config.source_file)
+		return
+	}
+
+	// the order file knows every scene by its position, so
+	// repeats don't get lost, but they're worth a mention
+	for _, number := range repeated_numbers(scenes) {
+		eprintf("stripboard: more than one scene is numbered %q", number)
+	}
+
+	strips := make([]Strip, 0, len(scenes))
+
+	if config.strip_order == "" {
+		for _, scene := range scenes {
+			strips = append(strips, Strip{scene: scene})
+		}
+	} else {
+		blob, err := os.ReadFile(fix_path(config.strip_order))
+
+		switch {
+		case os.IsNotExist(err):
+			// start the user off with the script order
+			if !write_file(fix_path(config.strip_order), []byte(strip_order_text(scenes))) {
+				eprintln("failed to write", config.strip_order)
+				return
+			}
+			for _, scene := range scenes {
+				strips = append(strips, Strip{scene: scene})
+			}
+
+		case err != nil:
+			eprintf("failed to load %q", config.strip_order)
+			return
+
+		default:
+			strips = read_strip_order(config.strip_order, string(blob), scenes)
+		}
+	}
+
+	render_stripboard(config, data, strips)
+}
+
+// strip_order_text writes the scenes in script order as a
+// starting point for the order file.  each one is known by
+// its position, such as @12, which is the only part of the
+// line read back; the number and heading are reminders.
+func strip_order_text(scenes []*Scene) string {
+	buffer := strings.Builder{}
+
+	buffer.WriteString(STRIP_ORDER_HEADER)
+
+	width := len(strconv.Itoa(len(scenes))) + 1
+
+	for i, scene := range scenes {
+		buffer.WriteString(fmt.Sprintf("@%-*d  %-6s  %s\n", width, i + 1, scene.number, scene.heading))
+	}
+
+	return buffer.String()
+}
+
+// read_strip_order puts the scenes in the order given by the
+// file, one scene per line, known either by its position in
+// the script, such as @12, or by its number, if no other scene
+// shares it.  a position followed by a number that no longer
+// matches is reported.  lines starting with = are banners and
+// lines starting with # are ignored.  any scenes the file
+// leaves out go at the end, under their own banner.
+func read_strip_order(path, text string, scenes []*Scene) []Strip {
+	lookup := make(map[string][]int, len(scenes))
+	for i, scene := range scenes {
+		key := strings.ToUpper(scene.number)
+		lookup[key] = append(lookup[key], i)
+	}
+
+	used   := make(map[*Scene]bool, len(scenes))
+	strips := make([]Strip, 0, len(scenes))
+
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		if line[0] == '=' {
+			strips = append(strips, Strip{banner: strings.TrimSpace(line[1:])})
+			continue
+		}
+
+		fields := strings.Fields(line)
+		word   := fields[0]
+
+		var scene *Scene
+
+		if word[0] == '@' {
+			n, err := strconv.Atoi(word[1:])
+			if err != nil || n < 1 || n > len(scenes) {
+				eprintf("%s:%d: there's no scene at position %q", path, i + 1, word)
+				continue
+			}
+			scene = scenes[n - 1]
+
+			// the number written alongside a position shows
+			// which scene was there when the file was made.
+			// if the script has changed since, the number is
+			// followed instead, as long as it's still unique
+			if len(fields) > 1 && scene.number != "" && !strings.EqualFold(fields[1], scene.number) {
+				found := lookup[strings.ToUpper(fields[1])]
+
+				if len(found) == 1 {
+					eprintf("%s:%d: scene %q has moved from %s to @%d, so it's been followed", path, i + 1, fields[1], word, found[0] + 1)
+					scene = scenes[found[0]]
+					word  = fields[1]
+				} else {
+					eprintf("%s:%d: %s is now scene %q, not %q — check the order file against the script", path, i + 1, word, scene.number, fields[1])
+				}
+			}
+		} else {
+			found := lookup[strings.ToUpper(word)]
+
+			switch len(found) {
+			case 0:
+				eprintf("%s:%d: no scene numbered %q", path, i + 1, word)
+				continue
+			case 1:
+				scene = scenes[found[0]]
+			default:
+				eprintf("%s:%d: more than one scene is numbered %q, so give its position instead, such as @%d", path, i + 1, word, found[0] + 1)
+				continue
+			}
+		}
+
+		if used[scene] {
+			eprintf("%s:%d: scene %q is already on the board", path, i + 1, word)
+			continue
+		}
+
+		used[scene] = true
+		strips = append(strips, Strip{scene: scene})
+	}
+
+	banner := false
+
+	for _, scene := range scenes {
+		if used[scene] {
+			continue
+		}
+		if !banner {
+			strips = append(strips, Strip{banner: STRIP_UNSCHEDULED})
+			banner = true
+		}
+		strips = append(strips, Strip{scene: scene})
+	}
+
+	return strips
+}
+
+// strip_color picks the industry colour for a scene from its
+// setting and time of day
+func strip_color(scene *Scene) Color {
+	exterior := strings.HasPrefix(scene.setting, "EXT")
+
+	switch {
+	case exterior && scene.night:
+		return STRIP_EXT_NIGHT
+	case exterior:
+		return STRIP_EXT_DAY
+	case scene.night:
+		return STRIP_INT_NIGHT
+	}
+	return STRIP_INT_DAY
+}
+
+// is_night reads a scene's time of day.  anything that isn't
+// obviously day or night, such as CONTINUOUS, follows the
+// scene before it in the script, which is why read_scenes
+// works it out in script order and not board order.
+func is_night(time string, last_night bool) bool {
+	time = strings.ToUpper(time)
+
+	for _, word := range STRIP_NIGHT {
+		if strings.Contains(time, word) {
+			return true
+		}
+	}
+	for _, word := range STRIP_DAY {
+		if strings.Contains(time, word) {
+			return false
+		}
+	}
+	return last_night
+}
+
+// cast_ids lists the cast ID numbers of everyone who speaks
+// in a scene, which are their positions in the character
+// list counting from one
+func cast_ids(scene *Scene) string {
+	ids := make([]string, 0, len(scene.cast))
+	for _, i := range scene.cast {
+		ids = append(ids, strconv.Itoa(i + 1))
+	}
+	return strings.Join(ids, ", ")
+}
+
+func render_stripboard(config *Config, data *Fountain, strips []Strip) {
+	r := new_report(config, data, STRIP_HEADING, false)
+
+	const strip_height = LINE_HEIGHT * 2 + 6
+
+	number_width := rune_count(BREAKDOWN_SCENE)
+	for _, strip := range strips {
+		if strip.scene == nil {
+			continue
+		}
+		if n := rune_count(strip.scene.number); n > number_width {
+			number_width = n
+		}
+	}
+
+	col_heading := r.left + CHAR_WIDTH + float64(number_width + 2) * CHAR_WIDTH
+	col_length  := r.right - CHAR_WIDTH * 7
+
+	heading_width := int((col_length - col_heading) / CHAR_WIDTH) - 2
+	cast_width    := int((r.right - col_heading) / CHAR_WIDTH) - 1
+
+	for _, strip := range strips {
+		report_fits(r, strip_height)
+
+		top := r.y - PICA + 1
+
+		if strip.scene == nil {
+			r.doc.SetFillColor(0, 0, 0)
+			r.doc.RectFromUpperLeftWithStyle(r.left, top, r.right - r.left, strip_height, "F")
+
+			r.doc.SetTextColor(255, 255, 255)
+			set_font(r.doc, BOLD)
+			r.doc.SetXY(r.left + CHAR_WIDTH, r.y + LINE_HEIGHT / 2 + 3)
+			r.doc.Text(truncate(strings.ToUpper(strip.banner), cast_width))
+			set_font(r.doc, NO_TYPE)
+
+			r.y += strip_height + 2
+			continue
+		}
+
+		scene := strip.scene
+
+		color := strip_color(scene)
+
+		r.doc.SetFillColor(color.R, color.G, color.B)
+		r.doc.SetStrokeColor(0, 0, 0)
+		r.doc.SetLineWidth(0.5)
+		r.doc.RectFromUpperLeftWithStyle(r.left, top, r.right - r.left, strip_height, "FD")
+
+		report_text(r, r.left + CHAR_WIDTH, scene.number,                           BOLD)
+		report_text(r, col_heading,         truncate(scene.heading, heading_width), NORMAL)
+		report_text(r, col_length,          format_eighths(scene.eighths),          NORMAL)
+
+		r.y += LINE_HEIGHT
+
+		if ids := cast_ids(scene); ids != "" {
+			report_text(r, col_heading, truncate(STRIP_CAST + ids, cast_width), ITALIC)
+		}
+
+		r.y += strip_height - LINE_HEIGHT + 2
+	}
+
+	render_cast_list(r, data)
+
+	save_report(r, config.output_file)
+}
+
+// the key to the cast ID numbers on the strips
+func render_cast_list(r *Report, data *Fountain) {
+	if len(data.Characters) == 0 {
+		return
+	}
+
+	r.y += LINE_HEIGHT
+	report_fits(r, LINE_HEIGHT * 3)
+
+	report_text(r, r.left, STRIP_CAST_HEADING, BOLD)
+	report_rule(r)
+	r.y += LINE_HEIGHT * 2
+
+	width := len(strconv.Itoa(len(data.Characters))) + 3
+
+	for i, c := range data.Characters {
+		report_fits(r, LINE_HEIGHT)
+		report_text(r, r.left, fmt.Sprintf("%*d", width - 3, i + 1), NORMAL)
+		report_text(r, r.left + float64(width) * CHAR_WIDTH, c.Name, NORMAL)
+		r.y += LINE_HEIGHT
+	}
+}
+
+// truncate shortens text to a number of characters,
+// marking that something was cut off
+func truncate(text string, n int) string {
+	if rune_count(text) <= n || n < 4 {
+		return text
+	}
+	runes := []rune(text)
+	return string(runes[:n - 3]) + "..."
+}
@@ -23,6 +23,10 @@ const (
 	MARGIN_LEFT   = INCH * 1.5
 	MARGIN_RIGHT  = INCH
 	MARGIN_BOTTOM = INCH
+
+	// reading speeds for the runtime estimate
+	DIALOGUE_WPM = 150
+	ACTION_WPM   = 80
 )
 
 const (
@@ -74,6 +78,9 @@ type Template struct {
 	header_margin float64
 	footer_margin float64
 
+	dialogue_wpm float64
+	action_wpm   float64
+
 	text_color      Color
 	note_color      Color
 	highlight_color Color
@@ -303,6 +310,13 @@ func build_template(config *Config, format Format) *Template {
 		output.types[ACTION].width = output.margin_right - output.margin_left - PICA
 	}
 
+	if output.dialogue_wpm == 0 {
+		output.dialogue_wpm = DIALOGUE_WPM
+	}
+	if output.action_wpm == 0 {
+		output.action_wpm = ACTION_WPM
+	}
+
 	output.starred_nudge  = 1.2
 	output.starred_margin = output.margin_right + PICA * 2
 
@@ -405,7 +419,8 @@ func default_screenplay(output *Template) {
 	output.dual_right_offset = output.paper.W - output.margin_right - output.types[DUAL_DIALOGUE].width - output.margin_left - PICA
 }
 
-func template_entry_parser(template *Template, current Section_Type, line string, line_count int) bool {
+// report receives any problems with the entry
+func template_entry_parser(template *Template, current Section_Type, line string, report func(string, ...any)) bool {
 	line = strings.ToLower(line)
 	ident, w := extract_ident(line)
 	line = left_trim(line[w:])
@@ -413,7 +428,7 @@ func template_entry_parser(template *Template, current Section_Type, line string
 	if r, w := get_rune(line); r == ':' {
 		line = left_trim(line[w:])
 	} else {
-		// @error
+		report("expected a colon after %q", ident)
 		return false
 	}
 
@@ -426,74 +441,88 @@ func template_entry_parser(template *Template, current Section_Type, line string
 			if x, success := set_style(line); success {
 				template.types[current].style = x
 			} else {
-				eprintf("template error: invalid style %q", line)
+				report("invalid style %q", line)
 			}
 
 		case "casing":
 			if x, success := set_casing(line); success {
 				template.types[current].casing = x
 			} else {
-				eprintf("template error: line %-3d invalid letter case %q", line_count, line)
+				report("invalid letter case %q", line)
 			}
 
 		case "justify":
 			if x, success := set_alignment(line); success {
 				template.types[current].justify = x
 			} else {
-				eprintf("template error: invalid alignment %q", line)
+				report("invalid alignment %q", line)
 			}
 
 		case "margin":
-			template.types[current].margin = do_maths(template, line)
+			template.types[current].margin = do_maths(template, line, report)
 
 		case "width":
-			template.types[current].width = do_maths(template, line)
+			template.types[current].width = do_maths(template, line, report)
 
 		case "space_above":
-			template.types[current].space_above = do_maths(template, line)
+			template.types[current].space_above = do_maths(template, line, report)
 
 		case "line_height":
-			template.types[current].line_height = do_maths(template, line)
+			template.types[current].line_height = do_maths(template, line, report)
 
 		case "trail_height":
-			template.types[current].trail_height = do_maths(template, line)
+			template.types[current].trail_height = do_maths(template, line, report)
 
 		case "para_indent":
-			template.types[current].para_indent = int(do_maths(template, line))
+			template.types[current].para_indent = int(do_maths(template, line, report))
 
 		default:
-			eprintf("template error: line %-3d bad key in template %q", line_count, ident)
+			report("bad key in template %q", ident)
 		}
 		return true
 	}
 
 	switch ident {
 	case "margin_left":
-		template.margin_left = do_maths(template, line)
+		template.margin_left = do_maths(template, line, report)
 
 	case "margin_right":
-		template.margin_right = do_maths(template, line)
+		template.margin_right = do_maths(template, line, report)
 
 	case "margin_top":
-		template.margin_top = do_maths(template, line)
+		template.margin_top = do_maths(template, line, report)
 
 	case "margin_bottom":
-		template.margin_bottom = do_maths(template, line)
+		template.margin_bottom = do_maths(template, line, report)
 
 	case "line_height":
-		template.line_height = do_maths(template, line)
+		template.line_height = do_maths(template, line, report)
 
 	case "center_line":
-		template.center_line = do_maths(template, line)
+		template.center_line = do_maths(template, line, report)
 
 	case "dual_right_offset":
-		template.dual_right_offset = do_maths(template, line)
+		template.dual_right_offset = do_maths(template, line, report)
 
 	case "header_margin":
-		template.header_margin = do_maths(template, line)
+		template.header_margin = do_maths(template, line, report)
 
 	case "footer_margin":
-		template.footer_margin = do_maths(template, line)
+		template.footer_margin = do_maths(template, line, report)
+
+	case "dialogue_wpm":
+		if x, success := parse_wpm(line); success {
+			template.dialogue_wpm = x
+		} else {
+			report("invalid words per minute %q", line)
+		}
+
+	case "action_wpm":
+		if x, success := parse_wpm(line); success {
+			template.action_wpm = x
+		} else {
+			report("invalid words per minute %q", line)
+		}
 
 	case "landscape":
 		if line == "false" {
@@ -513,29 +542,32 @@ func template_entry_parser(template *Template, current Section_Type, line string
 		if x, success := parse_color(line); success {
 			template.text_color = x
 		} else {
-			eprintf("template error: line %-3d invalid values in colour %q", line_count, line)
+			report("invalid values in colour %q", line)
 		}
 
 	case "note_color":
 		if x, success := parse_color(line); success {
 			template.note_color = x
 		} else {
-			eprintf("template error: line %-3d invalid values in colour %q", line_count, line)
+			report("invalid values in colour %q", line)
 		}
 
 	case "highlight_color":
 		if x, success := parse_color(line); success {
 			template.highlight_color = x
 		} else {
-			eprintf("template error: line %-3d invalid values in colour %q", line_count, line)
+			report("invalid values in colour %q", line)
 		}
 
 	case "title_page_align":
 		if x, success := set_alignment(line); success {
 			template.title_page_align = x
 		} else {
-			eprintf("template error: invalid alignment %q", line)
+			report("invalid alignment %q", line)
 		}
+
+	default:
+		report("bad key in template %q", ident)
 	}
 
 	return true
@@ -622,6 +654,14 @@ func set_alignment(x string) (uint8, bool) {
 	return LEFT, false
 }
 
+func parse_wpm(text string) (float64, bool) {
+	x, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+	if err != nil || x <= 0 {
+		return 0, false
+	}
+	return x, true
+}
+
 func parse_color(numbers string) (Color, bool) {
 	var c Color
 
@@ -643,56 +683,18 @@ func parse_color(numbers string) (Color, bool) {
 	return c, true
 }
 
-func string_to_section_type(x string) (Section_Type, bool) {
-	switch strings.ToLower(x) {
-	case "action":
-		return ACTION, true
-	case "scene":
-		return SCENE, true
-	case "character":
-		return CHARACTER, true
-	case "dual_character":
-		return DUAL_CHARACTER, true
-	case "parenthetical":
-		return PARENTHETICAL, true
-	case "dual_parenthetical":
-		return DUAL_PARENTHETICAL, true
-	case "dialogue":
-		return DIALOGUE, true
-	case "dual_dialogue":
-		return DUAL_DIALOGUE, true
-	case "lyric":
-		return LYRIC, true
-	case "dual_lyric":
-		return DUAL_LYRIC, true
-	case "transition":
-		return TRANSITION, true
-	case "synopsis":
-		return SYNOPSIS, true
-	case "centered":
-		return CENTERED, true
-	case "section":
-		return SECTION, true
-	case "section2":
-		return SECTION2, true
-	case "section3":
-		return SECTION3, true
-	}
-	return WHITESPACE, false
-}
-
-func get_template_value(t *Template, name string) float64 {
+func get_template_value(t *Template, name string, report func(string, ...any)) float64 {
 	n := strings.IndexRune(name, '.')
 	if n >= 0 {
 		taxonomy, width := extract_ident(name)
 		name = name[width:]
 		if len(name) > 0 && name[0] != '.' {
-			eprintf("bad template parse") // @todo
+			report("bad template field %q", name)
 		}
 
 		tax_type, success := string_to_section_type(taxonomy)
 		if !success {
-			eprintf("bad template parse on type") // @todo
+			report("unknown element type %q", taxonomy)
 		}
 
 		name = name[1:]
@@ -712,7 +714,7 @@ func get_template_value(t *Template, name string) float64 {
 			return float64(t.types[tax_type].para_indent)
 		}
 
-		eprintf("can't do maths on template field %q", name)
+		report("can't do maths on template field %q", name)
 		return 0
 	}
 
@@ -754,7 +756,7 @@ func get_template_value(t *Template, name string) float64 {
 		return t.paper.H
 	}
 
-	eprintf("can't do maths on template field %q", name)
+	report("can't do maths on template field %q", name)
 	return 0
 }
 
@@ -796,7 +798,7 @@ func extract_dotted_number(input string) (string, int) {
 	return input, width
 }
 
-func do_maths(t *Template, text string) float64 {
+func do_maths(t *Template, text string, report func(string, ...any)) float64 {
 	operations := make([]Operation, 0, 32)
 
 	for {
@@ -838,7 +840,7 @@ func do_maths(t *Template, text string) float64 {
 
 			} else if unicode.IsLetter(char) {
 				ident, ident_width := extract_dotted_ident(text)
-				op.value = get_template_value(t, ident)
+				op.value = get_template_value(t, ident, report)
 				text = text[ident_width:]
 			}
 		}
@@ -846,7 +848,7 @@ func do_maths(t *Template, text string) float64 {
 		operations = append(operations, op)
 	}
 
-	operations = shunting_yard(operations)
+	operations = shunting_yard(operations, report)
 	stack := make([]Operation, 0, len(operations))
 
 	for _, token := range operations {
@@ -855,6 +857,11 @@ func do_maths(t *Template, text string) float64 {
 			continue
 		}
 
+		if len(stack) < 2 {
+			report("incomplete expression %q", text)
+			return 0
+		}
+
 		a := stack[len(stack) - 2].value
 		b := stack[len(stack) - 1].value
 
@@ -879,7 +886,7 @@ func do_maths(t *Template, text string) float64 {
 	return stack[0].value
 }
 
-func shunting_yard(operations []Operation) []Operation {
+func shunting_yard(operations []Operation, report func(string, ...any)) []Operation {
 	final     := make([]Operation, 0, len(operations))
 	operators := make([]Operation, 0, len(operations))
 
@@ -906,7 +913,7 @@ func shunting_yard(operations []Operation) []Operation {
 				}
 
 				if !found_left {
-					eprintln("mismatched parentheses in expression")
+					report("mismatched parentheses in expression")
 					return nil
 				}
 
@@ -64,34 +64,6 @@ func left_trim_ignore_newlines(input string) string {
 	return output
 }
 
-func right_trim(input string) string {
-	start := 0
-	stop := len(input)
-
-	for ; stop > start; stop-- {
-		c := input[stop-1]
-
-		if c >= utf8.RuneSelf {
-			return strings.TrimFunc(input[start:stop], unicode.IsSpace)
-		}
-
-		if ascii_space[c] == 0 {
-			break
-		}
-	}
-
-	return input[start:stop]
-}
-
-func consume_newlines(input string) string {
-	for i, c := range input {
-		if c != '\n' {
-			return input[i:]
-		}
-	}
-	return input
-}
-
 func is_format_char(x rune) bool {
 	switch x {
 	case '*':  return true
@@ -203,60 +175,6 @@ func int_power(n, m int) int {
 	return result
 }
 
-func normalise_text(input string) string {
-	buffer := strings.Builder{}
-	buffer.Grow(len(input))
-
-	input = strings.TrimSpace(input)
-
-	last_rune := 'a'
-
-	for _, c := range input {
-		switch c {
-		case '“', '”':
-			buffer.WriteRune('"')
-			last_rune = c
-			continue
-
-		case '’':
-			buffer.WriteRune('\'')
-			last_rune = c
-			continue
-
-		case '`', '‘':
-			buffer.WriteRune('\'')
-			last_rune = c
-			continue
-
-		case '\n':
-			if last_rune == '\r' {
-				continue
-			}
-			buffer.WriteRune('\n')
-			last_rune = c
-			continue
-
-		case '\r':
-			if last_rune == '\n' {
-				continue
-			}
-			buffer.WriteRune('\n')
-			last_rune = c
-			continue
-
-		case '\t':
-			buffer.WriteString(`    `) // 4 spaces
-			last_rune = c
-			continue
-		}
-
-		last_rune = c
-		buffer.WriteRune(c)
-	}
-
-	return buffer.String()
-}
-
 // homogenise "Draft Date" or "draft_date" into "draftdate"
 // this helps us simplify any multi-matches in the title page
 func homogenise(input string) string {
@@ -279,15 +197,6 @@ func homogenise(input string) string {
 	return buffer.String()
 }
 
-func extract_to_newline(input string) string {
-	for i, c := range input {
-		if c == '\n' {
-			return input[:i]
-		}
-	}
-	return input
-}
-
 func count_whitespace(input string) int {
 	for i, c := range input {
 		if !unicode.IsSpace(c) {
@@ -317,32 +226,6 @@ func count_rune(input string, r rune) int {
 	return count
 }
 
-func rune_on_line(input string, x rune) int {
-	for i, c := range input {
-		if c == x {
-			return i + 1
-		}
-		if c == '\n' {
-			break
-		}
-	}
-
-	return -1
-}
-
-func rune_pair(text string, x, y rune) int {
-	last := 'a'
-
-	for i, c := range text {
-		if c == y && last == x { // swapped from above
-			return i + 1
-		}
-		last = c
-	}
-
-	return -1
-}
-
 func clean_string(input string) string {
 	if input == "" {
 		return ""
@@ -435,52 +318,6 @@ func title_case(input string) string {
 	return strings.Join(words, " ")
 }
 
-func word_count(text string) int {
-	total_count := 0
-	each_word   := 0
-
-	for {
-		if len(text) == 0 {
-			break
-		}
-
-		r, w := get_rune(text)
-		text = text[w:]
-
-		if unicode.IsLetter(r) || unicode.IsNumber(r) {
-			each_word += 1
-			continue
-		}
-
-		if each_word > 0 {
-			total_count += 1
-			each_word = 0
-		}
-	}
-
-	if each_word > 0 {
-		total_count += 1
-	}
-
-	return total_count
-}
-
-func load_file(source_file string) (string, bool) {
-	bytes, err := os.ReadFile(source_file)
-	if err != nil {
-		return "", false
-	}
-	return string(bytes), true
-}
-
-func load_file_normalise(source_file string) (string, bool) {
-	text, success := load_file(source_file)
-	if !success {
-		return "", false
-	}
-	return normalise_text(text), success
-}
-
 func write_file(path string, content []byte) bool {
 	return os.WriteFile(path, content, os.ModePerm) == nil
 }
@@ -500,13 +337,6 @@ func rewrite_ext(path, new_ext string) string {
 	return raw + new_ext
 }
 
-func include_path(parent, input string) string {
-	if !filepath.IsAbs(input) {
-		return filepath.Join(filepath.Dir(parent), input)
-	}
-	return input
-}
-
 func print(words ...string) {
 	l := len(words) - 1
 	for i, w := range words {
@@ -0,0 +1,224 @@
+/*
+	Meander
+	A portable Fountain utility for production writing
+	Copyright (C) 2022-2023 Harley Denham
+*/
+
+package main
+
+import "fmt"
+import "strings"
+
+// Word_Count is the number of words in one part of
+// the document, a section or a scene; a section's
+// totals include the sections and scenes inside it
+type Word_Count struct {
+	title string
+	level int // of a section, or zero for a scene
+	depth int // how many sections it falls under
+
+	words     int
+	dialogue  int
+	narration int
+
+	entry *Section
+}
+
+func command_words(config *Config) {
+	data, success := parse_file(config)
+	if !success {
+		return
+	}
+
+	list, total := count_scoped_words(data)
+
+	print_words(data, list, total)
+}
+
+// count_scoped_words walks the document in outline order and
+// counts the words in every section and scene.  everything
+// printed counts towards the totals, the same as #wordcount,
+// but only dialogue and lyrics count as dialogue, and only
+// action and centered text count as narration.  each heading
+// is given its total, for the #sectionwords and #scenewords
+// counters to pick up during pagination.
+func count_scoped_words(data *Fountain) ([]*Word_Count, *Word_Count) {
+	list  := make([]*Word_Count, 0, 64)
+	total := &Word_Count{}
+
+	open := make([]*Word_Count, 0, 3) // sections, outermost first
+
+	var scene *Word_Count
+
+	for i := range data.Content {
+		section := &data.Content[i]
+
+		switch section.Type {
+		case SECTION:
+			// a section runs until the next one
+			// at the same level or above it
+			for len(open) > 0 && open[len(open) - 1].level >= section.Level {
+				open = open[:len(open) - 1]
+			}
+
+			c := &Word_Count{
+				title: section.Text,
+				level: section.Level,
+				depth: len(open),
+				entry: section,
+			}
+
+			list  = append(list, c)
+			open  = append(open, c)
+			scene = nil
+
+		case SCENE:
+			title := section.Text
+			if section.SceneNumber != "" {
+				title = section.SceneNumber + "  " + title
+			}
+
+			scene = &Word_Count{
+				title: title,
+				depth: len(open),
+				entry: section,
+			}
+
+			list = append(list, scene)
+		}
+
+		if section.Type < is_printable {
+			continue
+		}
+
+		n := count_words(section.Text)
+
+		add := func(c *Word_Count) {
+			c.words += n
+
+			switch section.Type {
+			case DIALOGUE, DUAL_DIALOGUE, LYRIC, DUAL_LYRIC:
+				c.dialogue += n
+			case ACTION, CENTERED:
+				c.narration += n
+			}
+		}
+
+		add(total)
+		for _, c := range open {
+			add(c)
+		}
+		if scene != nil {
+			add(scene)
+		}
+	}
+
+	for _, c := range list {
+		c.entry.words = c.words
+	}
+
+	return list, total
+}
+
+// set_word_scope moves the #sectionwords and #scenewords
+// counters on to each section or scene that's started,
+// in order, and hands back the emptied list
+func set_word_scope(data *Fountain, scope []*Section) []*Section {
+	for _, section := range scope {
+		switch {
+		case section.Type == SCENE:
+			data.counter_lookup["scenewords"].value = section.words
+
+		case section.Type > is_section:
+			data.counter_lookup["sectionwords"].value = section.words
+			data.counter_lookup["scenewords"].value   = 0
+		}
+	}
+	return scope[:0]
+}
+
+// find_word_scope looks ahead past the end of a page for
+// any sections or scenes that open the next one, so the
+// header on that page counts them and not the last page
+func find_word_scope(search_array []Section, search_depth int) []*Section {
+	scope := make([]*Section, 0, 4)
+
+	outer: for index := range search_array {
+		if index > search_depth {
+			break
+		}
+
+		section := &search_array[index]
+
+		switch {
+		case section.Type < is_printable:
+			continue
+
+		case section.Type > is_section:
+			scope = append(scope, section)
+
+		case section.Type == SCENE:
+			scope = append(scope, section)
+			break outer
+
+		default:
+			break outer
+		}
+	}
+
+	return scope
+}
+
+func print_words(data *Fountain, list []*Word_Count, total *Word_Count) {
+	const max_name = 48
+
+	longest := rune_count(WORDS_COLUMN)
+	names   := make([]string, len(list))
+
+	for i, c := range list {
+		names[i] = truncate(strings.Repeat("  ", c.depth) + c.title, max_name)
+		if n := rune_count(names[i]); n > longest {
+			longest = n
+		}
+	}
+
+	println_color("\n   ", clean_string(data.Title.Title), WORDS_HEADING)
+
+	print("\n    ")
+	print_padded(WORDS_COLUMN,    longest)
+	print_padded(CROSS_WORDS,     8)
+	print_padded(WORDS_DIALOGUE,  8)
+	println(WORDS_NARRATION)
+
+	print("    ")
+	print_dashes(longest + 31)
+
+	for i, c := range list {
+		is_head := c.level > 0 && running_in_term
+
+		print("    ")
+		if is_head {
+			print(ANSI_COLOR)
+		}
+		print_padded(names[i], longest)
+		print_padded(fmt.Sprintf("%d", c.words),    8)
+		print_padded(fmt.Sprintf("%d", c.dialogue), 8)
+		println(fmt.Sprintf("%d", c.narration))
+		if is_head {
+			print(ANSI_RESET)
+		}
+	}
+
+	if len(list) > 0 {
+		print("    ")
+		print_dashes(longest + 31)
+	}
+
+	print("    ")
+	print_padded(RUNTIME_TOTAL, longest)
+	print_padded(fmt.Sprintf("%d", total.words),    8)
+	print_padded(fmt.Sprintf("%d", total.dialogue), 8)
+	println(fmt.Sprintf("%d", total.narration))
+
+	print("\n")
+}